@@ -10,6 +10,7 @@ export const navigation: NavItem[] = [
       'base-folders',
       'worktrees',
       'state-files',
+      'configuration',
     ],
   },
   {
@@ -47,6 +48,7 @@ export const titles: Record<string, string> = {
   'base-folders': 'Base Folders',
   'worktrees': 'Worktrees',
   'state-files': 'State Files',
+  'configuration': 'Configuration',
   'integration': 'Integration',
   'claude-code-hooks': 'Claude Code Hooks',
   'slash-commands': 'Slash Commands',
@@ -0,0 +1,174 @@
+// Package alloc hands out per-worktree resources: a range of ports, a
+// database name and a cache directory.
+//
+// Dev servers started in several worktrees of one repo would otherwise all
+// listen on the same port and share a database. Each worktree gets its own
+// resources, recorded in allocations.jsonl and written to a generated
+// .env.bearing in the worktree, until the worktree is cleaned up.
+package alloc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+// EnvFile is the generated env file, relative to the worktree root
+const EnvFile = ".env.bearing"
+
+// maxDatabaseLen is the longest identifier PostgreSQL keeps
+const maxDatabaseLen = 63
+
+// Policy says what to hand out
+type Policy struct {
+	PortBase         int    // first port of the first range
+	PortsPerWorktree int    // ports in each range
+	CacheDir         string // parent of the per-worktree cache dirs
+}
+
+// portFree reports whether nothing listens on port; replaced in tests
+var portFree = func(port int) bool {
+	l, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// Find returns the allocation for folder, or nil if it has none
+func Find(entries []jsonl.AllocationEntry, folder string) *jsonl.AllocationEntry {
+	for i := range entries {
+		if entries[i].Folder == folder {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// Allocate returns entries with an allocation for folder added, and that
+// allocation. A folder that already has one keeps it. New port ranges take
+// the lowest slot that overlaps no other allocation and whose ports are
+// not in use by another process.
+func Allocate(entries []jsonl.AllocationEntry, p Policy, folder, repo string, now time.Time) ([]jsonl.AllocationEntry, jsonl.AllocationEntry, error) {
+	if e := Find(entries, folder); e != nil {
+		return entries, *e, nil
+	}
+
+	slot, err := freeSlot(entries, p)
+	if err != nil {
+		return nil, jsonl.AllocationEntry{}, err
+	}
+	e := jsonl.AllocationEntry{
+		Folder:    folder,
+		Repo:      repo,
+		Slot:      slot,
+		Port:      p.PortBase + slot*p.PortsPerWorktree,
+		PortCount: p.PortsPerWorktree,
+		Database:  databaseName(entries, folder, slot),
+		CacheDir:  filepath.Join(p.CacheDir, folder),
+		Allocated: now,
+	}
+	return append(entries, e), e, nil
+}
+
+func freeSlot(entries []jsonl.AllocationEntry, p Policy) (int, error) {
+	for slot := 0; ; slot++ {
+		start := p.PortBase + slot*p.PortsPerWorktree
+		end := start + p.PortsPerWorktree // exclusive
+		if end-1 > 65535 {
+			return 0, fmt.Errorf("no free port range of %d ports from %d", p.PortsPerWorktree, p.PortBase)
+		}
+		if overlaps(entries, start, end) {
+			continue
+		}
+		free := true
+		for port := start; port < end && free; port++ {
+			free = portFree(port)
+		}
+		if free {
+			return slot, nil
+		}
+	}
+}
+
+// overlaps reports whether any allocation uses a port in [start, end)
+func overlaps(entries []jsonl.AllocationEntry, start, end int) bool {
+	for _, e := range entries {
+		if e.Port < end && start < e.Port+e.PortCount {
+			return true
+		}
+	}
+	return false
+}
+
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// databaseName derives a database name from folder that no other
+// allocation uses
+func databaseName(entries []jsonl.AllocationEntry, folder string, slot int) string {
+	name := strings.Trim(nonIdentifier.ReplaceAllString(strings.ToLower(folder), "_"), "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "wt_" + name
+	}
+	name = strings.TrimSuffix(name[:min(len(name), maxDatabaseLen)], "_")
+	for _, e := range entries {
+		if e.Database == name {
+			suffix := "_" + strconv.Itoa(slot)
+			return name[:min(len(name), maxDatabaseLen-len(suffix))] + suffix
+		}
+	}
+	return name
+}
+
+// Free returns entries without the allocation for folder, and that
+// allocation, or nil if folder had none
+func Free(entries []jsonl.AllocationEntry, folder string) ([]jsonl.AllocationEntry, *jsonl.AllocationEntry) {
+	var kept []jsonl.AllocationEntry
+	var freed *jsonl.AllocationEntry
+	for _, e := range entries {
+		if e.Folder == folder {
+			freed = &e
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept, freed
+}
+
+// Env returns the variables describing e, in the order they are written
+func Env(e jsonl.AllocationEntry) [][2]string {
+	return [][2]string{
+		{"PORT", strconv.Itoa(e.Port)},
+		{"BEARING_PORT", strconv.Itoa(e.Port)},
+		{"BEARING_PORT_RANGE", fmt.Sprintf("%d-%d", e.Port, e.Port+e.PortCount-1)},
+		{"BEARING_DATABASE", e.Database},
+		{"BEARING_CACHE_DIR", e.CacheDir},
+	}
+}
+
+// WriteEnvFile writes the variables of e to EnvFile in worktree
+func WriteEnvFile(worktree string, e jsonl.AllocationEntry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by bearing for %s; do not edit.\n", e.Folder)
+	b.WriteString("# Freed by bearing worktree cleanup. Print again with: bearing worktree env " + e.Folder + "\n")
+	for _, kv := range Env(e) {
+		fmt.Fprintf(&b, "%s=%s\n", kv[0], Quote(kv[1]))
+	}
+	return os.WriteFile(filepath.Join(worktree, EnvFile), []byte(b.String()), 0644)
+}
+
+// Quote quotes value for a shell or dotenv file if it needs it
+func Quote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:") == "" {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
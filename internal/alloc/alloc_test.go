@@ -0,0 +1,111 @@
+package alloc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+var testPolicy = Policy{PortBase: 4000, PortsPerWorktree: 10, CacheDir: "/cache"}
+
+func withBusyPorts(t *testing.T, busy ...int) {
+	t.Helper()
+	orig := portFree
+	portFree = func(port int) bool {
+		for _, b := range busy {
+			if port == b {
+				return false
+			}
+		}
+		return true
+	}
+	t.Cleanup(func() { portFree = orig })
+}
+
+func TestAllocate(t *testing.T) {
+	withBusyPorts(t)
+	now := time.Now()
+
+	entries, a, err := Allocate(nil, testPolicy, "app-one", "app", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Slot != 0 || a.Port != 4000 || a.PortCount != 10 || a.Database != "app_one" || a.CacheDir != "/cache/app-one" {
+		t.Errorf("unexpected allocation: %+v", a)
+	}
+
+	entries, b, _ := Allocate(entries, testPolicy, "app-two", "app", now)
+	if b.Slot != 1 || b.Port != 4010 {
+		t.Errorf("expected the next slot, got %+v", b)
+	}
+
+	// Allocating again returns the existing allocation
+	again, a2, _ := Allocate(entries, testPolicy, "app-one", "app", now)
+	if len(again) != 2 || a2.Port != a.Port {
+		t.Errorf("expected existing allocation reused, got %+v", a2)
+	}
+
+	// A freed slot is handed out again
+	entries, freed := Free(entries, "app-one")
+	if freed == nil || freed.Port != 4000 || len(entries) != 1 {
+		t.Fatalf("unexpected free: %+v, %+v", freed, entries)
+	}
+	if _, c, _ := Allocate(entries, testPolicy, "app-three", "app", now); c.Port != 4000 {
+		t.Errorf("expected freed slot reused, got %+v", c)
+	}
+	if _, none := Free(entries, "missing"); none != nil {
+		t.Errorf("expected nothing freed, got %+v", none)
+	}
+}
+
+func TestAllocateSkipsBusyAndOverlapping(t *testing.T) {
+	withBusyPorts(t, 4005)
+
+	// A range allocated with a different size still blocks its ports
+	existing := []jsonl.AllocationEntry{{Folder: "old", Port: 4010, PortCount: 15, Database: "old"}}
+	_, a, err := Allocate(existing, testPolicy, "new", "app", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Port != 4030 {
+		t.Errorf("expected first range clear of busy and allocated ports, got %d", a.Port)
+	}
+
+	_, _, err = Allocate(nil, Policy{PortBase: 65530, PortsPerWorktree: 10}, "x", "app", time.Now())
+	if err == nil {
+		t.Error("expected error when ports run out")
+	}
+}
+
+func TestDatabaseName(t *testing.T) {
+	existing := []jsonl.AllocationEntry{{Database: "app_feature_x"}}
+	cases := []struct{ folder, want string }{
+		{"App.Feature/Y", "app_feature_y"},
+		{"app-feature_x", "app_feature_x_3"},
+		{"2fa-login", "wt_2fa_login"},
+		{strings.Repeat("a", 80), strings.Repeat("a", 63)},
+	}
+	for _, c := range cases {
+		if got := databaseName(existing, c.folder, 3); got != c.want {
+			t.Errorf("databaseName(%q) = %q, want %q", c.folder, got, c.want)
+		}
+	}
+}
+
+func TestWriteEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	e := jsonl.AllocationEntry{Folder: "app-one", Port: 4000, PortCount: 10, Database: "app_one", CacheDir: "/my cache/app-one"}
+	if err := WriteEnvFile(dir, e); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, EnvFile))
+	for _, want := range []string{"\nPORT=4000\n", "BEARING_PORT_RANGE=4000-4009\n", "BEARING_DATABASE=app_one\n", "BEARING_CACHE_DIR='/my cache/app-one'\n"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q in:\n%s", want, data)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package bootstrap prepares a newly created worktree for work.
+//
+// Files git ignores, such as .env or node_modules, exist only in the base
+// folder, so a new worktree starts without them. A bootstrap copies or
+// links them over from the base folder, then runs setup commands and the
+// repo's own .bearing/bootstrap script in the worktree.
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ScriptPath is the repo's bootstrap script, relative to the repo root
+const ScriptPath = ".bearing/bootstrap"
+
+// Plan says how to prepare a worktree
+type Plan struct {
+	Copy    []string      // paths or globs copied from the base folder
+	Symlink []string      // paths or globs linked to the base folder
+	Run     []string      // shell commands run in the worktree
+	Timeout time.Duration // for all commands and the script together; 0 for none
+}
+
+// Target is the worktree being prepared
+type Target struct {
+	Repo     string
+	Branch   string
+	BaseDir  string   // the repo's base folder
+	Worktree string   // the new worktree
+	Env      []string // extra KEY=value variables for commands
+}
+
+// HasScript reports whether the worktree has a bootstrap script
+func (t Target) HasScript() bool {
+	info, err := os.Stat(filepath.Join(t.Worktree, ScriptPath))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Empty reports whether p does nothing
+func (p Plan) Empty() bool {
+	return len(p.Copy) == 0 && len(p.Symlink) == 0 && len(p.Run) == 0
+}
+
+// Run prepares t as p says, then runs t's bootstrap script if it has one,
+// writing progress and command output to out. It stops at the first
+// failure.
+func Run(ctx context.Context, p Plan, t Target, out io.Writer) error {
+	for _, pattern := range p.Copy {
+		if err := each(t, pattern, out, "copied", copyPath); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range p.Symlink {
+		if err := each(t, pattern, out, "linked", func(src, dst string) error {
+			return os.Symlink(src, dst)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	for _, command := range p.Run {
+		fmt.Fprintf(out, "bootstrap: $ %s\n", command)
+		if err := run(ctx, t, out, "sh", "-c", command); err != nil {
+			return commandError(ctx, command, err)
+		}
+	}
+	if t.HasScript() {
+		fmt.Fprintf(out, "bootstrap: running %s\n", ScriptPath)
+		script := filepath.Join(t.Worktree, ScriptPath)
+		if err := run(ctx, t, out, "sh", script); err != nil {
+			return commandError(ctx, ScriptPath, err)
+		}
+	}
+	return nil
+}
+
+// each applies fn to every match of pattern in the base folder and the
+// same path in the worktree. Paths the worktree already has are skipped.
+func each(t Target, pattern string, out io.Writer, verb string, fn func(src, dst string) error) error {
+	if filepath.IsAbs(pattern) || pattern == ".." || strings.HasPrefix(filepath.Clean(pattern), ".."+string(filepath.Separator)) {
+		return fmt.Errorf("bootstrap path must be inside the repo: %s", pattern)
+	}
+	matches, err := filepath.Glob(filepath.Join(t.BaseDir, pattern))
+	if err != nil {
+		return fmt.Errorf("invalid bootstrap path %q: %w", pattern, err)
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(out, "bootstrap: %s not in base folder, skipped\n", pattern)
+		return nil
+	}
+	for _, src := range matches {
+		rel, _ := filepath.Rel(t.BaseDir, src)
+		dst := filepath.Join(t.Worktree, rel)
+		if _, err := os.Lstat(dst); err == nil {
+			fmt.Fprintf(out, "bootstrap: %s already exists, skipped\n", rel)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := fn(src, dst); err != nil {
+			return fmt.Errorf("failed to bootstrap %s: %w", rel, err)
+		}
+		fmt.Fprintf(out, "bootstrap: %s %s\n", verb, rel)
+	}
+	return nil
+}
+
+// copyPath copies a file, symlink or directory tree, keeping modes
+func copyPath(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// run runs a command in the worktree with its output streamed to out. The
+// whole process group is killed when ctx ends, so commands that spawn
+// children don't outlive the timeout.
+func run(ctx context.Context, t Target, out io.Writer, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = t.Worktree
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = append(os.Environ(),
+		"BEARING_REPO="+t.Repo,
+		"BEARING_BRANCH="+t.Branch,
+		"BEARING_BASE_DIR="+t.BaseDir,
+		"BEARING_WORKTREE="+t.Worktree,
+	)
+	cmd.Env = append(cmd.Env, t.Env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	return cmd.Run()
+}
+
+func commandError(ctx context.Context, command string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s timed out", command)
+	}
+	return fmt.Errorf("%s failed: %w", command, err)
+}
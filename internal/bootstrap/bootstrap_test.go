@@ -0,0 +1,87 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupTarget(t *testing.T) Target {
+	t.Helper()
+	dir := t.TempDir()
+	target := Target{
+		Repo:     "app",
+		Branch:   "feat",
+		BaseDir:  filepath.Join(dir, "app"),
+		Worktree: filepath.Join(dir, "app-feat"),
+	}
+	os.MkdirAll(filepath.Join(target.BaseDir, "node_modules", "lib"), 0755)
+	os.WriteFile(filepath.Join(target.BaseDir, ".env"), []byte("KEY=1\n"), 0600)
+	os.WriteFile(filepath.Join(target.BaseDir, ".env.local"), []byte("LOCAL=1\n"), 0600)
+	os.WriteFile(filepath.Join(target.BaseDir, "node_modules", "lib", "index.js"), []byte("x"), 0644)
+	os.MkdirAll(target.Worktree, 0755)
+	return target
+}
+
+func TestRun(t *testing.T) {
+	target := setupTarget(t)
+	os.MkdirAll(filepath.Join(target.Worktree, ".bearing"), 0755)
+	os.WriteFile(filepath.Join(target.Worktree, ScriptPath), []byte("echo script on $BEARING_BRANCH > script.out\n"), 0644)
+
+	plan := Plan{
+		Copy:    []string{".env*", "missing.txt"},
+		Symlink: []string{"node_modules"},
+		Run:     []string{"echo hello from $BEARING_REPO"},
+	}
+	var out bytes.Buffer
+	if err := Run(context.Background(), plan, target, &out); err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, out.String())
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(target.Worktree, ".env.local")); string(data) != "LOCAL=1\n" {
+		t.Errorf("expected .env.local copied, got %q", data)
+	}
+	if info, err := os.Stat(filepath.Join(target.Worktree, ".env")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected .env copied with its mode, got %v %v", info, err)
+	}
+	if link, err := os.Readlink(filepath.Join(target.Worktree, "node_modules")); err != nil || link != filepath.Join(target.BaseDir, "node_modules") {
+		t.Errorf("expected node_modules linked to the base folder, got %q %v", link, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(target.Worktree, "script.out")); string(data) != "script on feat\n" {
+		t.Errorf("expected script to run in the worktree, got %q", data)
+	}
+	for _, want := range []string{"missing.txt not in base folder", "hello from app"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// Running again leaves what is already there
+	out.Reset()
+	if err := Run(context.Background(), Plan{Copy: []string{".env"}}, target, &out); err != nil || !strings.Contains(out.String(), ".env already exists") {
+		t.Errorf("expected existing file skipped, got %v\n%s", err, out.String())
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	target := setupTarget(t)
+
+	err := Run(context.Background(), Plan{Run: []string{"exit 3", "echo never"}}, target, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "exit 3 failed") {
+		t.Errorf("expected failing command error, got %v", err)
+	}
+
+	start := time.Now()
+	err = Run(context.Background(), Plan{Run: []string{"sleep 10 & sleep 10"}, Timeout: 200 * time.Millisecond}, target, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "timed out") || time.Since(start) > 5*time.Second {
+		t.Errorf("expected timeout, got %v after %v", err, time.Since(start))
+	}
+
+	if err := Run(context.Background(), Plan{Copy: []string{"../secrets"}}, target, &bytes.Buffer{}); err == nil {
+		t.Error("expected error for a path outside the repo")
+	}
+}
@@ -45,7 +45,7 @@ func init() {
 }
 
 func runAISummarize(cmd *cobra.Command, args []string) error {
-	store := jsonl.NewStore(WorkspaceDir())
+	store := openStore()
 
 	locals, _ := store.ReadLocal()
 	health, _ := store.ReadHealth()
@@ -71,7 +71,7 @@ Keep response under 200 words.`, len(locals), len(workflows), len(health))
 }
 
 func runAIClassify(cmd *cobra.Command, args []string) error {
-	store := jsonl.NewStore(WorkspaceDir())
+	store := openStore()
 	workflows, _ := store.ReadWorkflow()
 
 	if len(workflows) == 0 {
@@ -96,7 +96,7 @@ func runAIClassify(cmd *cobra.Command, args []string) error {
 }
 
 func runAISuggestFix(cmd *cobra.Command, args []string) error {
-	store := jsonl.NewStore(WorkspaceDir())
+	store := openStore()
 	health, _ := store.ReadHealth()
 	locals, _ := store.ReadLocal()
 
@@ -112,7 +112,7 @@ func runAISuggestFix(cmd *cobra.Command, args []string) error {
 		if local.Base && h.Dirty {
 			issues = append(issues, fmt.Sprintf("- %s: base folder has uncommitted changes", h.Folder))
 		}
-		if !local.Base && h.Unpushed > 0 {
+		if !local.Base && h.Unpushed > 0 && !h.Merged {
 			issues = append(issues, fmt.Sprintf("- %s: %d unpushed commits", h.Folder, h.Unpushed))
 		}
 	}
@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonObject is a JSON object that keeps its keys in order, so a settings
+// file can be edited without reordering or dropping keys bearing doesn't
+// know about
+type jsonObject struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+func (o *jsonObject) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return fmt.Errorf("expected a JSON object")
+	}
+	o.keys, o.values = nil, make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		if _, dup := o.values[key]; !dup {
+			o.keys = append(o.keys, key)
+		}
+		o.values[key] = value
+	}
+	_, err := dec.Token()
+	return err
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(o.values[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// get decodes the value of key into v and reports whether it was present
+func (o *jsonObject) get(key string, v any) (bool, error) {
+	raw, ok := o.values[key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("invalid %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// set stores v under key, appending the key if it is new
+func (o *jsonObject) set(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if o.values == nil {
+		o.values = make(map[string]json.RawMessage)
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = raw
+	return nil
+}
+
+// del removes key
+func (o *jsonObject) del(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// suiteHook is one Claude Code hook installed by bearing init
+type suiteHook struct {
+	Event   string
+	Matcher string // tool name pattern, for tool events
+	Args    string // bearing arguments
+}
+
+// hookSuite is every hook bearing init installs
+var hookSuite = []suiteHook{
+	{Event: "SessionStart", Args: "hook session-start"},
+	{Event: "UserPromptSubmit", Args: "worktree check --json"},
+	{Event: "PreToolUse", Matcher: "Bash|Write|Edit|MultiEdit|NotebookEdit", Args: "hook pre-tool-use"},
+	{Event: "Stop", Args: "hook stop"},              // end of every turn: activity only
+	{Event: "SessionEnd", Args: "hook session-end"}, // the session itself ends
+}
+
+// hookCommand returns the command for h, pinned to workspace if set
+func hookCommand(h suiteHook, workspace string) string {
+	if workspace == "" {
+		return "bearing " + h.Args
+	}
+	return fmt.Sprintf("bearing -w %s %s", shellQuote(workspace), h.Args)
+}
+
+// shellQuote quotes s for sh when it contains anything but safe characters
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isBearingHook reports whether command was installed by bearing init, in
+// this or an earlier version
+func isBearingHook(command string) bool {
+	if !strings.HasPrefix(command, "bearing ") {
+		return false
+	}
+	return strings.HasSuffix(command, " worktree check --json") || strings.Contains(command, " hook ")
+}
+
+// applyHookSuite installs suite into settings, pinned to workspace if set,
+// or with uninstall removes every bearing hook. Hooks that aren't
+// bearing's, and bearing hooks already installed as wanted, are left in
+// place, so applying the same suite twice changes nothing.
+func applyHookSuite(settings *jsonObject, suite []suiteHook, workspace string, uninstall bool) error {
+	var hooks jsonObject
+	if _, err := settings.get("hooks", &hooks); err != nil {
+		return err
+	}
+
+	type key struct{ event, matcher, command string }
+	wanted := make(map[key]bool)
+	if !uninstall {
+		for _, h := range suite {
+			wanted[key{h.Event, h.Matcher, hookCommand(h, workspace)}] = true
+		}
+	}
+	installed := make(map[key]bool)
+
+	for _, event := range append([]string(nil), hooks.keys...) {
+		var groups []jsonObject
+		if _, err := hooks.get(event, &groups); err != nil {
+			return err
+		}
+		kept := groups[:0]
+		for _, g := range groups {
+			var matcher string
+			g.get("matcher", &matcher)
+			var entries []jsonObject
+			if _, err := g.get("hooks", &entries); err != nil {
+				return err
+			}
+
+			keptEntries := entries[:0]
+			for _, e := range entries {
+				var command string
+				e.get("command", &command)
+				k := key{event, matcher, command}
+				if isBearingHook(command) && (!wanted[k] || installed[k]) {
+					continue
+				}
+				installed[k] = true
+				keptEntries = append(keptEntries, e)
+			}
+			if len(keptEntries) == len(entries) {
+				kept = append(kept, g)
+				continue
+			}
+			if len(keptEntries) > 0 {
+				g.set("hooks", keptEntries)
+				kept = append(kept, g)
+			}
+		}
+		if len(kept) == 0 {
+			hooks.del(event)
+		} else if err := hooks.set(event, kept); err != nil {
+			return err
+		}
+	}
+
+	if !uninstall {
+		for _, h := range suite {
+			command := hookCommand(h, workspace)
+			if installed[key{h.Event, h.Matcher, command}] {
+				continue
+			}
+			var group jsonObject
+			if h.Matcher != "" {
+				group.set("matcher", h.Matcher)
+			}
+			var entry jsonObject
+			entry.set("type", "command")
+			entry.set("command", command)
+			group.set("hooks", []jsonObject{entry})
+
+			var groups []jsonObject
+			hooks.get(h.Event, &groups)
+			if err := hooks.set(h.Event, append(groups, group)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(hooks.keys) == 0 {
+		settings.del("hooks")
+		return nil
+	}
+	return settings.set("hooks", hooks)
+}
+
+// formatSettings renders settings the way Claude Code writes them
+func formatSettings(settings jsonObject) ([]byte, error) {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
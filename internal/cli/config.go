@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshribakoff/bearing/internal/config"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+var (
+	configUser bool
+	configJSON bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and write bearing settings",
+	Long: `Read and write bearing settings.
+
+Settings are layered, lowest precedence first: built-in defaults,
+~/.bearing/config.yaml, then .bearing.yaml in the workspace. Each layer can
+override settings for one project under projects.<name>, for example:
+
+  defaultBranch: main
+  githubOwner: acme
+  projects:
+    legacy-app:
+      defaultBranch: master`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting, e.g. daemon.port",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Write a setting to .bearing.yaml (or the user file with --user)",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all settings and where each comes from",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigList,
+}
+
+func init() {
+	configGetCmd.Flags().BoolVar(&configJSON, "json", false, "output as JSON")
+	configSetCmd.Flags().BoolVar(&configUser, "user", false, "write ~/.bearing/config.yaml instead of the workspace file")
+	configListCmd.Flags().BoolVar(&configJSON, "json", false, "output as JSON")
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configListCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	s, ok := settings().Get(args[0])
+	if !ok {
+		return fmt.Errorf("unknown setting: %s", args[0])
+	}
+	if configJSON {
+		return json.NewEncoder(os.Stdout).Encode(s)
+	}
+	fmt.Println(formatSetting(s.Value))
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	path := filepath.Join(WorkspaceDir(), config.WorkspaceFile)
+	if configUser {
+		path = filepath.Join(BearingDir(), config.UserFile)
+	}
+	if err := settings().Set(path, args[0], args[1]); err != nil {
+		return err
+	}
+	fmt.Printf("Set %s = %s in %s\n", args[0], args[1], path)
+	return nil
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	list := settings().List()
+	if configJSON {
+		return json.NewEncoder(os.Stdout).Encode(list)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, s := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, formatSetting(s.Value), s.Source)
+	}
+	return w.Flush()
+}
+
+// formatSetting renders a value as single-line YAML
+func formatSetting(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	setFlowStyle(node)
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func setFlowStyle(n *yaml.Node) {
+	n.Style |= yaml.FlowStyle
+	for _, c := range n.Content {
+		setFlowStyle(c)
+	}
+}
@@ -41,7 +41,7 @@ var daemonStatusCmd = &cobra.Command{
 }
 
 func init() {
-	daemonStartCmd.Flags().IntVar(&daemonInterval, "interval", 300, "check interval in seconds")
+	daemonStartCmd.Flags().IntVar(&daemonInterval, "interval", 0, "check interval in seconds (default: daemon.interval setting)")
 	daemonStartCmd.Flags().BoolVar(&daemonForeground, "foreground", false, "run in foreground")
 	daemonStatusCmd.Flags().BoolVar(&daemonStatusJSON, "json", false, "output as JSON")
 
@@ -60,10 +60,23 @@ func newDaemon() *daemon.Daemon {
 		webDir = filepath.Join(BearingDir(), "..", "web")
 	}
 
+	interval := daemonInterval
+	if interval <= 0 {
+		interval = settings().Daemon.Interval
+	}
+
 	config := daemon.Config{
 		WorkspaceDir: WorkspaceDir(),
 		BearingDir:   BearingDir(),
-		Interval:     time.Duration(daemonInterval) * time.Second,
+		Interval:     time.Duration(interval) * time.Second,
+		HTTPPort:     settings().Daemon.Port,
+		Workers:      settings().Daemon.Workers,
+		Timeout:      time.Duration(settings().Daemon.Timeout) * time.Second,
+		Store:        openStore(),
+	}
+	if settings().GC.Auto {
+		config.GC = daemonGC
+		config.GCInterval = time.Duration(settings().GC.Interval) * time.Hour
 	}
 
 	if info, err := os.Stat(webDir); err == nil && info.IsDir() {
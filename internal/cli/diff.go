@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unifiedDiff returns a unified diff of the lines of a and b with three
+// lines of context, or "" if they are equal. Meant for small files such
+// as settings; it is quadratic in the number of lines.
+func unifiedDiff(name string, a, b string) string {
+	if a == b {
+		return ""
+	}
+	x, y := splitDiffLines(a), splitDiffLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte // ' ', '-' or '+'
+		line string
+		i, j int // line numbers in a and b before this op
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, op{' ', x[i], i, j})
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', x[i], i, j})
+			i++
+		default:
+			ops = append(ops, op{'+', y[j], i, j})
+			j++
+		}
+	}
+
+	const context = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		// Grow the hunk until changes are more than two contexts apart
+		lo := max(start-context, 0)
+		end := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				end = k
+			} else if k-end > 2*context {
+				break
+			}
+		}
+		hi := min(end+context+1, len(ops))
+
+		aLen, bLen := 0, 0
+		for _, o := range ops[lo:hi] {
+			if o.kind != '+' {
+				aLen++
+			}
+			if o.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(ops[lo].i, aLen), hunkRange(ops[lo].j, bLen))
+		for _, o := range ops[lo:hi] {
+			fmt.Fprintf(&out, "%c%s\n", o.kind, o.line)
+		}
+		start = hi
+	}
+	return out.String()
+}
+
+// hunkRange formats the start,count of a hunk side; start is 0-based
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
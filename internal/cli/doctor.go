@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorQuarantine bool
+	doctorJSON       bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose workspace state",
+}
+
+var doctorJSONLCmd = &cobra.Command{
+	Use:   "jsonl",
+	Short: "Check workspace JSONL files for malformed lines",
+	Long: `Check workspace JSONL files for malformed lines.
+
+Reads skip lines that fail to parse, so a bad merge or a partial write can
+hide worktrees from every view. doctor jsonl lists each such line with its
+file, line number and parse error. With --quarantine the lines are moved
+into a *.rejected.jsonl sidecar next to the file, so nothing is lost.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctorJSONL,
+}
+
+func init() {
+	doctorJSONLCmd.Flags().BoolVar(&doctorQuarantine, "quarantine", false, "move malformed lines into *.rejected.jsonl")
+	doctorJSONLCmd.Flags().BoolVar(&doctorJSON, "json", false, "output as JSON")
+	doctorCmd.AddCommand(doctorJSONLCmd)
+	rootCmd.AddCommand(doctorCmd)
+}
+
+type doctorProblem struct {
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Error       string `json:"error"`
+	Text        string `json:"text"`
+	Quarantined string `json:"quarantined,omitempty"`
+}
+
+func runDoctorJSONL(cmd *cobra.Command, args []string) error {
+	store := openFileStore()
+
+	var found []*jsonl.ParseError
+	var err error
+	if doctorQuarantine {
+		found, err = store.Quarantine()
+	} else {
+		found, err = store.Check()
+	}
+	if err != nil {
+		return err
+	}
+
+	problems := make([]doctorProblem, 0, len(found))
+	for _, p := range found {
+		problem := doctorProblem{
+			File:  p.Path,
+			Line:  p.Line,
+			Error: p.Err.Error(),
+			Text:  p.Text,
+		}
+		if doctorQuarantine {
+			problem.Quarantined = jsonl.RejectedPath(p.Path)
+		}
+		problems = append(problems, problem)
+	}
+
+	if doctorJSON {
+		if err := json.NewEncoder(os.Stdout).Encode(problems); err != nil {
+			return err
+		}
+	} else if len(problems) == 0 {
+		fmt.Println("✓ All JSONL files parse cleanly")
+	} else {
+		for _, p := range found {
+			fmt.Printf("✗ %v\n", p)
+			fmt.Printf("  %s\n", truncateLine(p.Text, 120))
+		}
+		if doctorQuarantine {
+			fmt.Printf("\nMoved %d line(s) into *.rejected.jsonl sidecars.\n", len(found))
+		} else {
+			fmt.Println("\nRun 'bearing doctor jsonl --quarantine' to move them into *.rejected.jsonl sidecars.")
+		}
+	}
+
+	// Quarantined lines are no longer in the files, so only a plain check fails
+	if len(problems) > 0 && !doctorQuarantine {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("found %d malformed line(s)", len(problems))
+	}
+	return nil
+}
+
+// truncateLine shortens s to at most n runes for display
+func truncateLine(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Entry points for Claude Code hooks",
+	Long: `Entry points for Claude Code hooks.
+
+Each subcommand reads the hook input Claude Code writes to stdin, such as
+the session ID and working directory.`,
+}
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+}
+
+// hookInput is the part of the JSON Claude Code passes hooks on stdin
+// that bearing uses
+type hookInput struct {
+	SessionID     string `json:"session_id"`
+	Cwd           string `json:"cwd"`
+	HookEventName string `json:"hook_event_name"`
+	Source        string `json:"source,omitempty"` // SessionStart: startup, resume, clear or compact
+	ToolName      string `json:"tool_name,omitempty"`
+	ToolInput     struct {
+		Command      string `json:"command,omitempty"`       // Bash
+		FilePath     string `json:"file_path,omitempty"`     // Write, Edit, MultiEdit
+		NotebookPath string `json:"notebook_path,omitempty"` // NotebookEdit
+	} `json:"tool_input"`
+}
+
+// readHookInput decodes hook input from stdin unless it is a terminal, so
+// running a hook by hand never blocks. Claude Code may pass a pipe, a file
+// or a socket. Anything unreadable yields an empty input.
+func readHookInput(r io.Reader) hookInput {
+	var in hookInput
+	if f, ok := r.(*os.File); ok {
+		if info, err := f.Stat(); err != nil || info.Mode()&os.ModeCharDevice != 0 {
+			return in
+		}
+	}
+	json.NewDecoder(r).Decode(&in)
+	if in.Cwd == "" {
+		in.Cwd, _ = os.Getwd()
+	}
+	return in
+}
+
+// errNoSession is returned by hooks that need a session ID but got none
+var errNoSession = errors.New("hook input has no session_id")
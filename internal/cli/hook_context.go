@@ -0,0 +1,322 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	contextMaxTokens int
+	contextPlain     bool
+)
+
+var hookContextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Summarize workspace state for an agent (SessionStart hook)",
+	Long: `Summarize workspace state for an agent (SessionStart hook).
+
+Lists the worktrees, leases, agent sessions, pull requests and open plans
+from local.jsonl, health.jsonl, workflow.jsonl, leases.jsonl and plans/,
+scoped to the repo of the hook's working directory when it is inside one.
+The summary is printed as additionalContext for Claude Code, or as text
+with --plain, and trimmed to about --max-tokens tokens.
+
+bearing hook session-start prints the same summary.`,
+	Args: cobra.NoArgs,
+	RunE: runHookContext,
+}
+
+func init() {
+	hookContextCmd.Flags().IntVar(&contextMaxTokens, "max-tokens", 800, "approximate size limit of the summary")
+	hookContextCmd.Flags().BoolVar(&contextPlain, "plain", false, "print the summary as text instead of hook output")
+	hookCmd.AddCommand(hookContextCmd)
+}
+
+func runHookContext(cmd *cobra.Command, args []string) error {
+	in := readHookInput(os.Stdin)
+	summary, err := workspaceContext(in)
+	if err != nil {
+		return err
+	}
+	if contextPlain {
+		fmt.Print(summary)
+		return nil
+	}
+	event := in.HookEventName
+	if event == "" {
+		event = "SessionStart"
+	}
+	return writeHookContext(event, summary)
+}
+
+// contextInput is the workspace state a context summary is built from
+type contextInput struct {
+	Workspace string
+	Folder    string // where the agent is; empty outside any worktree
+	SessionID string
+	Statuses  []worktreeStatus
+	Workflow  []jsonl.WorkflowEntry
+	Leases    []jsonl.LeaseEntry
+	Conflicts map[string][]string // by folder, from health.jsonl
+	Plans     []contextPlan
+	Now       time.Time
+}
+
+// contextPlan is a plan file summarized for agents
+type contextPlan struct {
+	Project string
+	Title   string
+	Status  string
+	Issue   string
+}
+
+// workspaceContext gathers workspace state from the store and plans/ and
+// summarizes it for the agent described by in, without live git queries
+func workspaceContext(in hookInput) (string, error) {
+	statuses, err := collectWorktreeStatus(false, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to read local.jsonl: %w", err)
+	}
+	store := openStore()
+	c := contextInput{
+		Workspace: WorkspaceDir(),
+		Folder:    session.Folder(WorkspaceDir(), in.Cwd),
+		SessionID: in.SessionID,
+		Statuses:  statuses,
+		Conflicts: make(map[string][]string),
+		Plans:     readContextPlans(filepath.Join(WorkspaceDir(), "plans")),
+		Now:       time.Now(),
+	}
+	c.Workflow, _ = store.ReadWorkflow()
+	c.Leases, _ = store.ReadLeases()
+	health, _ := store.ReadHealth()
+	for _, h := range health {
+		c.Conflicts[h.Folder] = h.ConflictsWith
+	}
+	return buildContextSummary(c, contextMaxTokens), nil
+}
+
+// closedPlanStatuses are plan statuses left out of the summary
+var closedPlanStatuses = map[string]bool{"done": true, "closed": true, "completed": true, "merged": true}
+
+// readContextPlans reads the open plans under dir
+func readContextPlans(dir string) []contextPlan {
+	var plans []contextPlan
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		project, _, ok := strings.Cut(rel, string(filepath.Separator))
+		if !ok {
+			return nil
+		}
+		fm, body, err := parsePlanFile(path)
+		if err != nil {
+			return nil
+		}
+		heading, status := planHeading(body)
+		if fm.Status == "" {
+			fm.Status = status
+		}
+		if closedPlanStatuses[strings.ToLower(fm.Status)] {
+			return nil
+		}
+		title := fm.Title
+		if title == "" {
+			title = heading
+		}
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(path), ".md")
+		}
+		plans = append(plans, contextPlan{Project: project, Title: title, Status: fm.Status, Issue: fm.Issue})
+		return nil
+	})
+	return plans
+}
+
+// planHeading returns the first top-level heading in body and the status
+// from a "## Status: ..." heading, for plans without frontmatter
+func planHeading(body string) (title, status string) {
+	for _, line := range strings.Split(body, "\n") {
+		if t, ok := strings.CutPrefix(line, "# "); ok && title == "" {
+			title = strings.TrimSpace(t)
+		}
+		if s, ok := strings.CutPrefix(line, "## Status:"); ok && status == "" {
+			status = strings.TrimSpace(s)
+		}
+	}
+	return title, status
+}
+
+// contextSection is a titled list of summary lines, trimmed from the end
+// when over budget
+type contextSection struct {
+	title string
+	lines []string
+}
+
+// buildContextSummary renders c as a compact summary of roughly maxTokens
+// tokens (estimated as four characters each). Sections come in priority
+// order; lines that don't fit are counted instead of shown.
+func buildContextSummary(c contextInput, maxTokens int) string {
+	byFolder := make(map[string]worktreeStatus, len(c.Statuses))
+	for _, s := range c.Statuses {
+		byFolder[s.Folder] = s
+	}
+	leases := make(map[string]jsonl.LeaseEntry)
+	for _, l := range c.Leases {
+		if !l.Expired(c.Now) {
+			leases[l.Folder] = l
+		}
+	}
+	purposes := make(map[string]string)
+	for _, w := range c.Workflow {
+		if w.Status == "active" && w.Purpose != "" {
+			purposes[w.Repo+"/"+w.Branch] = w.Purpose
+		}
+	}
+
+	var header []string
+	header = append(header, fmt.Sprintf("Bearing workspace %s. Base folders stay on their base branch; do work in worktrees (bearing worktree new <repo> <branch>).", c.Workspace))
+
+	repo := ""
+	if here, ok := byFolder[c.Folder]; ok {
+		repo = here.Repo
+		if here.Base {
+			header = append(header, fmt.Sprintf("You are in base folder %s of %s on %s. Do not commit or switch branches here; create a worktree first.", here.Folder, here.Repo, here.Branch))
+		} else {
+			header = append(header, fmt.Sprintf("You are in worktree %s of %s on branch %s. Commit your work here.", here.Folder, here.Repo, here.Branch))
+		}
+		if p := purposes[here.Repo+"/"+here.Branch]; p != "" {
+			header = append(header, "Purpose: "+p)
+		}
+		if l, ok := leases[here.Folder]; ok {
+			if l.Owner == c.SessionID {
+				header = append(header, fmt.Sprintf("You hold its lease until %s.", l.Expires.Local().Format(time.Kitchen)))
+			} else {
+				header = append(header, fmt.Sprintf("It is claimed by %s until %s; do not work here.", l.Owner, l.Expires.Local().Format(time.Kitchen)))
+			}
+		}
+	}
+
+	// Worktrees of the current repo, or of every repo at the workspace root
+	var worktrees contextSection
+	otherRepos := make(map[string]int)
+	for _, s := range c.Statuses {
+		if repo != "" && s.Repo != repo {
+			otherRepos[s.Repo]++
+			continue
+		}
+		worktrees.lines = append(worktrees.lines, "- "+contextWorktreeLine(c, s, leases))
+	}
+	worktrees.title = "Worktrees:"
+	if repo != "" {
+		worktrees.title = "Worktrees of " + repo + ":"
+	}
+	if len(otherRepos) > 0 {
+		names := make([]string, 0, len(otherRepos))
+		for name, n := range otherRepos {
+			names = append(names, fmt.Sprintf("%s (%d)", name, n))
+		}
+		sort.Strings(names)
+		worktrees.lines = append(worktrees.lines, "- other repos: "+strings.Join(names, ", "))
+	}
+
+	var plans contextSection
+	plans.title = "Open plans:"
+	if repo != "" {
+		plans.title = "Open plans for " + repo + ":"
+	}
+	for _, p := range c.Plans {
+		if repo != "" && p.Project != repo {
+			continue
+		}
+		line := "- " + p.Title
+		if repo == "" {
+			line = "- " + p.Project + ": " + p.Title
+		}
+		if p.Status != "" {
+			line += " [" + p.Status + "]"
+		}
+		if p.Issue != "" {
+			line += " #" + p.Issue
+		}
+		plans.lines = append(plans.lines, line)
+	}
+
+	budget := maxTokens * 4
+	var out strings.Builder
+	for _, line := range header {
+		out.WriteString(line + "\n")
+	}
+	for _, sec := range []contextSection{worktrees, plans} {
+		if len(sec.lines) == 0 {
+			continue
+		}
+		out.WriteString(sec.title + "\n")
+		for i, line := range sec.lines {
+			if out.Len()+len(line) > budget {
+				fmt.Fprintf(&out, "- ... %d more\n", len(sec.lines)-i)
+				break
+			}
+			out.WriteString(line + "\n")
+		}
+	}
+	return out.String()
+}
+
+// contextWorktreeLine describes one worktree in a few words
+func contextWorktreeLine(c contextInput, s worktreeStatus, leases map[string]jsonl.LeaseEntry) string {
+	line := s.Folder + " (" + s.Branch
+	if s.Base {
+		line += ", base"
+	}
+	line += ")"
+
+	var notes []string
+	if s.Folder == c.Folder {
+		notes = append(notes, "you are here")
+	}
+	if s.Dirty {
+		notes = append(notes, "uncommitted changes")
+	}
+	if s.Unpushed > 0 {
+		notes = append(notes, fmt.Sprintf("%d unpushed", s.Unpushed))
+	}
+	if s.Merged {
+		notes = append(notes, "merged into "+s.BasedOn)
+	}
+	if s.Operation != "" {
+		notes = append(notes, s.Operation+" in progress")
+	}
+	if s.PRState != nil {
+		pr := "PR " + strings.ToLower(*s.PRState)
+		if s.PRTitle != nil {
+			pr += fmt.Sprintf(" %q", *s.PRTitle)
+		}
+		notes = append(notes, pr)
+	}
+	if l, ok := leases[s.Folder]; ok && l.Owner != c.SessionID {
+		notes = append(notes, "claimed by "+l.Owner)
+	}
+	if s.Session != nil && s.SessionState == session.StateActive && s.Session.ID != c.SessionID {
+		notes = append(notes, "another agent active")
+	}
+	if others := c.Conflicts[s.Folder]; len(others) > 0 {
+		notes = append(notes, "conflicts with "+strings.Join(others, ", "))
+	}
+	if len(notes) > 0 {
+		line += ": " + strings.Join(notes, "; ")
+	}
+	return line
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+func testContextInput() contextInput {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	open := "OPEN"
+	title := "Add login"
+	return contextInput{
+		Workspace: "/w",
+		Folder:    "app-login",
+		SessionID: "me",
+		Statuses: []worktreeStatus{
+			{Folder: "app", Repo: "app", Branch: "main", Base: true},
+			{Folder: "app-login", Repo: "app", Branch: "login", Dirty: true, PRState: &open, PRTitle: &title},
+			{Folder: "app-api", Repo: "app", Branch: "api", Unpushed: 2},
+			{Folder: "lib", Repo: "lib", Branch: "main", Base: true},
+		},
+		Workflow: []jsonl.WorkflowEntry{{Repo: "app", Branch: "login", Status: "active", Purpose: "OAuth login"}},
+		Leases: []jsonl.LeaseEntry{
+			{Folder: "app-api", Owner: "other", Expires: now.Add(time.Hour)},
+			{Folder: "app", Owner: "gone", Expires: now.Add(-time.Hour)},
+		},
+		Conflicts: map[string][]string{"app-api": {"app-login"}},
+		Plans: []contextPlan{
+			{Project: "app", Title: "Login flow", Status: "active", Issue: "12"},
+			{Project: "lib", Title: "Unrelated"},
+		},
+		Now: now,
+	}
+}
+
+func TestBuildContextSummary(t *testing.T) {
+	summary := buildContextSummary(testContextInput(), 800)
+
+	for _, want := range []string{
+		"You are in worktree app-login of app on branch login",
+		"Purpose: OAuth login",
+		"Worktrees of app:",
+		`- app-login (login): you are here; uncommitted changes; PR open "Add login"`,
+		"- app-api (api): 2 unpushed; claimed by other; conflicts with app-login",
+		"- other repos: lib (1)",
+		"Open plans for app:\n- Login flow [active] #12",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+	if strings.Contains(summary, "Unrelated") || strings.Contains(summary, "gone") {
+		t.Errorf("summary not scoped to the repo or kept an expired lease:\n%s", summary)
+	}
+}
+
+func TestBuildContextSummaryBudget(t *testing.T) {
+	c := testContextInput()
+	c.Folder = "" // at the workspace root every repo is listed
+	for i := 0; i < 100; i++ {
+		c.Plans = append(c.Plans, contextPlan{Project: "app", Title: strings.Repeat("x", 40)})
+	}
+
+	summary := buildContextSummary(c, 200)
+	if len(summary) > 200*4+50 {
+		t.Errorf("summary exceeds budget: %d bytes", len(summary))
+	}
+	if !strings.Contains(summary, "- lib (main, base)") || !strings.Contains(summary, "more\n") {
+		t.Errorf("expected all worktrees and trimmed plans:\n%s", summary)
+	}
+}
+
+func TestReadContextPlans(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "app"), 0755)
+	os.WriteFile(filepath.Join(dir, "app", "001-a.md"), []byte("---\ntitle: Open plan\nissue: 3\n---\nbody\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "app", "002-b.md"), []byte("---\ntitle: Finished\nstatus: done\n---\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "app", "003-c.md"), []byte("# Heading plan\n\n## Status: Future\n"), 0644)
+
+	plans := readContextPlans(dir)
+	if len(plans) != 2 {
+		t.Fatalf("expected two open plans, got %+v", plans)
+	}
+	if plans[0].Title != "Open plan" || plans[0].Issue != "3" {
+		t.Errorf("unexpected plan: %+v", plans[0])
+	}
+	if plans[1].Title != "Heading plan" || plans[1].Status != "Future" {
+		t.Errorf("unexpected plan without frontmatter: %+v", plans[1])
+	}
+}
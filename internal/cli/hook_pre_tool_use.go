@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/guard"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/spf13/cobra"
+)
+
+var hookPreToolUseCmd = &cobra.Command{
+	Use:   "pre-tool-use",
+	Short: "Block risky tool calls in base folders (PreToolUse hook)",
+	Long: `Block risky tool calls in base folders (PreToolUse hook).
+
+Bash commands are checked for branch switches, git reset --hard and
+commits, and file edits by path, wherever they land in a base folder
+registered in local.jsonl. Each rule is allowed, asked about or denied as
+set under guard in the config, and decisions are recorded in audit.jsonl
+unless guard.audit is false.`,
+	Args: cobra.NoArgs,
+	RunE: runHookPreToolUse,
+}
+
+func init() {
+	hookCmd.AddCommand(hookPreToolUseCmd)
+}
+
+// preToolUseOutput is the PreToolUse hook response Claude Code reads
+type preToolUseOutput struct {
+	HookSpecificOutput struct {
+		HookEventName            string `json:"hookEventName"`
+		PermissionDecision       string `json:"permissionDecision"`
+		PermissionDecisionReason string `json:"permissionDecisionReason"`
+	} `json:"hookSpecificOutput"`
+}
+
+func runHookPreToolUse(cmd *cobra.Command, args []string) error {
+	in := readHookInput(os.Stdin)
+	call := guard.Call{
+		Tool:    in.ToolName,
+		Cwd:     in.Cwd,
+		Command: in.ToolInput.Command,
+		Path:    in.ToolInput.FilePath,
+	}
+	if call.Path == "" {
+		call.Path = in.ToolInput.NotebookPath
+	}
+
+	store := openStore()
+	local, err := store.ReadLocal()
+	if err != nil {
+		return fmt.Errorf("failed to read local.jsonl: %w", err)
+	}
+	decisions := guard.Evaluate(WorkspaceDir(), local, guard.PolicyFrom(settings().Guard), call)
+
+	if settings().Guard.Audit {
+		now := time.Now()
+		for _, d := range decisions {
+			entry := jsonl.AuditEntry{
+				Time:      now,
+				SessionID: in.SessionID,
+				Tool:      call.Tool,
+				Folder:    d.Folder,
+				Cwd:       call.Cwd,
+				Command:   call.Command,
+				Path:      call.Path,
+				Rule:      d.Rule,
+				Decision:  d.Action,
+				Reason:    d.Reason,
+			}
+			if err := store.AppendAudit(entry); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to write audit.jsonl: %v\n", err)
+			}
+		}
+	}
+
+	// Allowed calls print nothing, leaving the usual permission checks
+	d := guard.Strictest(decisions)
+	if d == nil || d.Action == guard.Allow {
+		return nil
+	}
+	var out preToolUseOutput
+	out.HookSpecificOutput.HookEventName = "PreToolUse"
+	out.HookSpecificOutput.PermissionDecision = d.Action
+	out.HookSpecificOutput.PermissionDecisionReason = d.Reason
+	return json.NewEncoder(os.Stdout).Encode(out)
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var hookSessionStartCmd = &cobra.Command{
+	Use:   "session-start",
+	Short: "Record an agent session starting (SessionStart hook)",
+	Long: `Record an agent session starting (SessionStart hook).
+
+Also prints the workspace summary of bearing hook context, so the agent
+starts out knowing where it is and what else is going on.`,
+	Args: cobra.NoArgs,
+	RunE: runHookSessionStart,
+}
+
+var hookStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Record agent activity at the end of a turn (Stop hook)",
+	Long: `Record agent activity at the end of a turn (Stop hook).
+
+Claude Code runs Stop hooks whenever the agent finishes responding, not
+when the session ends, so this only keeps the session live. session-end
+ends it.`,
+	Args: cobra.NoArgs,
+	RunE: runHookStop,
+}
+
+var hookSessionEndCmd = &cobra.Command{
+	Use:   "session-end",
+	Short: "Record an agent session ending (SessionEnd hook)",
+	Args:  cobra.NoArgs,
+	RunE:  runHookSessionEnd,
+}
+
+func init() {
+	hookCmd.AddCommand(hookSessionStartCmd)
+	hookCmd.AddCommand(hookStopCmd)
+	hookCmd.AddCommand(hookSessionEndCmd)
+}
+
+func runHookSessionStart(cmd *cobra.Command, args []string) error {
+	in := readHookInput(os.Stdin)
+	if in.SessionID == "" {
+		return errNoSession
+	}
+	if _, err := session.Touch(openStore(), WorkspaceDir(), in.SessionID, in.Cwd, time.Now()); err != nil {
+		return fmt.Errorf("failed to record session: %w", err)
+	}
+	summary, err := workspaceContext(in)
+	if err != nil {
+		return err
+	}
+	return writeHookContext("SessionStart", summary)
+}
+
+func runHookStop(cmd *cobra.Command, args []string) error {
+	in := readHookInput(os.Stdin)
+	if in.SessionID == "" {
+		return errNoSession
+	}
+	if _, err := session.Touch(openStore(), WorkspaceDir(), in.SessionID, in.Cwd, time.Now()); err != nil {
+		return fmt.Errorf("failed to record session: %w", err)
+	}
+	return nil
+}
+
+func runHookSessionEnd(cmd *cobra.Command, args []string) error {
+	in := readHookInput(os.Stdin)
+	if in.SessionID == "" {
+		return errNoSession
+	}
+	if _, err := session.Stop(openStore(), WorkspaceDir(), in.SessionID, in.Cwd, time.Now()); err != nil {
+		return fmt.Errorf("failed to record session: %w", err)
+	}
+	return nil
+}
+
+// hookContextOutput adds context to the agent's conversation
+type hookContextOutput struct {
+	HookSpecificOutput struct {
+		HookEventName     string `json:"hookEventName"`
+		AdditionalContext string `json:"additionalContext"`
+	} `json:"hookSpecificOutput"`
+}
+
+// writeHookContext prints context for the agent in the hook output format
+func writeHookContext(event, context string) error {
+	var out hookContextOutput
+	out.HookSpecificOutput.HookEventName = event
+	out.HookSpecificOutput.AdditionalContext = context
+	return json.NewEncoder(os.Stdout).Encode(out)
+}
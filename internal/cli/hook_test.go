@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestReadHookInputSocket(t *testing.T) {
+	// Node starts hooks with socketpair stdio rather than pipes
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Skipf("socketpair unavailable: %v", err)
+	}
+	ours, theirs := os.NewFile(uintptr(fds[0]), "ours"), os.NewFile(uintptr(fds[1]), "theirs")
+	defer theirs.Close()
+	ours.WriteString(`{"session_id":"s1","cwd":"/w/app","hook_event_name":"PreToolUse","tool_name":"Bash","tool_input":{"command":"git checkout -b x"}}`)
+	ours.Close()
+
+	in := readHookInput(theirs)
+	if in.SessionID != "s1" || in.Cwd != "/w/app" || in.ToolInput.Command != "git checkout -b x" {
+		t.Errorf("unexpected input: %+v", in)
+	}
+}
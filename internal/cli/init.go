@@ -9,86 +9,108 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	initUninstall bool
+	initDryRun    bool
+	initUser      bool
+)
+
 var initCmd = &cobra.Command{
 	Use:   "init",
-	Short: "Initialize bearing hooks in the workspace",
-	RunE:  runInit,
+	Short: "Install bearing hooks into Claude Code settings",
+	Long: `Install bearing hooks into Claude Code settings.
+
+The hooks record agent sessions and orient them when they start, check
+workspace invariants before each prompt, block risky tool calls in base
+folders, and record when an agent stops. They are merged into
+.claude/settings.json in the workspace, or ~/.claude/settings.json with
+--user; other settings and hooks in the file are kept. Running init again
+changes nothing, and --uninstall removes only bearing's hooks.
+
+User-level hooks run in every project, so their commands are pinned to
+this workspace with --workspace.`,
+	Args: cobra.NoArgs,
+	RunE: runInit,
 }
 
 func init() {
+	initCmd.Flags().BoolVar(&initUninstall, "uninstall", false, "remove bearing hooks instead of installing them")
+	initCmd.Flags().BoolVar(&initDryRun, "dry-run", false, "print the change as a diff without writing it")
+	initCmd.Flags().BoolVar(&initUser, "user", false, "edit ~/.claude/settings.json instead of the workspace's")
 	rootCmd.AddCommand(initCmd)
 }
 
-// ClaudeSettings represents .claude/settings.json structure
-type ClaudeSettings struct {
-	Hooks map[string][]HookConfig `json:"hooks,omitempty"`
-}
-
-type HookConfig struct {
-	Hooks []Hook `json:"hooks"`
-}
-
-type Hook struct {
-	Type    string `json:"type"`
-	Command string `json:"command"`
-}
-
 func runInit(cmd *cobra.Command, args []string) error {
-	claudeDir := filepath.Join(WorkspaceDir(), ".claude")
-	settingsPath := filepath.Join(claudeDir, "settings.json")
-
-	// Ensure .claude directory exists
-	if err := os.MkdirAll(claudeDir, 0755); err != nil {
-		return err
+	settingsPath := filepath.Join(WorkspaceDir(), ".claude", "settings.json")
+	workspace := ""
+	if initUser {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home directory: %w", err)
+		}
+		settingsPath = filepath.Join(home, ".claude", "settings.json")
+		if workspace, err = filepath.Abs(WorkspaceDir()); err != nil {
+			return fmt.Errorf("failed to resolve workspace: %w", err)
+		}
 	}
 
-	// Load existing settings or create new
-	var settings ClaudeSettings
+	// Load existing settings; a file that doesn't parse is left alone
+	// rather than overwritten
+	var settings jsonObject
+	var before []byte
+	mode := os.FileMode(0644)
 	if data, err := os.ReadFile(settingsPath); err == nil {
-		json.Unmarshal(data, &settings)
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", settingsPath, err)
+		}
+		if before, err = formatSettings(settings); err != nil {
+			return err
+		}
+		if info, err := os.Stat(settingsPath); err == nil {
+			mode = info.Mode().Perm()
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", settingsPath, err)
 	}
 
-	if settings.Hooks == nil {
-		settings.Hooks = make(map[string][]HookConfig)
+	if err := applyHookSuite(&settings, hookSuite, workspace, initUninstall); err != nil {
+		return fmt.Errorf("failed to update %s: %w", settingsPath, err)
 	}
-
-	// Define the bearing hook command
-	bearingHook := Hook{
-		Type:    "command",
-		Command: "bearing worktree check --json",
+	after, err := formatSettings(settings)
+	if err != nil {
+		return err
+	}
+	if before == nil && initUninstall {
+		after = nil
 	}
 
-	// Check if hook already exists
-	existingHooks := settings.Hooks["UserPromptSubmit"]
-	hookExists := false
-	for _, hc := range existingHooks {
-		for _, h := range hc.Hooks {
-			if h.Command == bearingHook.Command {
-				hookExists = true
-				break
-			}
+	if string(before) == string(after) {
+		if initUninstall {
+			fmt.Printf("No bearing hooks in %s\n", settingsPath)
+		} else {
+			fmt.Printf("Hooks already configured in %s\n", settingsPath)
 		}
+		return nil
+	}
+	if initDryRun {
+		fmt.Print(unifiedDiff(settingsPath, string(before), string(after)))
+		return nil
 	}
 
-	if hookExists {
-		fmt.Println("Hook already configured in .claude/settings.json")
-	} else {
-		// Add the hook
-		settings.Hooks["UserPromptSubmit"] = append(existingHooks, HookConfig{
-			Hooks: []Hook{bearingHook},
-		})
-
-		// Write back
-		data, err := json.MarshalIndent(settings, "", "  ")
-		if err != nil {
-			return err
-		}
-		if err := os.WriteFile(settingsPath, data, 0644); err != nil {
-			return err
-		}
-		fmt.Println("Added bearing hook to .claude/settings.json")
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(settingsPath), err)
+	}
+	if err := os.WriteFile(settingsPath, after, mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", settingsPath, err)
 	}
 
-	fmt.Println("\nBearing initialized. The worktree-check hook will run on each prompt.")
+	if initUninstall {
+		fmt.Printf("Removed bearing hooks from %s\n", settingsPath)
+		return nil
+	}
+	fmt.Printf("Added bearing hooks to %s\n", settingsPath)
+	for _, h := range hookSuite {
+		fmt.Printf("  %-16s %s\n", h.Event, hookCommand(h, workspace))
+	}
 	return nil
 }
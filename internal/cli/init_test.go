@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const existingSettings = `{
+  "permissions": {"allow": ["Bash(make:*)"]},
+  "model": "opus",
+  "hooks": {
+    "UserPromptSubmit": [
+      {"hooks": [{"type": "command", "command": "bearing worktree check --json"}]}
+    ],
+    "PreToolUse": [
+      {"matcher": "Bash", "hooks": [{"type": "command", "command": "./lint-guard.sh", "timeout": 5}]}
+    ]
+  }
+}`
+
+func applyToSettings(t *testing.T, data string, workspace string, uninstall bool) string {
+	t.Helper()
+	var settings jsonObject
+	if err := json.Unmarshal([]byte(data), &settings); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyHookSuite(&settings, hookSuite, workspace, uninstall); err != nil {
+		t.Fatal(err)
+	}
+	out, err := formatSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestApplyHookSuite(t *testing.T) {
+	installed := applyToSettings(t, existingSettings, "", false)
+
+	// Unknown keys and foreign hooks survive, in their original order
+	if !strings.Contains(installed, `"Bash(make:*)"`) || !strings.Contains(installed, `"timeout": 5`) {
+		t.Errorf("lost existing settings:\n%s", installed)
+	}
+	if strings.Index(installed, `"permissions"`) > strings.Index(installed, `"model"`) {
+		t.Errorf("reordered keys:\n%s", installed)
+	}
+	for _, h := range hookSuite {
+		if strings.Count(installed, `"`+hookCommand(h, "")+`"`) != 1 {
+			t.Errorf("expected %s installed once:\n%s", h.Args, installed)
+		}
+	}
+
+	if again := applyToSettings(t, installed, "", false); again != installed {
+		t.Errorf("install is not idempotent:\n%s", unifiedDiff("settings.json", installed, again))
+	}
+
+	removed := applyToSettings(t, installed, "", true)
+	if strings.Contains(removed, "bearing") || strings.Contains(removed, "UserPromptSubmit") {
+		t.Errorf("expected bearing hooks removed:\n%s", removed)
+	}
+	if !strings.Contains(removed, "./lint-guard.sh") || !strings.Contains(removed, `"model"`) {
+		t.Errorf("uninstall removed foreign settings:\n%s", removed)
+	}
+}
+
+func TestApplyHookSuitePinsWorkspace(t *testing.T) {
+	out := applyToSettings(t, existingSettings, "/home/me/my work", false)
+	if !strings.Contains(out, `bearing -w '/home/me/my work' hook pre-tool-use`) {
+		t.Errorf("expected pinned commands:\n%s", out)
+	}
+	// The unpinned check from an earlier install is replaced
+	if strings.Contains(out, `"bearing worktree check --json"`) {
+		t.Errorf("expected unpinned hook replaced:\n%s", out)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	if d := unifiedDiff("f", "a\nb\n", "a\nb\n"); d != "" {
+		t.Errorf("expected no diff, got %q", d)
+	}
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n"
+	want := `--- f
++++ f
+@@ -2,8 +2,9 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+ 9
++10
+`
+	if d := unifiedDiff("f", a, b); d != want {
+		t.Errorf("unexpected diff:\n%s", d)
+	}
+	if d := unifiedDiff("f", "", "x\n"); !strings.Contains(d, "@@ -0,0 +1,1 @@\n+x\n") {
+		t.Errorf("unexpected diff for a new file:\n%s", d)
+	}
+}
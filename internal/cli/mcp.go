@@ -0,0 +1,432 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joshribakoff/bearing/internal/daemon"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve bearing operations as MCP tools over stdio",
+	Long: `Serve bearing operations as Model Context Protocol tools over stdio.
+
+Register with Claude Code:
+  claude mcp add bearing -- bearing -w ~/Projects mcp
+
+Stdout carries JSON-RPC only; diagnostics go to stderr.`,
+	Args: cobra.NoArgs,
+	RunE: runMCP,
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+}
+
+func runMCP(cmd *cobra.Command, args []string) error {
+	return newMCPServer().Serve(os.Stdin, os.Stdout)
+}
+
+// newMCPServer builds the MCP server with all bearing tools registered
+func newMCPServer() *mcp.Server {
+	s := mcp.NewServer("bearing", version)
+
+	s.AddTool(mcp.Tool{
+		Name:        "worktree_list",
+		Description: "List worktree folders from local.jsonl, or lifecycle entries from workflow.jsonl",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "repo": {"type": "string", "description": "only include this repo"},
+    "workflow": {"type": "boolean", "description": "list workflow.jsonl entries instead of local folders"}
+  }
+}`),
+		Handler: mcpWorktreeList,
+	})
+
+	s.AddTool(mcp.Tool{
+		Name:        "worktree_status",
+		Description: "Show worktrees with dirty state, unpushed commits and PR state",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "folder": {"type": "string", "description": "only include this folder"},
+    "refresh": {"type": "boolean", "description": "ignore cached health data and query git/gh"},
+    "cached": {"type": "boolean", "description": "use cached health data only"}
+  }
+}`),
+		Handler: mcpWorktreeStatus,
+	})
+
+	s.AddTool(mcp.Tool{
+		Name:        "worktree_new",
+		Description: "Create a worktree for a new branch and record it in workflow.jsonl and local.jsonl",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "repo": {"type": "string", "description": "base folder of the repo"},
+    "branch": {"type": "string", "description": "new branch name"},
+    "basedOn": {"type": "string", "description": "start point (default: main)"},
+    "purpose": {"type": "string", "description": "purpose description"}
+  },
+  "required": ["repo", "branch"]
+}`),
+		Handler: mcpWorktreeNew,
+	})
+
+	s.AddTool(mcp.Tool{
+		Name:        "worktree_cleanup",
+		Description: "Check a worktree is safe to remove, remove it, delete its branch if merged, and update manifests",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "repo": {"type": "string"},
+    "branch": {"type": "string"},
+    "force": {"type": "boolean", "description": "clean up despite uncommitted changes, unpushed commits, an open PR or a live lease or session"},
+    "archive": {"type": "string", "enum": ["none", "tag", "bundle"], "description": "save the branch before deleting it (default: cleanup.archive)"}
+  },
+  "required": ["repo", "branch"]
+}`),
+		Handler: mcpWorktreeCleanup,
+	})
+
+	s.AddTool(mcp.Tool{
+		Name:        "plan_create",
+		Description: "Create a new draft plan file with a short GUID identifier",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "project": {"type": "string"},
+    "title": {"type": "string"}
+  },
+  "required": ["project", "title"]
+}`),
+		Handler: mcpPlanCreate,
+	})
+
+	s.AddTool(mcp.Tool{
+		Name:        "plan_push",
+		Description: "Create or update the GitHub issue for a plan file",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "file": {"type": "string", "description": "path to the plan file"},
+    "dryRun": {"type": "boolean"}
+  },
+  "required": ["file"]
+}`),
+		Handler: mcpPlanPush,
+	})
+
+	s.AddTool(mcp.Tool{
+		Name:        "plan_pull",
+		Description: "Create a plan file from a GitHub issue",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "repo": {"type": "string"},
+    "issue": {"type": "string", "description": "issue number"}
+  },
+  "required": ["repo", "issue"]
+}`),
+		Handler: mcpPlanPull,
+	})
+
+	s.AddTool(mcp.Tool{
+		Name:        "health",
+		Description: "Read cached health data from health.jsonl, flagging worktrees that need attention",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "folder": {"type": "string", "description": "only include this folder"},
+    "attention": {"type": "boolean", "description": "only include worktrees that need attention"}
+  }
+}`),
+		Handler: mcpHealth,
+	})
+
+	return s
+}
+
+// requireArgs returns an invalid_arguments error naming the first empty value
+func requireArgs(pairs ...string) error {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if strings.TrimSpace(pairs[i+1]) == "" {
+			return mcp.Errorf("invalid_arguments", "%s is required", pairs[i])
+		}
+	}
+	return nil
+}
+
+func mcpWorktreeList(args json.RawMessage) (interface{}, error) {
+	var in struct {
+		Repo     string `json:"repo"`
+		Workflow bool   `json:"workflow"`
+	}
+	if err := mcp.DecodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	store := openStore()
+
+	if in.Workflow {
+		entries, err := store.ReadWorkflow()
+		if err != nil {
+			return nil, mcp.Errorf("store_error", "%v", err)
+		}
+		out := make([]jsonl.WorkflowEntry, 0, len(entries))
+		for _, e := range entries {
+			if in.Repo == "" || e.Repo == in.Repo {
+				out = append(out, e)
+			}
+		}
+		return map[string]interface{}{"workflow": out}, nil
+	}
+
+	entries, err := store.ReadLocal()
+	if err != nil {
+		return nil, mcp.Errorf("store_error", "%v", err)
+	}
+	out := make([]jsonl.LocalEntry, 0, len(entries))
+	for _, e := range entries {
+		if in.Repo == "" || e.Repo == in.Repo {
+			out = append(out, e)
+		}
+	}
+	return map[string]interface{}{"worktrees": out}, nil
+}
+
+func mcpWorktreeStatus(args json.RawMessage) (interface{}, error) {
+	var in struct {
+		Folder  string `json:"folder"`
+		Refresh bool   `json:"refresh"`
+		Cached  bool   `json:"cached"`
+	}
+	if err := mcp.DecodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	statuses, err := collectWorktreeStatus(in.Refresh, in.Cached)
+	if err != nil {
+		return nil, mcp.Errorf("store_error", "%v", err)
+	}
+
+	out := make([]worktreeStatus, 0, len(statuses))
+	for _, s := range statuses {
+		if in.Folder == "" || s.Folder == in.Folder {
+			out = append(out, s)
+		}
+	}
+	if in.Folder != "" && len(out) == 0 {
+		return nil, mcp.Errorf("not_found", "folder not registered: %s", in.Folder)
+	}
+	return map[string]interface{}{"worktrees": out}, nil
+}
+
+func mcpWorktreeNew(args json.RawMessage) (interface{}, error) {
+	var in struct {
+		Repo    string `json:"repo"`
+		Branch  string `json:"branch"`
+		BasedOn string `json:"basedOn"`
+		Purpose string `json:"purpose"`
+	}
+	if err := mcp.DecodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if err := requireArgs("repo", in.Repo, "branch", in.Branch); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(WorkspaceDir(), in.Repo)); os.IsNotExist(err) {
+		return nil, mcp.Errorf("not_found", "repo folder not found: %s", in.Repo)
+	}
+
+	result, err := createWorktree(in.Repo, in.Branch, in.BasedOn, in.Purpose)
+	if err != nil {
+		return nil, mcp.Errorf("worktree_failed", "%v", err)
+	}
+	// stdout carries the protocol, so bootstrap output goes to stderr; a
+	// failure is reported in the result since the worktree exists
+	result.Bootstrap, _ = bootstrapWorktree(result.Repo, result.Branch, result.Folder, os.Stderr)
+	return result, nil
+}
+
+func mcpWorktreeCleanup(args json.RawMessage) (interface{}, error) {
+	var in struct {
+		Repo    string `json:"repo"`
+		Branch  string `json:"branch"`
+		Force   bool   `json:"force"`
+		Archive string `json:"archive"`
+	}
+	if err := mcp.DecodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if err := requireArgs("repo", in.Repo, "branch", in.Branch); err != nil {
+		return nil, err
+	}
+	if in.Archive == "" {
+		in.Archive = settings().Cleanup.Archive
+	}
+	if in.Archive != "none" && in.Archive != "tag" && in.Archive != "bundle" {
+		return nil, mcp.Errorf("invalid_arguments", "archive must be none, tag or bundle")
+	}
+	folder := worktreeFolderName(in.Repo, in.Branch)
+	if _, err := os.Stat(filepath.Join(WorkspaceDir(), folder)); os.IsNotExist(err) {
+		return nil, mcp.Errorf("not_found", "worktree folder not found: %s", folder)
+	}
+
+	plan, err := planCleanup(in.Repo, in.Branch, "")
+	if err != nil {
+		return nil, mcp.Errorf("worktree_failed", "%v", err)
+	}
+	if len(plan.Problems) > 0 && !in.Force {
+		return nil, mcp.Errorf("unsafe", "refusing to clean up %s: %s (pass force to override)", folder, strings.Join(plan.Problems, "; "))
+	}
+	result, err := executeCleanup(plan, cleanupOptions{Force: in.Force, Archive: in.Archive})
+	if err != nil {
+		return nil, mcp.Errorf("worktree_failed", "%v", err)
+	}
+	return result, nil
+}
+
+func mcpPlanCreate(args json.RawMessage) (interface{}, error) {
+	var in struct {
+		Project string `json:"project"`
+		Title   string `json:"title"`
+	}
+	if err := mcp.DecodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if err := requireArgs("project", in.Project, "title", in.Title); err != nil {
+		return nil, err
+	}
+
+	planFile, err := createPlanFile(in.Project, in.Title)
+	if err != nil {
+		return nil, mcp.Errorf("plan_failed", "%v", err)
+	}
+	return map[string]string{"file": planFile}, nil
+}
+
+func mcpPlanPush(args json.RawMessage) (interface{}, error) {
+	var in struct {
+		File   string `json:"file"`
+		DryRun bool   `json:"dryRun"`
+	}
+	if err := mcp.DecodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if err := requireArgs("file", in.File); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(in.File); os.IsNotExist(err) {
+		return nil, mcp.Errorf("not_found", "plan file not found: %s", in.File)
+	}
+
+	// Progress messages must stay off stdout, which carries the protocol
+	result, err := pushPlan(in.File, in.DryRun, os.Stderr)
+	if err != nil {
+		return nil, mcp.Errorf("plan_failed", "%v", err)
+	}
+	return result, nil
+}
+
+func mcpPlanPull(args json.RawMessage) (interface{}, error) {
+	var in struct {
+		Repo  string `json:"repo"`
+		Issue string `json:"issue"`
+	}
+	if err := mcp.DecodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if err := requireArgs("repo", in.Repo, "issue", in.Issue); err != nil {
+		return nil, err
+	}
+	if !isNumeric(in.Issue) {
+		return nil, mcp.Errorf("invalid_arguments", "issue must be numeric, got: %q", in.Issue)
+	}
+
+	planFile, err := pullPlan(in.Repo, in.Issue)
+	if err != nil {
+		return nil, mcp.Errorf("plan_failed", "%v", err)
+	}
+	return map[string]string{"file": planFile}, nil
+}
+
+// mcpHealthEntry is a health.jsonl entry annotated for agents
+type mcpHealthEntry struct {
+	jsonl.HealthEntry
+	Repo           string `json:"repo,omitempty"`
+	Branch         string `json:"branch,omitempty"`
+	Base           bool   `json:"base"`
+	NeedsAttention bool   `json:"needsAttention"`
+}
+
+func mcpHealth(args json.RawMessage) (interface{}, error) {
+	var in struct {
+		Folder    string `json:"folder"`
+		Attention bool   `json:"attention"`
+	}
+	if err := mcp.DecodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	store := openStore()
+
+	var health []jsonl.HealthEntry
+	var locals []jsonl.LocalEntry
+	var err error
+	if in.Folder != "" {
+		health, locals, err = folderHealth(store, in.Folder)
+	} else {
+		health, err = store.ReadHealth()
+		if err == nil {
+			locals, err = store.ReadLocal()
+		}
+	}
+	if err != nil {
+		return nil, mcp.Errorf("store_error", "%v", err)
+	}
+	localMap := make(map[string]jsonl.LocalEntry)
+	for _, l := range locals {
+		localMap[l.Folder] = l
+	}
+
+	out := make([]mcpHealthEntry, 0, len(health))
+	for _, h := range health {
+		if in.Folder != "" && h.Folder != in.Folder {
+			continue
+		}
+		l := localMap[h.Folder]
+		e := mcpHealthEntry{
+			HealthEntry:    h,
+			Repo:           l.Repo,
+			Branch:         l.Branch,
+			Base:           l.Base,
+			NeedsAttention: daemon.NeedsAttention(h, l),
+		}
+		if in.Attention && !e.NeedsAttention {
+			continue
+		}
+		out = append(out, e)
+	}
+	if in.Folder != "" && len(out) == 0 && !in.Attention {
+		return nil, mcp.Errorf("not_found", "no health data for folder: %s", in.Folder)
+	}
+	return map[string]interface{}{"health": out}, nil
+}
+
+// folderHealth looks up the health and local entries of one folder
+func folderHealth(store jsonl.Store, folder string) ([]jsonl.HealthEntry, []jsonl.LocalEntry, error) {
+	h, err := store.FindHealth(folder)
+	if err != nil || h == nil {
+		return nil, nil, err
+	}
+	l, err := store.FindLocal(folder)
+	if err != nil || l == nil {
+		return []jsonl.HealthEntry{*h}, nil, err
+	}
+	return []jsonl.HealthEntry{*h}, []jsonl.LocalEntry{*l}, nil
+}
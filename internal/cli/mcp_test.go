@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func callMCPTool(t *testing.T, name, args string) map[string]interface{} {
+	t.Helper()
+	req := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + name + `","arguments":` + args + `}}`
+	var out bytes.Buffer
+	if err := newMCPServer().Serve(strings.NewReader(req+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Result map[string]interface{} `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", out.String(), err)
+	}
+	return resp.Result
+}
+
+func TestMCPWorktreeList(t *testing.T) {
+	dir := t.TempDir()
+	workspaceDir = dir
+	defer func() { workspaceDir = "" }()
+
+	local := `{"folder":"app","repo":"app","branch":"main","base":true}
+{"folder":"other","repo":"other","branch":"main","base":true}
+`
+	os.WriteFile(filepath.Join(dir, "local.jsonl"), []byte(local), 0644)
+
+	result := callMCPTool(t, "worktree_list", `{"repo":"app"}`)
+	if result["isError"] == true {
+		t.Fatalf("unexpected error: %v", result)
+	}
+	worktrees := result["structuredContent"].(map[string]interface{})["worktrees"].([]interface{})
+	if len(worktrees) != 1 {
+		t.Fatalf("expected 1 worktree, got %d", len(worktrees))
+	}
+	if worktrees[0].(map[string]interface{})["folder"] != "app" {
+		t.Errorf("expected app, got %v", worktrees[0])
+	}
+}
+
+func TestMCPWorktreeNew_MissingArgs(t *testing.T) {
+	workspaceDir = t.TempDir()
+	defer func() { workspaceDir = "" }()
+
+	result := callMCPTool(t, "worktree_new", `{"repo":"app"}`)
+	if result["isError"] != true {
+		t.Fatal("expected error result")
+	}
+	toolErr := result["structuredContent"].(map[string]interface{})["error"].(map[string]interface{})
+	if toolErr["code"] != "invalid_arguments" {
+		t.Errorf("expected invalid_arguments, got %v", toolErr["code"])
+	}
+}
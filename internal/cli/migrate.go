@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateDryRun bool
+	migrateJSON   bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade workspace JSONL files to the current schema version",
+	Long: `Upgrade workspace JSONL files to the current schema version.
+
+Older records are already upgraded in memory on every read; migrate rewrites
+them on disk so other tools see the current format. Fields unknown to this
+build and lines that fail to parse are preserved.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrate,
+}
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "report what would be upgraded without writing")
+	migrateCmd.Flags().BoolVar(&migrateJSON, "json", false, "output as JSON")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	store := openFileStore()
+	reports, err := store.Migrate(migrateDryRun)
+	if err != nil {
+		return err
+	}
+
+	if migrateJSON {
+		if reports == nil {
+			reports = []jsonl.MigrationReport{}
+		}
+		return json.NewEncoder(os.Stdout).Encode(reports)
+	}
+
+	if len(reports) == 0 {
+		fmt.Println("No JSONL files found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tVERSION\tRECORDS\tUPGRADED\tNOTES")
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s\tv%d\t%d\t%d\t%s\n", r.File, r.Version, r.Records, r.Upgraded, migrationNotes(r))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if migrateDryRun {
+		fmt.Println("\nDry run - no changes made.")
+	}
+	return nil
+}
+
+func migrationNotes(r jsonl.MigrationReport) string {
+	var notes []string
+	versions := make([]int, 0, len(r.From))
+	for v := range r.From {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	for _, v := range versions {
+		notes = append(notes, fmt.Sprintf("%d from v%d", r.From[v], v))
+	}
+	if r.Newer > 0 {
+		notes = append(notes, fmt.Sprintf("%d newer than v%d (kept)", r.Newer, r.Version))
+	}
+	if r.Invalid > 0 {
+		notes = append(notes, fmt.Sprintf("%d malformed (kept)", r.Invalid))
+	}
+	return strings.Join(notes, ", ")
+}
@@ -20,7 +20,9 @@ var planCreateCmd = &cobra.Command{
 Example:
   bearing plan create --project bearing "Smart Refresh Queue"
 
-Creates: ~/Projects/plans/bearing/a3f2c-smart-refresh-queue.md`,
+Creates: ~/Projects/plans/bearing/a3f2c-smart-refresh-queue.md
+
+The plans directory is the plansDir setting (default ~/Projects/plans).`,
 	Args: cobra.ExactArgs(1),
 	RunE: runPlanCreate,
 }
@@ -32,13 +34,21 @@ func init() {
 }
 
 func runPlanCreate(cmd *cobra.Command, args []string) error {
-	title := args[0]
+	planFile, err := createPlanFile(planCreateProject, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created plan file: %s\n", planFile)
+	return nil
+}
 
-	if strings.TrimSpace(planCreateProject) == "" {
-		return fmt.Errorf("project name cannot be empty")
+// createPlanFile writes a new draft plan for project and returns its path
+func createPlanFile(project, title string) (string, error) {
+	if strings.TrimSpace(project) == "" {
+		return "", fmt.Errorf("project name cannot be empty")
 	}
 	if strings.TrimSpace(title) == "" {
-		return fmt.Errorf("title cannot be empty")
+		return "", fmt.Errorf("title cannot be empty")
 	}
 
 	// Generate 5-character ID
@@ -47,13 +57,13 @@ func runPlanCreate(cmd *cobra.Command, args []string) error {
 	filename := fmt.Sprintf("%s-%s.md", id, slug)
 
 	// Create plan directory
-	home, err := os.UserHomeDir()
+	plansDir, err := settings().PlansPath()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return "", err
 	}
-	planDir := filepath.Join(home, "Projects", "plans", planCreateProject)
+	planDir := filepath.Join(plansDir, project)
 	if err := os.MkdirAll(planDir, 0755); err != nil {
-		return fmt.Errorf("failed to create plan directory: %w", err)
+		return "", fmt.Errorf("failed to create plan directory: %w", err)
 	}
 
 	planFile := filepath.Join(planDir, filename)
@@ -66,14 +76,13 @@ status: draft
 
 # %s
 
-`, id, planCreateProject, title)
+`, id, project, title)
 
 	if err := os.WriteFile(planFile, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write plan file: %w", err)
+		return "", fmt.Errorf("failed to write plan file: %w", err)
 	}
 
-	fmt.Printf("Created plan file: %s\n", planFile)
-	return nil
+	return planFile, nil
 }
 
 // generateShortID returns a 5-character alphanumeric ID
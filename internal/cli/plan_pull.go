@@ -29,9 +29,16 @@ func init() {
 }
 
 func runPlanPull(cmd *cobra.Command, args []string) error {
-	repo := args[0]
-	issueNum := args[1]
+	planFile, err := pullPlan(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created plan file: %s\n", planFile)
+	return nil
+}
 
+// pullPlan writes a plan file from a GitHub issue and returns its path
+func pullPlan(repo, issueNum string) (string, error) {
 	repoPath := filepath.Join(WorkspaceDir(), repo)
 
 	// Fetch issue details using gh
@@ -39,19 +46,19 @@ func runPlanPull(cmd *cobra.Command, args []string) error {
 	ghCmd.Dir = repoPath
 	output, err := ghCmd.Output()
 	if err != nil {
-		return fmt.Errorf("failed to fetch issue: %w", err)
+		return "", fmt.Errorf("failed to fetch issue: %w", err)
 	}
 
 	// Parse JSON response
 	var issue ghIssue
 	if err := json.Unmarshal(output, &issue); err != nil {
-		return fmt.Errorf("failed to parse issue JSON: %w", err)
+		return "", fmt.Errorf("failed to parse issue JSON: %w", err)
 	}
 
 	// Create plan file
 	planDir := filepath.Join(WorkspaceDir(), "plans", repo)
 	if err := os.MkdirAll(planDir, 0755); err != nil {
-		return err
+		return "", err
 	}
 
 	planFile := filepath.Join(planDir, fmt.Sprintf("%s.md", sanitizeFilename(issueNum)))
@@ -68,11 +75,10 @@ status: draft
 `, issueNum, repo, issue.Title, issue.Body)
 
 	if err := os.WriteFile(planFile, []byte(content), 0644); err != nil {
-		return err
+		return "", err
 	}
 
-	fmt.Printf("Created plan file: %s\n", planFile)
-	return nil
+	return planFile, nil
 }
 
 func sanitizeFilename(s string) string {
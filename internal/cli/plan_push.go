@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -36,30 +37,46 @@ type planFrontmatter struct {
 }
 
 func runPlanPush(cmd *cobra.Command, args []string) error {
-	planFile := args[0]
+	_, err := pushPlan(args[0], planPushDryRun, os.Stdout)
+	return err
+}
+
+// planPushResult describes the outcome of pushPlan
+type planPushResult struct {
+	Action   string `json:"action"` // created, updated, or dry-run
+	Repo     string `json:"repo"`
+	FullRepo string `json:"fullRepo"`
+	Issue    string `json:"issue,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Title    string `json:"title"`
+	Body     string `json:"body,omitempty"`
+}
 
+// pushPlan creates or updates the GitHub issue for a plan file, writing
+// progress messages to out
+func pushPlan(planFile string, dryRun bool, out io.Writer) (*planPushResult, error) {
 	// Read and parse frontmatter
 	fm, body, err := parsePlanFile(planFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Auto-infer repo from path: plans/<project>/xxx.md
 	if fm.Repo == "" {
 		fm.Repo = inferRepoFromPath(planFile)
 		if fm.Repo == "" {
-			return fmt.Errorf("no repo in frontmatter and couldn't infer from path")
+			return nil, fmt.Errorf("no repo in frontmatter and couldn't infer from path")
 		}
-		fmt.Printf("Auto-detected repo: %s\n", fm.Repo)
+		fmt.Fprintf(out, "Auto-detected repo: %s\n", fm.Repo)
 	}
 
 	// Auto-infer title from first markdown heading if not in frontmatter
 	if fm.Title == "" {
 		fm.Title = extractTitleFromBody(body)
 		if fm.Title == "" {
-			return fmt.Errorf("no title in frontmatter and no markdown heading found")
+			return nil, fmt.Errorf("no title in frontmatter and no markdown heading found")
 		}
-		fmt.Printf("Auto-detected title: %s\n", fm.Title)
+		fmt.Fprintf(out, "Auto-detected title: %s\n", fm.Title)
 	}
 
 	// Trim leading/trailing whitespace from body
@@ -68,18 +85,31 @@ func runPlanPush(cmd *cobra.Command, args []string) error {
 	// Resolve full repo name (owner/repo) for gh CLI
 	fullRepo := LookupGitHubRepo(fm.Repo)
 	if fullRepo == "" {
-		// Fallback: assume owner/repo format already provided or use default owner
-		fullRepo = "joshribakoff/" + fm.Repo
-		fmt.Printf("Warning: repo %q not in projects.jsonl, assuming %s\n", fm.Repo, fullRepo)
+		// Fallback: the configured owner, else the base folder's origin
+		if owner := settings().GitHubOwnerFor(fm.Repo); owner != "" {
+			fullRepo = owner + "/" + fm.Repo
+		} else if fullRepo = GitHubRepoFromRemote(fm.Repo); fullRepo == "" {
+			return nil, fmt.Errorf("repo %q not in projects.jsonl, no githubOwner configured (bearing config set githubOwner <owner>) and origin is not on GitHub", fm.Repo)
+		}
+		fmt.Fprintf(out, "Warning: repo %q not in projects.jsonl, assuming %s\n", fm.Repo, fullRepo)
+	}
+
+	result := &planPushResult{
+		Repo:     fm.Repo,
+		FullRepo: fullRepo,
+		Issue:    fm.Issue,
+		Title:    fm.Title,
 	}
 
 	if fm.Issue == "" {
 		// Create new issue
-		if planPushDryRun {
-			fmt.Printf("Would create issue in %s:\n", fullRepo)
-			fmt.Printf("Title: %s\n", fm.Title)
-			fmt.Printf("Body:\n%s\n", body)
-			return nil
+		if dryRun {
+			fmt.Fprintf(out, "Would create issue in %s:\n", fullRepo)
+			fmt.Fprintf(out, "Title: %s\n", fm.Title)
+			fmt.Fprintf(out, "Body:\n%s\n", body)
+			result.Action = "dry-run"
+			result.Body = body
+			return result, nil
 		}
 
 		ghCmd := exec.Command("gh", "issue", "create",
@@ -92,7 +122,7 @@ func runPlanPush(cmd *cobra.Command, args []string) error {
 		ghCmd.Stderr = &stderr
 
 		if err := ghCmd.Run(); err != nil {
-			return fmt.Errorf("failed to create issue: %w\n%s", err, stderr.String())
+			return nil, fmt.Errorf("failed to create issue: %w\n%s", err, stderr.String())
 		}
 
 		// Parse issue number from output (URL format: https://github.com/owner/repo/issues/123)
@@ -102,20 +132,25 @@ func runPlanPush(cmd *cobra.Command, args []string) error {
 
 		// Update frontmatter with issue number
 		if err := updateFrontmatter(planFile, "issue", issueNum); err != nil {
-			fmt.Printf("Warning: created issue #%s but failed to update frontmatter: %v\n", issueNum, err)
+			fmt.Fprintf(out, "Warning: created issue #%s but failed to update frontmatter: %v\n", issueNum, err)
 		}
 
-		fmt.Printf("Created issue #%s in %s\n", issueNum, fm.Repo)
-		fmt.Printf("URL: %s\n", url)
-		return nil
+		fmt.Fprintf(out, "Created issue #%s in %s\n", issueNum, fm.Repo)
+		fmt.Fprintf(out, "URL: %s\n", url)
+		result.Action = "created"
+		result.Issue = issueNum
+		result.URL = url
+		return result, nil
 	}
 
 	// Update existing issue
-	if planPushDryRun {
-		fmt.Printf("Would update issue %s in %s:\n", fm.Issue, fullRepo)
-		fmt.Printf("Status: %s\n", fm.Status)
-		fmt.Printf("Body:\n%s\n", body)
-		return nil
+	if dryRun {
+		fmt.Fprintf(out, "Would update issue %s in %s:\n", fm.Issue, fullRepo)
+		fmt.Fprintf(out, "Status: %s\n", fm.Status)
+		fmt.Fprintf(out, "Body:\n%s\n", body)
+		result.Action = "dry-run"
+		result.Body = body
+		return result, nil
 	}
 
 	ghCmd := exec.Command("gh", "issue", "edit", fm.Issue,
@@ -125,11 +160,12 @@ func runPlanPush(cmd *cobra.Command, args []string) error {
 	ghCmd.Stderr = &stderr
 
 	if err := ghCmd.Run(); err != nil {
-		return fmt.Errorf("failed to update issue: %w\n%s", err, stderr.String())
+		return nil, fmt.Errorf("failed to update issue: %w\n%s", err, stderr.String())
 	}
 
-	fmt.Printf("Updated issue %s in %s\n", fm.Issue, fm.Repo)
-	return nil
+	fmt.Fprintf(out, "Updated issue %s in %s\n", fm.Issue, fm.Repo)
+	result.Action = "updated"
+	return result, nil
 }
 
 // inferRepoFromPath extracts repo name from plan path: plans/<project>/xxx.md
@@ -240,3 +240,19 @@ func createTempFile(t *testing.T, content string) string {
 	}
 	return tmpFile
 }
+
+func TestParseGitHubRemote(t *testing.T) {
+	for url, want := range map[string]string{
+		"git@github.com:acme/app.git":         "acme/app",
+		"https://github.com/acme/app":         "acme/app",
+		"https://github.com/acme/app.git":     "acme/app",
+		"ssh://git@github.com/acme/app.git":   "acme/app",
+		"git@gitlab.com:acme/app.git":         "",
+		"https://github.com/acme":             "",
+		"https://github.com/acme/app/pulls/1": "",
+	} {
+		if got := parseGitHubRemote(url); got != want {
+			t.Errorf("parseGitHubRemote(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -1,42 +1,43 @@
 package cli
 
 import (
-	"bufio"
-	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/joshribakoff/bearing/internal/git"
 	"github.com/joshribakoff/bearing/internal/jsonl"
 )
 
-var projectsCache map[string]*jsonl.ProjectEntry
+var (
+	projectsMu    sync.Mutex
+	projectsCache map[string]*jsonl.ProjectEntry
+)
 
-// LoadProjects loads and caches projects from projects.jsonl
+// LoadProjects loads and caches projects from projects.jsonl. It is safe
+// for concurrent use.
 func LoadProjects() (map[string]*jsonl.ProjectEntry, error) {
+	projectsMu.Lock()
+	defer projectsMu.Unlock()
 	if projectsCache != nil {
 		return projectsCache, nil
 	}
 
-	projectsFile := filepath.Join(WorkspaceDir(), "projects.jsonl")
-	f, err := os.Open(projectsFile)
+	entries, err := openStore().ReadProjects()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil // No projects.jsonl yet
-		}
 		return nil, err
 	}
-	defer f.Close()
+	if entries == nil {
+		return nil, nil // No projects.jsonl yet
+	}
 
 	projectsCache = make(map[string]*jsonl.ProjectEntry)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		var entry jsonl.ProjectEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
-		}
-		projectsCache[entry.Name] = &entry
+	for i := range entries {
+		projectsCache[entries[i].Name] = &entries[i]
 	}
-	return projectsCache, scanner.Err()
+	return projectsCache, nil
 }
 
 // LookupGitHubRepo returns the GitHub repo (owner/repo) for a project name
@@ -51,6 +52,33 @@ func LookupGitHubRepo(projectName string) string {
 	return ""
 }
 
+// GitHubRepoFromRemote returns owner/repo for a project from its base
+// folder's origin remote, or "" if origin is not on GitHub
+func GitHubRepoFromRemote(projectName string) string {
+	url, err := git.NewRepo(GetRepoPath(projectName)).RemoteURL("origin")
+	if err != nil {
+		return ""
+	}
+	return parseGitHubRemote(url)
+}
+
+// parseGitHubRemote extracts owner/repo from a GitHub remote URL in https,
+// ssh or scp-like form
+func parseGitHubRemote(url string) string {
+	var path string
+	for _, prefix := range []string{"https://github.com/", "http://github.com/", "ssh://git@github.com/", "git@github.com:"} {
+		if rest, ok := strings.CutPrefix(url, prefix); ok {
+			path = rest
+			break
+		}
+	}
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+	if owner, repo, ok := strings.Cut(path, "/"); !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return ""
+	}
+	return path
+}
+
 // GetRepoPath returns the local path for running gh commands
 func GetRepoPath(projectName string) string {
 	projects, err := LoadProjects()
@@ -62,3 +90,81 @@ func GetRepoPath(projectName string) string {
 	}
 	return filepath.Join(WorkspaceDir(), projectName)
 }
+
+// detectedBranches remembers default branches detected during this run.
+// detectMu serializes detection, which also updates the projects cache.
+var (
+	detectMu         sync.Mutex
+	detectedBranches = map[string]string{}
+)
+
+// DefaultBranch returns the default branch of project. A per-project
+// setting wins, then the branch cached in projects.jsonl, then the branch
+// detected from origin (cached back into projects.jsonl when the project
+// is listed there). Falls back to the defaultBranch setting.
+func DefaultBranch(project string) string {
+	if branch := settings().Projects[project].DefaultBranch; branch != "" {
+		return branch
+	}
+	if branch := detectDefaultBranch(project); branch != "" {
+		return branch
+	}
+	return settings().DefaultBranch
+}
+
+// detectDefaultBranch returns the cached or detected default branch of
+// project, or "" if origin does not say
+func detectDefaultBranch(project string) string {
+	detectMu.Lock()
+	defer detectMu.Unlock()
+	if branch, ok := detectedBranches[project]; ok {
+		return branch
+	}
+
+	projects, _ := LoadProjects()
+	if p, ok := projects[project]; ok && p.DefaultBranch != "" {
+		return p.DefaultBranch
+	}
+
+	branch, _ := git.NewRepo(GetRepoPath(project)).DefaultBranch()
+	detectedBranches[project] = branch
+	if branch != "" {
+		if p, ok := projects[project]; ok {
+			p.DefaultBranch = branch
+			cacheDefaultBranch(project, branch)
+		}
+	}
+	return branch
+}
+
+// cacheDefaultBranch records branch in the projects.jsonl entry of project
+func cacheDefaultBranch(project, branch string) {
+	err := openStore().UpdateProjects(func(entries []jsonl.ProjectEntry) ([]jsonl.ProjectEntry, error) {
+		for i := range entries {
+			if entries[i].Name == project {
+				entries[i].DefaultBranch = branch
+			}
+		}
+		return entries, nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to cache default branch of %s: %v\n", project, err)
+	}
+}
+
+// isBaseBranch reports whether a folder of project checked out on branch
+// is a base folder: branch is in the project's baseBranches setting, or
+// else is its default branch. The global baseBranches setting applies only
+// when the default branch cannot be determined.
+func isBaseBranch(project, branch string) bool {
+	if p, ok := settings().Projects[project]; ok && len(p.BaseBranches) > 0 {
+		return settings().IsBaseBranch(project, branch)
+	}
+	if configured := settings().Projects[project].DefaultBranch; configured != "" {
+		return branch == configured
+	}
+	if detected := detectDefaultBranch(project); detected != "" {
+		return branch == detected
+	}
+	return settings().IsBaseBranch(project, branch)
+}
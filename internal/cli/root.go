@@ -1,22 +1,45 @@
 package cli
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/joshribakoff/bearing/internal/config"
+	"github.com/joshribakoff/bearing/internal/git"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/sqlitestore"
 	"github.com/spf13/cobra"
 )
 
+// version is reported by the MCP server
+const version = "0.1.0"
+
 var (
 	workspaceDir string
+	strictJSONL  bool
+	loadedConfig *config.Config
+	openedStore  jsonl.Store
 	rootCmd      = &cobra.Command{
 		Use:   "bearing",
 		Short: "Worktree management for parallel AI-assisted development",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			err := loadConfig()
+			if err != nil && toleratesBadConfig(cmd) {
+				useDefaultConfig(err)
+				return nil
+			}
+			return err
+		},
 	}
 )
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&workspaceDir, "workspace", "w", "", "workspace directory (default: current directory)")
+	rootCmd.PersistentFlags().BoolVar(&strictJSONL, "strict", false, "fail on malformed JSONL lines instead of skipping them")
 }
 
 // WorkspaceDir returns the configured workspace directory
@@ -28,6 +51,103 @@ func WorkspaceDir() string {
 	return dir
 }
 
+// loadConfig reads the user and workspace config files once per invocation
+func loadConfig() error {
+	c, err := config.Load(WorkspaceDir(), BearingDir())
+	if err != nil {
+		return err
+	}
+	backend, err := git.BackendByName(c.Git.Backend)
+	if err != nil {
+		return err
+	}
+	git.SetDefaultBackend(backend)
+	loadedConfig = c
+	return nil
+}
+
+// settings returns the loaded configuration. Commands whose own
+// PersistentPreRunE replaces the root one load it on first use.
+func settings() *config.Config {
+	if loadedConfig == nil {
+		if err := loadConfig(); err != nil {
+			useDefaultConfig(err)
+		}
+	}
+	return loadedConfig
+}
+
+// useDefaultConfig warns about the config error err and carries on with
+// the defaults
+func useDefaultConfig(err error) {
+	fmt.Fprintf(os.Stderr, "warning: %v; using defaults\n", err)
+	loadedConfig = config.Default()
+}
+
+// toleratesBadConfig reports whether cmd runs on defaults when the config
+// is invalid rather than failing: config, so the file can be repaired, and
+// the hooks, so the guards keep working
+func toleratesBadConfig(cmd *cobra.Command) bool {
+	path := cmd.CommandPath()
+	return strings.HasPrefix(path, "bearing config") ||
+		strings.HasPrefix(path, "bearing hook") ||
+		path == "bearing worktree check"
+}
+
+// openStore returns the workspace store: the JSONL files, or bearing.db
+// when the store setting or BEARING_STORE is sqlite. It is opened once
+// per process and closed when Execute returns.
+func openStore() jsonl.Store {
+	if openedStore != nil {
+		return openedStore
+	}
+	backend := settings().Store
+	if env := os.Getenv("BEARING_STORE"); env != "" {
+		backend = env
+	}
+	if backend == "sqlite" {
+		path := filepath.Join(WorkspaceDir(), sqlitestore.DefaultFile)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "warning: %s does not exist yet (see 'bearing store import')\n", sqlitestore.DefaultFile)
+		}
+		openedStore = sqlitestore.New(path)
+	} else {
+		openedStore = openFileStore()
+	}
+	return openedStore
+}
+
+// closeStore closes the store opened by openStore, if it needs closing
+func closeStore() {
+	if c, ok := openedStore.(io.Closer); ok {
+		c.Close()
+	}
+	openedStore = nil
+}
+
+// openFileStore returns the JSONL store for the workspace. By default
+// malformed lines are skipped with a warning on stderr; --strict turns them
+// into errors.
+func openFileStore() *jsonl.FileStore {
+	store := jsonl.NewStore(WorkspaceDir())
+	if strictJSONL {
+		store.SetReadMode(jsonl.Strict)
+		return store
+	}
+	// The daemon reads from several goroutines at once
+	var mu sync.Mutex
+	warned := make(map[string]bool)
+	store.OnMalformed(func(e *jsonl.ParseError) {
+		mu.Lock()
+		defer mu.Unlock()
+		if key := e.Error(); !warned[key] {
+			warned[key] = true
+			fmt.Fprintf(os.Stderr, "warning: skipping malformed line %v (see 'bearing doctor jsonl')\n", e)
+		}
+	})
+	return store
+}
+
 // BearingDir returns the ~/.bearing directory for daemon files
 func BearingDir() string {
 	home, _ := os.UserHomeDir()
@@ -36,5 +156,6 @@ func BearingDir() string {
 
 // Execute runs the root command
 func Execute() error {
+	defer closeStore()
 	return rootCmd.Execute()
 }
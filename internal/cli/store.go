@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/sqlitestore"
+	"github.com/spf13/cobra"
+)
+
+var storeCmd = &cobra.Command{
+	Use:   "store",
+	Short: "Move workspace state between JSONL files and SQLite",
+	Long: `Move workspace state between the JSONL files and bearing.db.
+
+The JSONL files are the default store and the format other tools read. Set
+BEARING_STORE=sqlite to use bearing.db instead, which gives large
+workspaces indexed lookups. Import before switching, and export whenever
+tools that read the JSONL files need current state.`,
+}
+
+var storeImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Replace bearing.db contents with the JSONL files",
+	Args:  cobra.NoArgs,
+	RunE:  runStoreImport,
+}
+
+var storeExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Replace the JSONL files with bearing.db contents",
+	Args:  cobra.NoArgs,
+	RunE:  runStoreExport,
+}
+
+func init() {
+	storeCmd.AddCommand(storeImportCmd)
+	storeCmd.AddCommand(storeExportCmd)
+	rootCmd.AddCommand(storeCmd)
+}
+
+func openSQLiteStore() *sqlitestore.Store {
+	return sqlitestore.New(filepath.Join(WorkspaceDir(), sqlitestore.DefaultFile))
+}
+
+func runStoreImport(cmd *cobra.Command, args []string) error {
+	db := openSQLiteStore()
+	defer db.Close()
+
+	if err := jsonl.Copy(db, openFileStore()); err != nil {
+		return fmt.Errorf("failed to import: %w", err)
+	}
+	fmt.Printf("Imported JSONL files into %s\n", db.Path())
+	return nil
+}
+
+func runStoreExport(cmd *cobra.Command, args []string) error {
+	db := openSQLiteStore()
+	defer db.Close()
+
+	// An empty new database would wipe the JSONL files
+	if _, err := os.Stat(db.Path()); os.IsNotExist(err) {
+		return fmt.Errorf("%s not found; run 'bearing store import' first", db.Path())
+	}
+
+	if err := jsonl.Copy(openFileStore(), db); err != nil {
+		return fmt.Errorf("failed to export: %w", err)
+	}
+	fmt.Printf("Exported %s to JSONL files\n", db.Path())
+	return nil
+}
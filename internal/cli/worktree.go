@@ -1,6 +1,11 @@
 package cli
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
 
 var worktreeCmd = &cobra.Command{
 	Use:   "worktree",
@@ -10,3 +15,9 @@ var worktreeCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(worktreeCmd)
 }
+
+// worktreeFolderName returns the workspace folder for a repo/branch pair,
+// with / in the branch sanitized to -
+func worktreeFolderName(repoName, branch string) string {
+	return fmt.Sprintf("%s-%s", repoName, strings.ReplaceAll(branch, "/", "-"))
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/alloc"
+	"github.com/joshribakoff/bearing/internal/bootstrap"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/spf13/cobra"
+)
+
+var worktreeBootstrapCmd = &cobra.Command{
+	Use:   "bootstrap <folder>",
+	Short: "Prepare a worktree: copy ignored files, run setup commands",
+	Long: `Prepare a worktree for work, as worktree new does after creating it.
+
+Files listed under bootstrap.copy and bootstrap.symlink in the config are
+copied or linked from the base folder, bootstrap.run commands run in the
+worktree, and then the repo's .bearing/bootstrap script if it has one.
+A project's own projects.<name>.bootstrap replaces the top-level one.
+The outcome is recorded on the branch's workflow.jsonl entry.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorktreeBootstrap,
+}
+
+func init() {
+	worktreeCmd.AddCommand(worktreeBootstrapCmd)
+}
+
+func runWorktreeBootstrap(cmd *cobra.Command, args []string) error {
+	e, err := openStore().FindLocal(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read local.jsonl: %w", err)
+	}
+	if e == nil {
+		return fmt.Errorf("folder not registered: %s", args[0])
+	}
+	if e.Base {
+		return fmt.Errorf("%s is a base folder; bootstrap prepares worktrees", e.Folder)
+	}
+
+	status, err := bootstrapWorktree(e.Repo, e.Branch, e.Folder, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		fmt.Printf("Nothing to bootstrap for %s\n", e.Repo)
+		return nil
+	}
+	fmt.Printf("Bootstrapped %s in %s\n", e.Folder, time.Duration(status.DurationMs)*time.Millisecond)
+	return nil
+}
+
+// bootstrapWorktree prepares the worktree in folder and records the outcome
+// in workflow.jsonl. It returns nil, nil when the project has nothing to
+// bootstrap, and the recorded status with an error when bootstrap fails.
+func bootstrapWorktree(repo, branch, folder string, out io.Writer) (*jsonl.BootstrapStatus, error) {
+	workspace, err := filepath.Abs(WorkspaceDir())
+	if err != nil {
+		return nil, err
+	}
+	cfg := settings().BootstrapFor(repo)
+	plan := bootstrap.Plan{
+		Copy:    cfg.Copy,
+		Symlink: cfg.Symlink,
+		Run:     cfg.Run,
+		Timeout: time.Duration(cfg.Timeout) * time.Second,
+	}
+	target := bootstrap.Target{
+		Repo:     repo,
+		Branch:   branch,
+		BaseDir:  filepath.Join(workspace, repo),
+		Worktree: filepath.Join(workspace, folder),
+	}
+	if plan.Empty() && !target.HasScript() {
+		return nil, nil
+	}
+	// Commands see the worktree's allocated ports and database
+	if a, err := openStore().FindAllocation(folder); err == nil && a != nil {
+		for _, kv := range alloc.Env(*a) {
+			target.Env = append(target.Env, kv[0]+"="+kv[1])
+		}
+	}
+
+	start := time.Now()
+	runErr := bootstrap.Run(context.Background(), plan, target, out)
+	status := &jsonl.BootstrapStatus{
+		Status:     "ok",
+		Finished:   time.Now(),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if runErr != nil {
+		status.Status = "failed"
+		status.Error = runErr.Error()
+	}
+
+	err = openStore().UpdateWorkflow(func(entries []jsonl.WorkflowEntry) ([]jsonl.WorkflowEntry, error) {
+		// The newest entry for the branch is the current one
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].Repo == repo && entries[i].Branch == branch {
+				entries[i].Bootstrap = status
+				break
+			}
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return status, fmt.Errorf("failed to update workflow.jsonl: %w", err)
+	}
+	if runErr != nil {
+		return status, fmt.Errorf("bootstrap failed: %w (retry with: bearing worktree bootstrap %s)", runErr, folder)
+	}
+	return status, nil
+}
@@ -5,37 +5,47 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/joshribakoff/bearing/internal/git"
 	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/session"
 	"github.com/spf13/cobra"
 )
 
 var (
 	checkQuiet bool
 	checkJSON  bool
+	checkOwner string
 )
 
 var worktreeCheckCmd = &cobra.Command{
 	Use:   "check [folder...]",
 	Short: "Check worktree health",
-	RunE:  runWorktreeCheck,
+	Long: `Check worktree health.
+
+With --json it runs as a Claude Code hook: the hook input on stdin names
+the session and its working directory. If that directory is inside a
+worktree leased to another session, the prompt is blocked.`,
+	RunE: runWorktreeCheck,
 }
 
 func init() {
 	worktreeCheckCmd.Flags().BoolVarP(&checkQuiet, "quiet", "q", false, "only show problems")
 	worktreeCheckCmd.Flags().BoolVar(&checkJSON, "json", false, "output as JSON")
+	worktreeCheckCmd.Flags().StringVar(&checkOwner, "owner", "", "session checking leases (default: session_id from hook input)")
 	worktreeCmd.AddCommand(worktreeCheckCmd)
 }
 
 type checkResult struct {
-	Folder   string   `json:"folder"`
-	Problems []string `json:"problems,omitempty"`
-	OK       bool     `json:"ok"`
+	Folder   string            `json:"folder"`
+	Problems []string          `json:"problems,omitempty"`
+	OK       bool              `json:"ok"`
+	Lease    *jsonl.LeaseEntry `json:"lease,omitempty"`
 }
 
 func runWorktreeCheck(cmd *cobra.Command, args []string) error {
-	store := jsonl.NewStore(WorkspaceDir())
+	store := openStore()
 	entries, err := store.ReadLocal()
 	if err != nil {
 		return err
@@ -58,12 +68,24 @@ func runWorktreeCheck(cmd *cobra.Command, args []string) error {
 		entries = filtered
 	}
 
+	now := time.Now()
+	leases, err := store.ReadLeases()
+	if err != nil {
+		return fmt.Errorf("failed to read leases.jsonl: %w", err)
+	}
+	liveLeases := make(map[string]*jsonl.LeaseEntry)
+	for i := range leases {
+		if !leases[i].Expired(now) {
+			liveLeases[leases[i].Folder] = &leases[i]
+		}
+	}
+
 	var results []checkResult
 	hasProblems := false
 
 	for _, e := range entries {
 		folderPath := filepath.Join(WorkspaceDir(), e.Folder)
-		result := checkResult{Folder: e.Folder, OK: true}
+		result := checkResult{Folder: e.Folder, OK: true, Lease: liveLeases[e.Folder]}
 
 		// Check folder exists
 		if _, err := os.Stat(folderPath); os.IsNotExist(err) {
@@ -102,8 +124,25 @@ func runWorktreeCheck(cmd *cobra.Command, args []string) error {
 		hookOutput := struct {
 			Continue      bool   `json:"continue"`
 			SystemMessage string `json:"systemMessage,omitempty"`
+			Decision      string `json:"decision,omitempty"`
+			Reason        string `json:"reason,omitempty"`
 		}{Continue: true}
 
+		in := readHookInput(os.Stdin)
+		if in.SessionID != "" {
+			// Each prompt is activity by the session; best effort
+			session.Touch(store, WorkspaceDir(), in.SessionID, in.Cwd, now)
+		}
+		if l := blockingLease(in, liveLeases); l != nil {
+			msg := fmt.Sprintf("BEARING BLOCKED: %s is claimed by another session (%s) until %s. "+
+				"Work in a different worktree, or ask the user to run `bearing worktree release %s --force`.",
+				l.Folder, l.Owner, l.Expires.Local().Format(time.Kitchen), l.Folder)
+			hookOutput.Decision = "block"
+			hookOutput.Reason = msg
+			hookOutput.SystemMessage = msg
+			return json.NewEncoder(os.Stdout).Encode(hookOutput)
+		}
+
 		if hasProblems {
 			msg := "BEARING WARNING: Worktree violations detected. "
 			for _, r := range results {
@@ -129,6 +168,9 @@ func runWorktreeCheck(cmd *cobra.Command, args []string) error {
 				fmt.Printf("  - %s\n", p)
 			}
 		}
+		if r.Lease != nil {
+			fmt.Printf("  claimed by %s until %s\n", r.Lease.Owner, r.Lease.Expires.Local().Format(time.DateTime))
+		}
 	}
 
 	if hasProblems {
@@ -136,3 +178,17 @@ func runWorktreeCheck(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+// blockingLease returns the live lease on the hook's working folder if
+// another session holds it. Without a known session nothing is blocked.
+func blockingLease(in hookInput, leases map[string]*jsonl.LeaseEntry) *jsonl.LeaseEntry {
+	owner := checkOwner
+	if owner == "" {
+		owner = in.SessionID
+	}
+	l := leases[session.Folder(WorkspaceDir(), in.Cwd)]
+	if l == nil || owner == "" || l.Owner == owner {
+		return nil
+	}
+	return l
+}
@@ -1,82 +1,369 @@
 package cli
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/joshribakoff/bearing/internal/daemon"
+	"github.com/joshribakoff/bearing/internal/gh"
 	"github.com/joshribakoff/bearing/internal/git"
 	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/lease"
+	"github.com/joshribakoff/bearing/internal/session"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
 
+var (
+	cleanupForce   bool
+	cleanupYes     bool
+	cleanupDryRun  bool
+	cleanupArchive string
+	cleanupOwner   string
+)
+
 var worktreeCleanupCmd = &cobra.Command{
 	Use:   "cleanup <repo> <branch>",
 	Short: "Remove a worktree and update manifests",
-	Args:  cobra.ExactArgs(2),
-	RunE:  runWorktreeCleanup,
+	Long: `Remove a worktree, delete its branch if merged, and update manifests.
+
+Before removing anything cleanup checks the worktree for uncommitted
+changes, unpushed commits, an open pull request, a lease held by someone
+else and a live agent session, prints what it found and what it will do,
+and asks for confirmation when run from a terminal. It refuses if any
+check fails unless --force is given, which also removes a worktree with
+changes.
+
+A merged branch is deleted even when it was squash or rebase merged: its
+pull request says so, or git finds its changes in the base branch. With
+--archive tag or bundle (or cleanup.archive in the config) the branch is
+saved first and then deleted whether merged or not.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runWorktreeCleanup,
 }
 
 func init() {
+	worktreeCleanupCmd.Flags().BoolVarP(&cleanupForce, "force", "f", false, "clean up despite failed checks, discarding changes")
+	worktreeCleanupCmd.Flags().BoolVarP(&cleanupYes, "yes", "y", false, "don't ask for confirmation")
+	worktreeCleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "show the checks and what would be done, then stop")
+	worktreeCleanupCmd.Flags().StringVar(&cleanupArchive, "archive", "", "save the branch first as a tag or bundle (default: cleanup.archive)")
+	worktreeCleanupCmd.Flags().StringVar(&cleanupOwner, "owner", "", "your session ID, so your own lease and session don't block cleanup")
 	worktreeCmd.AddCommand(worktreeCleanupCmd)
 }
 
 func runWorktreeCleanup(cmd *cobra.Command, args []string) error {
-	repoName := args[0]
-	branch := args[1]
+	opts := cleanupOptions{Force: cleanupForce, Archive: cleanupArchive}
+	if opts.Archive == "" {
+		opts.Archive = settings().Cleanup.Archive
+	}
+	if opts.Archive != "none" && opts.Archive != "tag" && opts.Archive != "bundle" {
+		return fmt.Errorf("--archive must be none, tag or bundle, got %q", opts.Archive)
+	}
 
-	// Sanitize branch name for folder (replace / with -)
-	folderBranch := strings.ReplaceAll(branch, "/", "-")
-	folderName := fmt.Sprintf("%s-%s", repoName, folderBranch)
+	plan, err := planCleanup(args[0], args[1], cleanupOwner)
+	if err != nil {
+		return err
+	}
+	printCleanupPlan(os.Stdout, plan, opts)
+	if cleanupDryRun {
+		return nil
+	}
+	if len(plan.Problems) > 0 && !opts.Force {
+		return fmt.Errorf("refusing to clean up %s: %s (use --force to override)", plan.Folder, strings.Join(plan.Problems, "; "))
+	}
+	if !cleanupYes && stdinIsTerminal() && !confirm(os.Stdin, os.Stdout, "Proceed?") {
+		return fmt.Errorf("cleanup cancelled")
+	}
 
-	baseRepo := filepath.Join(WorkspaceDir(), repoName)
-	worktreePath := filepath.Join(WorkspaceDir(), folderName)
-	store := jsonl.NewStore(WorkspaceDir())
+	result, err := executeCleanup(plan, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Done. Worktree removed: %s\n", result.Folder)
+	if result.Archive != "" {
+		fmt.Printf("Branch %s archived to %s\n", result.Branch, result.Archive)
+	}
+	return nil
+}
 
-	repo := git.NewRepo(baseRepo)
+// cleanupOptions says how executeCleanup treats problems and the branch
+type cleanupOptions struct {
+	Force   bool   // clean up despite problems, removing a dirty worktree
+	Archive string // none, tag or bundle
+}
 
-	// Remove the worktree
-	fmt.Printf("Removing worktree: %s\n", worktreePath)
-	if err := repo.WorktreeRemove(worktreePath); err != nil {
-		return fmt.Errorf("failed to remove worktree: %w", err)
+// cleanupPlan is what the pre-flight checks found about a worktree
+type cleanupPlan struct {
+	Folder   string   `json:"folder"`
+	Repo     string   `json:"repo"`
+	Branch   string   `json:"branch"`
+	Path     string   `json:"path"`
+	Missing  bool     `json:"missing,omitempty"` // the folder no longer exists
+	Dirty    bool     `json:"dirty"`
+	Unpushed int      `json:"unpushed"`
+	PRState  string   `json:"prState,omitempty"`
+	Merged   bool     `json:"merged"`             // its pull request was merged, or its changes are in the base
+	Problems []string `json:"problems,omitempty"` // why cleanup is unsafe
+}
+
+// planCleanup checks whether removing the worktree for branch would lose
+// work or pull it from under someone. owner's own lease and session are
+// not counted as problems.
+func planCleanup(repoName, branch, owner string) (*cleanupPlan, error) {
+	folder := worktreeFolderName(repoName, branch)
+	plan := &cleanupPlan{
+		Folder: folder,
+		Repo:   repoName,
+		Branch: branch,
+		Path:   filepath.Join(WorkspaceDir(), folder),
+	}
+	if e, err := openStore().FindLocal(folder); err == nil && e != nil && e.Base {
+		return nil, fmt.Errorf("%s is a base folder; cleanup removes worktrees", folder)
 	}
 
-	// Delete the branch if it has been merged (non-force delete)
-	// If delete fails, branch has unmerged commits
-	status := "cleaned"
-	if err := repo.BranchDelete(branch, false); err == nil {
-		status = "merged"
+	store := openStore()
+	if _, err := os.Stat(plan.Path); os.IsNotExist(err) {
+		plan.Missing = true
+	} else {
+		timeout := time.Duration(settings().Daemon.Timeout) * time.Second
+		entry := jsonl.LocalEntry{Folder: folder, Repo: repoName, Branch: branch}
+		h := daemon.CheckWorktree(context.Background(), WorkspaceDir(), entry, daemon.LoadCheckOptions(store, timeout))
+		plan.Dirty = h.Dirty
+		plan.Unpushed = h.Unpushed
+		if h.PRState != nil {
+			plan.PRState = *h.PRState
+		}
+		plan.Merged = plan.PRState == "MERGED" || h.Merged
+
+		if h.Dirty {
+			plan.Problems = append(plan.Problems, "uncommitted changes")
+		}
+		if h.Operation != "" {
+			plan.Problems = append(plan.Problems, h.Operation+" in progress")
+		}
+		// Unpushed commits are safe to drop only if their changes are in
+		// the base. A merged pull request vouches for the commits it
+		// merged, not for any made since.
+		switch {
+		case h.Unpushed == 0 || h.Merged:
+		case plan.PRState == "MERGED":
+			if n := commitsAfterMerge(plan.Path, branch, h.Unpushed, timeout); n > 0 {
+				plan.Problems = append(plan.Problems, fmt.Sprintf("%d commit(s) after merge", n))
+			}
+		default:
+			plan.Problems = append(plan.Problems, fmt.Sprintf("%d unpushed commit(s)", h.Unpushed))
+		}
+		if plan.PRState == "OPEN" {
+			plan.Problems = append(plan.Problems, "pull request is open")
+		}
 	}
 
-	// Update workflow.jsonl with actual status
-	workflows, err := store.ReadWorkflow()
+	if plan.Missing {
+		plan.Merged = branchMerged(repoName, branch)
+	}
+
+	now := time.Now()
+	if l, err := lease.Active(store, folder, now); err == nil && l != nil && l.Owner != owner {
+		plan.Problems = append(plan.Problems, fmt.Sprintf("claimed by %s until %s", l.Owner, l.Expires.Local().Format(time.Kitchen)))
+	}
+	sessions, _ := store.SessionsFor(folder)
+	if s, ok := session.ByFolder(sessions)[folder]; ok && session.Live(s, now) && s.ID != owner {
+		plan.Problems = append(plan.Problems, fmt.Sprintf("agent session %s active %s ago", s.ID, shortDuration(now.Sub(s.LastActivity))))
+	}
+	return plan, nil
+}
+
+// commitsAfterMerge counts the commits on HEAD in path that came after
+// the head commit of branch's merged pull request. If that commit is
+// unknown here, all unpushed commits count.
+func commitsAfterMerge(path, branch string, unpushed int, timeout time.Duration) int {
+	ctx := context.Background()
+	pr, err := gh.NewClient(path).WithTimeout(ctx, timeout).GetPR(branch)
+	if err != nil || pr == nil || pr.HeadRefOid == "" {
+		return unpushed
+	}
+	ahead, _, err := git.NewRepo(path).WithTimeout(ctx, timeout).AheadBehind("HEAD", pr.HeadRefOid)
 	if err != nil {
-		return err
+		return unpushed
+	}
+	return ahead
+}
+
+// branchMerged reports whether branch's changes are in its base branch,
+// however it was merged, checking from the repo's base folder
+func branchMerged(repoName, branch string) bool {
+	base := DefaultBranch(repoName)
+	if w, err := openStore().FindWorkflow(repoName, branch); err == nil && w != nil && w.BasedOn != "" {
+		base = w.BasedOn
+	}
+	repo := git.NewRepo(filepath.Join(WorkspaceDir(), repoName))
+	ref := base
+	if repo.RemoteBranchExists(base) {
+		ref = "origin/" + base
+	}
+	merged, err := repo.IsMergedInto(branch, ref)
+	return err == nil && merged
+}
+
+// printCleanupPlan summarizes the checks and what cleanup will do
+func printCleanupPlan(w io.Writer, plan *cleanupPlan, opts cleanupOptions) {
+	fmt.Fprintf(w, "Cleanup of %s (branch %s):\n", plan.Folder, plan.Branch)
+	if plan.Missing {
+		fmt.Fprintf(w, "  worktree  %s is already gone\n", plan.Path)
+	} else {
+		fmt.Fprintf(w, "  worktree  %s will be removed\n", plan.Path)
 	}
-	for i, w := range workflows {
-		if w.Repo == repoName && w.Branch == branch {
-			workflows[i].Status = status
+
+	pr := "no pull request found"
+	if plan.PRState != "" {
+		pr = "pull request " + strings.ToLower(plan.PRState)
+	}
+	switch {
+	case opts.Archive != "none":
+		fmt.Fprintf(w, "  branch    %s, will be archived as a %s and deleted\n", pr, opts.Archive)
+	case plan.Merged:
+		if plan.PRState != "MERGED" {
+			pr += ", changes are in its base"
 		}
+		fmt.Fprintf(w, "  branch    %s, will be deleted\n", pr)
+	default:
+		fmt.Fprintf(w, "  branch    %s, will be deleted only if merged into its base\n", pr)
+	}
+
+	if len(plan.Problems) == 0 {
+		fmt.Fprintln(w, "  checks    ok")
+		return
+	}
+	for _, p := range plan.Problems {
+		fmt.Fprintf(w, "  problem   %s\n", p)
 	}
-	if err := store.WriteWorkflow(workflows); err != nil {
-		return fmt.Errorf("failed to update workflow.jsonl: %w", err)
+	if opts.Force {
+		fmt.Fprintln(w, "  --force given; continuing anyway")
 	}
+}
 
-	// Update local.jsonl - remove entry
-	locals, err := store.ReadLocal()
-	if err != nil {
-		return err
+// stdinIsTerminal reports whether a person could answer a prompt
+func stdinIsTerminal() bool {
+	return isatty.IsTerminal(os.Stdin.Fd())
+}
+
+// confirm asks question and reports whether the answer was yes
+func confirm(r io.Reader, w io.Writer, question string) bool {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// cleanupResult describes a worktree removed by executeCleanup
+type cleanupResult struct {
+	Folder        string `json:"folder"`
+	Repo          string `json:"repo"`
+	Branch        string `json:"branch"`
+	Status        string `json:"status"` // merged or cleaned
+	BranchDeleted bool   `json:"branchDeleted"`
+	Archive       string `json:"archive,omitempty"` // tag or bundle path the branch was saved to
+}
+
+// executeCleanup archives the branch if asked, removes the worktree,
+// deletes the branch if it is merged or archived, and updates
+// workflow.jsonl, local.jsonl and allocations.jsonl
+func executeCleanup(plan *cleanupPlan, opts cleanupOptions) (*cleanupResult, error) {
+	baseRepo := filepath.Join(WorkspaceDir(), plan.Repo)
+	repo := git.NewRepo(baseRepo)
+	result := &cleanupResult{
+		Folder: plan.Folder,
+		Repo:   plan.Repo,
+		Branch: plan.Branch,
+		Status: "cleaned",
 	}
-	var newLocals []jsonl.LocalEntry
-	for _, l := range locals {
-		if l.Folder != folderName {
-			newLocals = append(newLocals, l)
+
+	// Save the branch before anything is removed
+	if opts.Archive == "tag" || opts.Archive == "bundle" {
+		archive, err := archiveBranch(repo, plan, opts.Archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive branch: %w", err)
 		}
+		result.Archive = archive
 	}
-	if err := store.WriteLocal(newLocals); err != nil {
-		return fmt.Errorf("failed to update local.jsonl: %w", err)
+
+	if !plan.Missing {
+		if err := repo.WorktreeRemove(plan.Path, opts.Force); err != nil {
+			return nil, fmt.Errorf("failed to remove worktree: %w", err)
+		}
 	}
 
-	fmt.Printf("Done. Worktree removed: %s\n", folderName)
-	return nil
+	// A squash or rebase merged branch is not an ancestor of the base and
+	// needs a forced delete; an archived branch is safe to delete. Otherwise
+	// delete only if git sees it as merged.
+	if plan.Merged || result.Archive != "" {
+		result.BranchDeleted = repo.BranchDelete(plan.Branch, true) == nil
+	} else {
+		result.BranchDeleted = repo.BranchDelete(plan.Branch, false) == nil
+	}
+	if plan.Merged || (result.BranchDeleted && result.Archive == "") {
+		result.Status = "merged"
+	}
+
+	// Update workflow.jsonl with actual status and drop the local.jsonl entry
+	// in one transaction so concurrent cleanups don't lose each other's writes
+	store := openStore()
+	err := store.Update(func(tx jsonl.Tx) error {
+		workflows, err := tx.Workflow()
+		if err != nil {
+			return err
+		}
+		for i, w := range workflows {
+			if w.Repo == plan.Repo && w.Branch == plan.Branch {
+				workflows[i].Status = result.Status
+				if result.Archive != "" {
+					workflows[i].Archive = result.Archive
+				}
+			}
+		}
+		tx.SetWorkflow(workflows)
+
+		locals, err := tx.Local()
+		if err != nil {
+			return err
+		}
+		var newLocals []jsonl.LocalEntry
+		for _, l := range locals {
+			if l.Folder != plan.Folder {
+				newLocals = append(newLocals, l)
+			}
+		}
+		tx.SetLocal(newLocals)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update manifests: %w", err)
+	}
+	if _, err := freeWorktree(plan.Folder); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// archiveBranch saves the branch as a tag in the repo or a bundle file
+// under ~/.bearing/archive and returns the tag name or bundle path
+func archiveBranch(repo *git.Repo, plan *cleanupPlan, kind string) (string, error) {
+	stamp := time.Now().Format("20060102-150405")
+	ref := "refs/heads/" + plan.Branch
+	if kind == "tag" {
+		name := "archive/" + plan.Branch + "/" + stamp
+		return name, repo.Tag(name, ref)
+	}
+	dir := filepath.Join(BearingDir(), "archive")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, plan.Folder+"-"+stamp+".bundle")
+	return path, repo.Bundle(path, ref)
 }
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	for answer, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		if got := confirm(strings.NewReader(answer), &out, "Proceed?"); got != want {
+			t.Errorf("confirm(%q) = %v, want %v", answer, got, want)
+		}
+		if out.String() != "Proceed? [y/N] " {
+			t.Errorf("unexpected prompt %q", out.String())
+		}
+	}
+}
+
+func TestPrintCleanupPlan(t *testing.T) {
+	plan := &cleanupPlan{Folder: "app-x", Branch: "x", Path: "/w/app-x", PRState: "MERGED", Merged: true}
+	var out bytes.Buffer
+	printCleanupPlan(&out, plan, cleanupOptions{Archive: "none"})
+	if !strings.Contains(out.String(), "pull request merged, will be deleted") || !strings.Contains(out.String(), "checks    ok") {
+		t.Errorf("unexpected summary:\n%s", out.String())
+	}
+
+	plan = &cleanupPlan{Folder: "app-y", Branch: "y", Path: "/w/app-y", Problems: []string{"uncommitted changes"}}
+	out.Reset()
+	printCleanupPlan(&out, plan, cleanupOptions{Force: true, Archive: "bundle"})
+	for _, want := range []string{"archived as a bundle", "problem   uncommitted changes", "--force given"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/daemon"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/spf13/cobra"
+)
+
+var conflictsJSON bool
+
+var worktreeConflictsCmd = &cobra.Command{
+	Use:   "conflicts [repo]",
+	Short: "Predict merge conflicts between worktrees",
+	Long: `Predict merge conflicts between the branches checked out in worktrees.
+
+Every pair of active branches in a repo, and each branch against its base,
+is compared: files changed on both sides since they diverged are listed as
+overlap, and an in-memory git merge-tree names the files that would
+conflict. Only committed changes are compared. Requires git 2.38 or later.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWorktreeConflicts,
+}
+
+func init() {
+	worktreeConflictsCmd.Flags().BoolVar(&conflictsJSON, "json", false, "output as JSON")
+	worktreeCmd.AddCommand(worktreeConflictsCmd)
+}
+
+func runWorktreeConflicts(cmd *cobra.Command, args []string) error {
+	store := openStore()
+	local, err := store.ReadLocal()
+	if err != nil {
+		return fmt.Errorf("failed to read local.jsonl: %w", err)
+	}
+
+	var entries []jsonl.LocalEntry
+	for _, e := range local {
+		if len(args) == 0 || e.Repo == args[0] {
+			entries = append(entries, e)
+		}
+	}
+	if len(args) > 0 && len(entries) == 0 {
+		return fmt.Errorf("no worktrees for repo: %s", args[0])
+	}
+
+	opts := daemon.LoadCheckOptions(store, time.Duration(settings().Daemon.Timeout)*time.Second)
+	conflicts := daemon.FindConflicts(context.Background(), WorkspaceDir(), entries, opts)
+
+	// Share the result with the dashboard through health.jsonl
+	with := daemon.ConflictsWith(conflicts)
+	checked := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		checked[e.Folder] = true
+	}
+	err = store.UpdateHealth(func(existing []jsonl.HealthEntry) ([]jsonl.HealthEntry, error) {
+		for i := range existing {
+			if checked[existing[i].Folder] {
+				existing[i].ConflictsWith = with[existing[i].Folder]
+			}
+		}
+		return existing, nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to save conflicts to health.jsonl: %v\n", err)
+	}
+
+	if conflictsJSON {
+		return json.NewEncoder(os.Stdout).Encode(conflicts)
+	}
+	if len(conflicts) == 0 {
+		fmt.Println("No overlapping changes")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tFOLDER\tOTHER\tCONFLICTS\tOVERLAP")
+	for _, c := range conflicts {
+		other := c.Other
+		if c.Base {
+			other = c.OtherBranch + " (base)"
+		}
+		conflicting := "-"
+		if c.Likely() {
+			conflicting = strings.Join(c.Conflicts, ", ")
+		}
+		overlap := strings.Join(c.Overlap, ", ")
+		if c.Error != "" {
+			overlap = "error: " + firstLine(c.Error)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Repo, c.Folder, other, conflicting, overlap)
+	}
+	return w.Flush()
+}
+
+// firstLine returns s up to its first newline
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
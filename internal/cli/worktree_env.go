@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/alloc"
+	"github.com/joshribakoff/bearing/internal/git"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	envExport bool
+	envJSON   bool
+)
+
+var worktreeEnvCmd = &cobra.Command{
+	Use:   "env <folder>",
+	Short: "Print the ports, database and cache dir allocated to a worktree",
+	Long: `Print the ports, database name and cache directory allocated to a
+worktree, as KEY=value lines for shells and agents:
+
+  eval "$(bearing worktree env myrepo-feature --export)"
+
+worktree new allocates them and writes them to .env.bearing in the
+worktree; worktree cleanup frees them. A worktree without an allocation,
+such as one created before allocations were enabled, gets one now.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorktreeEnv,
+}
+
+func init() {
+	worktreeEnvCmd.Flags().BoolVar(&envExport, "export", false, "prefix each line with export")
+	worktreeEnvCmd.Flags().BoolVar(&envJSON, "json", false, "output the allocation as JSON")
+	worktreeCmd.AddCommand(worktreeEnvCmd)
+}
+
+func runWorktreeEnv(cmd *cobra.Command, args []string) error {
+	e, err := openStore().FindLocal(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read local.jsonl: %w", err)
+	}
+	if e == nil {
+		return fmt.Errorf("folder not registered: %s", args[0])
+	}
+	if e.Base {
+		return fmt.Errorf("%s is a base folder; resources are allocated to worktrees", e.Folder)
+	}
+
+	a, err := allocateWorktree(e.Repo, e.Folder)
+	if err != nil {
+		return err
+	}
+	if envJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(a)
+	}
+	for _, kv := range alloc.Env(*a) {
+		if envExport {
+			fmt.Print("export ")
+		}
+		fmt.Printf("%s=%s\n", kv[0], alloc.Quote(kv[1]))
+	}
+	return nil
+}
+
+// allocateWorktree returns the resources allocated to folder, allocating
+// them first if needed, and writes them to its .env.bearing
+func allocateWorktree(repo, folder string) (*jsonl.AllocationEntry, error) {
+	cfg := settings()
+	cacheDir, err := cfg.AllocationCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	policy := alloc.Policy{
+		PortBase:         cfg.Allocations.PortBase,
+		PortsPerWorktree: cfg.Allocations.PortsPerWorktree,
+		CacheDir:         cacheDir,
+	}
+
+	var a jsonl.AllocationEntry
+	err = openStore().UpdateAllocations(func(entries []jsonl.AllocationEntry) ([]jsonl.AllocationEntry, error) {
+		var err error
+		entries, a, err = alloc.Allocate(entries, policy, folder, repo, time.Now())
+		return entries, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate resources: %w", err)
+	}
+
+	worktree := filepath.Join(WorkspaceDir(), folder)
+	if err := os.MkdirAll(a.CacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	// Keep the generated file out of commits and out of git's way when the
+	// worktree is removed
+	if err := git.NewRepo(worktree).Exclude(alloc.EnvFile); err != nil {
+		return nil, fmt.Errorf("failed to exclude %s: %w", alloc.EnvFile, err)
+	}
+	if err := alloc.WriteEnvFile(worktree, a); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", alloc.EnvFile, err)
+	}
+	return &a, nil
+}
+
+// freeWorktree releases the resources allocated to folder and removes its
+// cache dir. It returns the freed allocation, or nil if there was none.
+func freeWorktree(folder string) (*jsonl.AllocationEntry, error) {
+	var freed *jsonl.AllocationEntry
+	err := openStore().UpdateAllocations(func(entries []jsonl.AllocationEntry) ([]jsonl.AllocationEntry, error) {
+		entries, freed = alloc.Free(entries, folder)
+		return entries, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update allocations.jsonl: %w", err)
+	}
+	if freed != nil && freed.CacheDir != "" {
+		if err := os.RemoveAll(freed.CacheDir); err != nil {
+			return freed, fmt.Errorf("failed to remove cache dir: %w", err)
+		}
+	}
+	return freed, nil
+}
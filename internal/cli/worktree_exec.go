@@ -0,0 +1,319 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/alloc"
+	"github.com/joshribakoff/bearing/internal/git"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	execRepo     string
+	execFilters  []string
+	execParallel int
+	execGroup    bool
+	execJSON     bool
+)
+
+var worktreeExecCmd = &cobra.Command{
+	Use:   "exec [--repo R] [--filter F] -- <cmd> [args...]",
+	Short: "Run a command in many worktrees",
+	Long: `Run a command in each folder from local.jsonl, several at a time.
+
+--filter narrows the folders and may be repeated; a folder must match all:
+
+  dirty    has uncommitted changes
+  active   is a worktree whose branch is active in workflow.jsonl
+  has-pr   has a pull request, as of the daemon's last check
+
+Output lines are prefixed with the folder as they arrive, or with --group
+printed per folder once its command finishes. The command sees
+BEARING_FOLDER, BEARING_REPO and BEARING_BRANCH, plus the worktree's
+allocated ports and database. bearing exits non-zero if the command fails
+in any folder.
+
+  bearing worktree exec --repo myapp --filter active -- git pull --rebase`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runWorktreeExec,
+}
+
+func init() {
+	worktreeExecCmd.Flags().StringVar(&execRepo, "repo", "", "only folders of this repo")
+	worktreeExecCmd.Flags().StringSliceVar(&execFilters, "filter", nil, "only folders that are dirty, active or has-pr")
+	worktreeExecCmd.Flags().IntVarP(&execParallel, "parallel", "j", 4, "folders to run in at once")
+	worktreeExecCmd.Flags().BoolVar(&execGroup, "group", false, "print each folder's output together when it finishes")
+	worktreeExecCmd.Flags().BoolVar(&execJSON, "json", false, "print a JSON summary with each folder's output and exit code")
+	worktreeCmd.AddCommand(worktreeExecCmd)
+}
+
+// execFilterNames are the accepted --filter values
+var execFilterNames = []string{"dirty", "active", "has-pr"}
+
+// execTarget is a folder a command runs in
+type execTarget struct {
+	Folder string
+	Repo   string
+	Branch string
+	Env    []string // extra KEY=value variables
+}
+
+// execResult is the outcome of the command in one folder
+type execResult struct {
+	Folder     string `json:"folder"`
+	Repo       string `json:"repo"`
+	Branch     string `json:"branch"`
+	ExitCode   int    `json:"exitCode"` // -1 if the command could not run
+	DurationMs int64  `json:"durationMs"`
+	Output     string `json:"output,omitempty"` // stdout and stderr, with --json
+	Error      string `json:"error,omitempty"`  // why the command could not run
+}
+
+func runWorktreeExec(cmd *cobra.Command, args []string) error {
+	for _, f := range execFilters {
+		if !slices.Contains(execFilterNames, f) {
+			return fmt.Errorf("unknown filter %q (want %s)", f, strings.Join(execFilterNames, ", "))
+		}
+	}
+	if execParallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+
+	targets, err := selectExecTargets(execRepo, execFilters)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		if execJSON {
+			fmt.Println("[]")
+			return nil
+		}
+		fmt.Fprintln(os.Stderr, "No folders match")
+		return nil
+	}
+
+	mode := execPrefixed
+	if execJSON {
+		mode = execCaptured
+	} else if execGroup {
+		mode = execGrouped
+	}
+	results := execInWorktrees(targets, args, execParallel, mode, os.Stdout)
+
+	var failed []string
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			failed = append(failed, fmt.Sprintf("%s (exit %d)", r.Folder, r.ExitCode))
+		}
+	}
+	if execJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("command failed in %d of %d folders: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// selectExecTargets returns the folders of repo (all repos if empty)
+// matching every filter
+func selectExecTargets(repo string, filters []string) ([]execTarget, error) {
+	store := openStore()
+	locals, err := store.ReadLocal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local.jsonl: %w", err)
+	}
+	workflow, _ := store.ReadWorkflow()
+	health, _ := store.ReadHealth()
+	allocations, _ := store.ReadAllocations()
+
+	// The newest workflow entry of a branch is its current status
+	active := make(map[string]bool)
+	for _, w := range workflow {
+		active[w.Repo+"/"+w.Branch] = w.Status == "active"
+	}
+	prs := make(map[string]bool)
+	for _, h := range health {
+		prs[h.Folder] = h.PRState != nil
+	}
+	dirty := func(folder string) bool {
+		d, err := git.NewRepo(filepath.Join(WorkspaceDir(), folder)).IsDirty()
+		return err == nil && d
+	}
+
+	var targets []execTarget
+	for _, e := range locals {
+		if repo != "" && e.Repo != repo {
+			continue
+		}
+		if !matchesExecFilters(e, filters, active, prs, dirty) {
+			continue
+		}
+		t := execTarget{Folder: e.Folder, Repo: e.Repo, Branch: e.Branch}
+		if a := alloc.Find(allocations, e.Folder); a != nil {
+			for _, kv := range alloc.Env(*a) {
+				t.Env = append(t.Env, kv[0]+"="+kv[1])
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// matchesExecFilters reports whether e matches every filter. dirty is only
+// called when a filter needs it, as it queries git.
+func matchesExecFilters(e jsonl.LocalEntry, filters []string, active, prs map[string]bool, dirty func(folder string) bool) bool {
+	for _, f := range filters {
+		switch f {
+		case "active":
+			if e.Base || !active[e.Repo+"/"+e.Branch] {
+				return false
+			}
+		case "has-pr":
+			if !prs[e.Folder] {
+				return false
+			}
+		case "dirty":
+			if !dirty(e.Folder) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Output modes of execInWorktrees
+const (
+	execPrefixed = iota // lines prefixed with the folder as they arrive
+	execGrouped         // each folder's output together once it finishes
+	execCaptured        // kept in the results, not written
+)
+
+// execInWorktrees runs argv in each target, parallel at a time, writing
+// output to out in mode, and returns the results in target order
+func execInWorktrees(targets []execTarget, argv []string, parallel, mode int, out io.Writer) []execResult {
+	width := 0
+	for _, t := range targets {
+		width = max(width, len(t.Folder))
+	}
+
+	var mu sync.Mutex // serializes writes to out
+	results := make([]execResult, len(targets))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(parallel, len(targets)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				t := targets[i]
+				var buf bytes.Buffer
+				var w io.Writer = &buf
+				var pw *prefixWriter
+				if mode == execPrefixed {
+					pw = &prefixWriter{mu: &mu, out: out, prefix: fmt.Sprintf("%-*s | ", width, t.Folder)}
+					w = pw
+				}
+				results[i] = execIn(t, argv, w)
+				if pw != nil {
+					pw.Flush()
+				}
+				switch mode {
+				case execCaptured:
+					results[i].Output = buf.String()
+				case execGrouped:
+					mu.Lock()
+					fmt.Fprintf(out, "==> %s (exit %d, %s)\n", t.Folder, results[i].ExitCode, time.Duration(results[i].DurationMs)*time.Millisecond)
+					out.Write(buf.Bytes())
+					if buf.Len() > 0 && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+						fmt.Fprintln(out)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// execIn runs argv in t's folder with its output written to w
+func execIn(t execTarget, argv []string, w io.Writer) execResult {
+	start := time.Now()
+	r := execResult{Folder: t.Folder, Repo: t.Repo, Branch: t.Branch}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = filepath.Join(WorkspaceDir(), t.Folder)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	cmd.Env = append(os.Environ(),
+		"BEARING_FOLDER="+t.Folder,
+		"BEARING_REPO="+t.Repo,
+		"BEARING_BRANCH="+t.Branch,
+	)
+	cmd.Env = append(cmd.Env, t.Env...)
+	err := cmd.Run()
+	r.DurationMs = time.Since(start).Milliseconds()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		r.ExitCode = exitErr.ExitCode()
+	default:
+		r.ExitCode = -1
+		r.Error = err.Error()
+		fmt.Fprintf(w, "bearing: %v\n", err)
+	}
+	return r
+}
+
+// prefixWriter writes whole lines to out, each starting with prefix, so
+// lines from commands running at once don't mix
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.mu.Lock()
+		fmt.Fprintf(w.out, "%s%s", w.prefix, w.buf[:i+1])
+		w.mu.Unlock()
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Flush writes a final line that has no newline
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.Write([]byte("\n"))
+	}
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+func TestMatchesExecFilters(t *testing.T) {
+	active := map[string]bool{"app/feature": true, "app/old": false}
+	prs := map[string]bool{"app-feature": true}
+	dirtyCalls := 0
+	dirty := func(folder string) bool {
+		dirtyCalls++
+		return folder == "app-old"
+	}
+
+	feature := jsonl.LocalEntry{Folder: "app-feature", Repo: "app", Branch: "feature"}
+	old := jsonl.LocalEntry{Folder: "app-old", Repo: "app", Branch: "old"}
+	base := jsonl.LocalEntry{Folder: "app", Repo: "app", Branch: "feature", Base: true}
+
+	cases := []struct {
+		e       jsonl.LocalEntry
+		filters []string
+		want    bool
+	}{
+		{feature, nil, true},
+		{feature, []string{"active", "has-pr"}, true},
+		{old, []string{"active"}, false},
+		{base, []string{"active"}, false},
+		{old, []string{"dirty"}, true},
+		{feature, []string{"has-pr", "dirty"}, false},
+	}
+	for _, c := range cases {
+		if got := matchesExecFilters(c.e, c.filters, active, prs, dirty); got != c.want {
+			t.Errorf("%s %v: got %v, want %v", c.e.Folder, c.filters, got, c.want)
+		}
+	}
+
+	// git is only queried when the cheaper filters pass
+	dirtyCalls = 0
+	matchesExecFilters(old, []string{"active", "dirty"}, active, prs, dirty)
+	if dirtyCalls != 0 {
+		t.Errorf("expected no dirty check, got %d", dirtyCalls)
+	}
+}
+
+func TestExecInWorktrees(t *testing.T) {
+	ws := t.TempDir()
+	workspaceDir = ws
+	defer func() { workspaceDir = "" }()
+	for _, f := range []string{"a", "bb"} {
+		os.Mkdir(filepath.Join(ws, f), 0755)
+	}
+	targets := []execTarget{{Folder: "a", Env: []string{"PORT=1"}}, {Folder: "bb", Env: []string{"PORT=2"}}}
+	argv := []string{"sh", "-c", `echo "port $PORT"; printf partial; [ "$BEARING_FOLDER" = a ]`}
+
+	var out bytes.Buffer
+	results := execInWorktrees(targets, argv, 2, execPrefixed, &out)
+	if results[0].ExitCode != 0 || results[1].ExitCode != 1 {
+		t.Errorf("unexpected exit codes: %+v", results)
+	}
+	for _, want := range []string{"a  | port 1\n", "bb | port 2\n", "a  | partial\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	results = execInWorktrees(targets, argv, 1, execCaptured, &out)
+	if out.Len() != 0 || results[1].Output != "port 2\npartial" {
+		t.Errorf("expected captured output, got %q and %+v", out.String(), results)
+	}
+
+	results = execInWorktrees(targets[:1], []string{"no-such-command-xyz"}, 1, execGrouped, &out)
+	if results[0].ExitCode != -1 || results[0].Error == "" || !strings.Contains(out.String(), "==> a (exit -1") {
+		t.Errorf("expected start failure reported, got %+v\n%s", results, out.String())
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &prefixWriter{mu: &sync.Mutex{}, out: &out, prefix: "x | "}
+	w.Write([]byte("one\ntw"))
+	w.Write([]byte("o\nthree"))
+	w.Flush()
+	if out.String() != "x | one\nx | two\nx | three\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
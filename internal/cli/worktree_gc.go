@@ -0,0 +1,351 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/gh"
+	"github.com/joshribakoff/bearing/internal/git"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcRepo     string
+	gcDryRun   bool
+	gcYes      bool
+	gcJSON     bool
+	gcIdleDays int
+	gcArchive  string
+)
+
+var worktreeGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove worktrees whose work is merged, closed or abandoned",
+	Long: `Find worktrees that are no longer needed and clean them up.
+
+A worktree is collected when its pull request was merged or closed, when
+its branch's changes are already in its base branch, even squashed, or
+when nothing has happened in it for gc.idleDays days (commits,
+checkouts, agent sessions). Each one goes through the same checks as
+worktree cleanup, and is skipped if any fail. Outcomes are recorded on
+the branch's workflow.jsonl entry.
+
+With gc.auto set, the daemon runs gc every gc.interval hours.`,
+	Args: cobra.NoArgs,
+	RunE: runWorktreeGC,
+}
+
+func init() {
+	worktreeGCCmd.Flags().StringVar(&gcRepo, "repo", "", "only worktrees of this repo")
+	worktreeGCCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "report what would be cleaned up without removing anything")
+	worktreeGCCmd.Flags().BoolVarP(&gcYes, "yes", "y", false, "don't ask for confirmation")
+	worktreeGCCmd.Flags().BoolVar(&gcJSON, "json", false, "output results as JSON")
+	worktreeGCCmd.Flags().IntVar(&gcIdleDays, "idle-days", 0, "days without activity before a worktree counts as abandoned (default: gc.idleDays; 0 never)")
+	worktreeGCCmd.Flags().StringVar(&gcArchive, "archive", "", "save branches first as a tag or bundle (default: cleanup.archive)")
+	worktreeCmd.AddCommand(worktreeGCCmd)
+}
+
+// containedGrace keeps gc away from new worktrees, whose branch starts out
+// contained in its base
+const containedGrace = 24 * time.Hour
+
+// gcCandidate is a worktree gc would clean up, and why
+type gcCandidate struct {
+	Folder string `json:"folder"`
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+	Reason string `json:"reason"` // merged, closed, contained or idle
+	Detail string `json:"detail"`
+}
+
+// gcResult is what gc did with a candidate
+type gcResult struct {
+	gcCandidate
+	Outcome  string   `json:"outcome"` // cleaned, skipped, failed or, with --dry-run, would clean
+	Problems []string `json:"problems,omitempty"`
+	Archive  string   `json:"archive,omitempty"`
+}
+
+func runWorktreeGC(cmd *cobra.Command, args []string) error {
+	idleDays := settings().GC.IdleDays
+	if cmd.Flags().Changed("idle-days") {
+		idleDays = gcIdleDays
+	}
+	opts := cleanupOptions{Archive: gcArchive}
+	if opts.Archive == "" {
+		opts.Archive = settings().Cleanup.Archive
+	}
+	if opts.Archive != "none" && opts.Archive != "tag" && opts.Archive != "bundle" {
+		return fmt.Errorf("--archive must be none, tag or bundle, got %q", opts.Archive)
+	}
+
+	candidates, err := findGCCandidates(gcRepo, time.Duration(idleDays)*24*time.Hour, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		if gcJSON {
+			fmt.Println("[]")
+		} else {
+			fmt.Println("No worktrees to clean up")
+		}
+		return nil
+	}
+
+	if !gcJSON && !gcDryRun {
+		fmt.Printf("Found %d worktree(s) to clean up:\n", len(candidates))
+		for _, c := range candidates {
+			fmt.Printf("  %s (%s: %s)\n", c.Folder, c.Reason, c.Detail)
+		}
+		if !gcYes && stdinIsTerminal() && !confirm(os.Stdin, os.Stdout, "Proceed?") {
+			return fmt.Errorf("gc cancelled")
+		}
+	}
+
+	results, recordErr := collectGarbage(candidates, opts, gcDryRun, "cli")
+	if gcJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+		return recordErr
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FOLDER\tREASON\tOUTCOME\tDETAIL")
+	for _, r := range results {
+		detail := r.Detail
+		if len(r.Problems) > 0 {
+			detail = fmt.Sprint(r.Problems)
+		} else if r.Archive != "" {
+			detail += ", archived to " + r.Archive
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Folder, r.Reason, r.Outcome, detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return recordErr
+}
+
+// findGCCandidates returns the worktrees of repo (all repos if empty)
+// that gc would clean up at now. Worktrees idle for longer than idle count
+// as abandoned; 0 disables that.
+func findGCCandidates(repo string, idle time.Duration, now time.Time) ([]gcCandidate, error) {
+	store := openStore()
+	locals, err := store.ReadLocal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local.jsonl: %w", err)
+	}
+	workflow, _ := store.ReadWorkflow()
+	sessions, _ := store.ReadSessions()
+	latest := session.ByFolder(sessions)
+
+	// The newest workflow entry of a branch is the current one
+	current := make(map[string]jsonl.WorkflowEntry)
+	for _, w := range workflow {
+		current[w.Repo+"/"+w.Branch] = w
+	}
+
+	var entries []jsonl.LocalEntry
+	for _, e := range locals {
+		if !e.Base && (repo == "" || e.Repo == repo) {
+			entries = append(entries, e)
+		}
+	}
+
+	timeout := time.Duration(settings().Daemon.Timeout) * time.Second
+	found := make([]*gcCandidate, len(entries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(settings().Daemon.Workers, len(entries)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				e := entries[i]
+				w := current[e.Repo+"/"+e.Branch]
+				var s *jsonl.SessionEntry
+				if l, ok := latest[e.Folder]; ok {
+					s = &l
+				}
+				found[i] = evaluateGC(e, w, s, idle, timeout, now)
+			}
+		}()
+	}
+	for i := range entries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var candidates []gcCandidate
+	for _, c := range found {
+		if c != nil {
+			candidates = append(candidates, *c)
+		}
+	}
+	return candidates, nil
+}
+
+// evaluateGC decides whether the worktree e is garbage, querying gh and
+// git, and returns nil if it is not
+func evaluateGC(e jsonl.LocalEntry, w jsonl.WorkflowEntry, s *jsonl.SessionEntry, idle, timeout time.Duration, now time.Time) *gcCandidate {
+	path := filepath.Join(WorkspaceDir(), e.Folder)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	ctx := context.Background()
+	repo := git.NewRepo(path).WithTimeout(ctx, timeout)
+
+	// gh may be missing or offline; the git checks still apply
+	pr, _ := gh.NewClient(path).WithTimeout(ctx, timeout).GetPR(e.Branch)
+
+	base := w.BasedOn
+	if base == "" {
+		base = DefaultBranch(e.Repo)
+	}
+	ref := base
+	if repo.RemoteBranchExists(base) {
+		ref = "origin/" + base
+	}
+	contained, err := repo.IsMergedInto(e.Branch, ref)
+	if err != nil {
+		contained = false
+	}
+
+	// The last sign of life: creation, a commit, an agent, or a checkout or
+	// reset in the HEAD reflog. Not the index, which git status rewrites.
+	last := w.Created
+	if t, err := repo.CommitTime(e.Branch); err == nil && t.After(last) {
+		last = t
+	}
+	if s != nil && s.LastActivity.After(last) {
+		last = s.LastActivity
+	}
+	if gitDir, err := git.GitDir(path); err == nil {
+		if info, err := os.Stat(filepath.Join(gitDir, "logs", "HEAD")); err == nil && info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+
+	reason, detail := gcReason(pr, contained, ref, last, now, idle)
+	if reason == "" {
+		return nil
+	}
+	return &gcCandidate{Folder: e.Folder, Repo: e.Repo, Branch: e.Branch, Reason: reason, Detail: detail}
+}
+
+// gcReason says why a worktree is garbage, or returns "" if it is not.
+// pr is its pull request, if any; contained whether its branch is in
+// base; last its latest activity.
+func gcReason(pr *gh.PRInfo, contained bool, base string, last, now time.Time, idle time.Duration) (reason, detail string) {
+	inactive := now.Sub(last)
+	switch {
+	case pr != nil && pr.State == "MERGED":
+		return "merged", fmt.Sprintf("pull request #%d merged", pr.Number)
+	case pr != nil && pr.State == "CLOSED":
+		return "closed", fmt.Sprintf("pull request #%d closed", pr.Number)
+	case pr != nil && pr.State == "OPEN":
+		// Under review, however quiet
+		return "", ""
+	case contained && inactive > containedGrace:
+		return "contained", "changes are in " + base
+	case idle > 0 && inactive > idle:
+		return "idle", "no activity for " + shortDuration(inactive)
+	}
+	return "", ""
+}
+
+// collectGarbage runs cleanup's checks on each candidate and cleans up
+// those that pass, recording outcomes in workflow.jsonl unless dryRun.
+// by says who asked: cli or daemon. The results are returned even when
+// recording them fails.
+func collectGarbage(candidates []gcCandidate, opts cleanupOptions, dryRun bool, by string) ([]gcResult, error) {
+	results := make([]gcResult, 0, len(candidates))
+	for _, c := range candidates {
+		r := gcResult{gcCandidate: c}
+		plan, err := planCleanup(c.Repo, c.Branch, "")
+		switch {
+		case err != nil:
+			r.Outcome = "failed"
+			r.Problems = []string{err.Error()}
+		case len(plan.Problems) > 0:
+			r.Outcome = "skipped"
+			r.Problems = plan.Problems
+		case dryRun:
+			r.Outcome = "would clean"
+		default:
+			if result, err := executeCleanup(plan, opts); err != nil {
+				r.Outcome = "failed"
+				r.Problems = []string{err.Error()}
+			} else {
+				r.Outcome = "cleaned"
+				r.Archive = result.Archive
+			}
+		}
+		results = append(results, r)
+	}
+	if !dryRun {
+		if err := recordGC(results, by, time.Now()); err != nil {
+			return results, fmt.Errorf("failed to record gc outcomes in workflow.jsonl: %w", err)
+		}
+	}
+	return results, nil
+}
+
+// recordGC stores each result on its branch's newest workflow.jsonl entry
+func recordGC(results []gcResult, by string, now time.Time) error {
+	return openStore().UpdateWorkflow(func(entries []jsonl.WorkflowEntry) ([]jsonl.WorkflowEntry, error) {
+		for _, r := range results {
+			for i := len(entries) - 1; i >= 0; i-- {
+				if entries[i].Repo == r.Repo && entries[i].Branch == r.Branch {
+					entries[i].GC = &jsonl.GCStatus{
+						Reason:   r.Reason,
+						Outcome:  r.Outcome,
+						Problems: r.Problems,
+						By:       by,
+						Time:     now,
+					}
+					break
+				}
+			}
+		}
+		return entries, nil
+	})
+}
+
+// daemonGC is the daemon's gc policy: collect with the configured
+// settings, without asking, and log what was done
+func daemonGC() int {
+	cfg := settings()
+	candidates, err := findGCCandidates("", time.Duration(cfg.GC.IdleDays)*24*time.Hour, time.Now())
+	if err != nil {
+		fmt.Printf("Error finding worktrees to clean up: %v\n", err)
+		return 0
+	}
+	if len(candidates) == 0 {
+		return 0
+	}
+	results, err := collectGarbage(candidates, cleanupOptions{Archive: cfg.Cleanup.Archive}, false, "daemon")
+	if err != nil {
+		fmt.Printf("Error recording gc outcomes: %v\n", err)
+	}
+	removed := 0
+	for _, r := range results {
+		fmt.Printf("gc: %s (%s) %s\n", r.Folder, r.Reason, r.Outcome)
+		if r.Outcome == "cleaned" {
+			removed++
+		}
+	}
+	return removed
+}
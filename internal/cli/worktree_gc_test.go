@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/config"
+	"github.com/joshribakoff/bearing/internal/gh"
+)
+
+func TestGCReason(t *testing.T) {
+	now := time.Now()
+	hour, day := now.Add(-time.Hour), now.Add(-48*time.Hour)
+	month := now.Add(-40 * 24 * time.Hour)
+	idle := 30 * 24 * time.Hour
+
+	cases := []struct {
+		name      string
+		pr        *gh.PRInfo
+		contained bool
+		last      time.Time
+		want      string
+	}{
+		{"merged", &gh.PRInfo{State: "MERGED", Number: 7}, false, hour, "merged"},
+		{"closed", &gh.PRInfo{State: "CLOSED", Number: 7}, false, hour, "closed"},
+		{"open and quiet", &gh.PRInfo{State: "OPEN", Number: 7}, true, month, ""},
+		{"contained", nil, true, day, "contained"},
+		{"contained but new", nil, true, hour, ""},
+		{"idle", nil, false, month, "idle"},
+		{"active", nil, false, day, ""},
+	}
+	for _, c := range cases {
+		if got, _ := gcReason(c.pr, c.contained, "main", c.last, now, idle); got != c.want {
+			t.Errorf("%s: gcReason = %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	if got, _ := gcReason(nil, false, "main", month, now, 0); got != "" {
+		t.Errorf("idle 0 should disable idle collection, got %q", got)
+	}
+	if _, detail := gcReason(nil, true, "origin/main", day, now, idle); detail != "changes are in origin/main" {
+		t.Errorf("unexpected detail %q", detail)
+	}
+}
+
+// useWorkspace points the package at a fresh workspace dir with the
+// default config and empty caches
+func useWorkspace(t *testing.T, dir string) {
+	t.Helper()
+	reset := func(ws string) {
+		closeStore()
+		workspaceDir = ws
+		loadedConfig = config.Default()
+		projectsCache = nil
+		detectedBranches = map[string]string{}
+	}
+	reset(dir)
+	t.Cleanup(func() {
+		reset("")
+		loadedConfig = nil
+	})
+}
+
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.email=t@t", "-c", "user.name=T"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestFindGCCandidatesDetectsBasesConcurrently(t *testing.T) {
+	ws := t.TempDir()
+	useWorkspace(t, ws)
+
+	// Several projects whose worktrees have no basedOn, so each worker
+	// detects a default branch
+	var local, projects strings.Builder
+	for i := range 4 {
+		name := fmt.Sprintf("p%d", i)
+		origin := filepath.Join(ws, "origins", name)
+		os.MkdirAll(origin, 0755)
+		gitIn(t, origin, "init", "-q", "--initial-branch=main")
+		gitIn(t, origin, "commit", "-q", "--allow-empty", "-m", "initial")
+		gitIn(t, ws, "clone", "-q", origin, name)
+		gitIn(t, filepath.Join(ws, name), "worktree", "add", "-q", "-b", "feature", filepath.Join(ws, name+"-feature"))
+		gitIn(t, filepath.Join(ws, name+"-feature"), "commit", "-q", "--allow-empty", "-m", "work")
+		fmt.Fprintf(&local, `{"folder":%q,"repo":%q,"branch":"feature"}`+"\n", name+"-feature", name)
+		fmt.Fprintf(&projects, `{"name":%q,"path":%q}`+"\n", name, name)
+	}
+	os.WriteFile(filepath.Join(ws, "local.jsonl"), []byte(local.String()), 0644)
+	os.WriteFile(filepath.Join(ws, "projects.jsonl"), []byte(projects.String()), 0644)
+
+	candidates, err := findGCCandidates("", 0, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 0 {
+		t.Errorf("expected no candidates, got %+v", candidates)
+	}
+	for i := range 4 {
+		if b := detectedBranches[fmt.Sprintf("p%d", i)]; b != "main" {
+			t.Errorf("p%d: detected %q, want main", i, b)
+		}
+	}
+}
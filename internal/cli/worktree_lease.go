@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/lease"
+	"github.com/spf13/cobra"
+)
+
+var (
+	leaseOwner string
+	leaseTTL   time.Duration
+	leaseForce bool
+)
+
+var worktreeClaimCmd = &cobra.Command{
+	Use:   "claim <folder>",
+	Short: "Claim exclusive ownership of a worktree",
+	Long: `Claim exclusive ownership of a worktree for an agent session.
+
+While the lease is live, the worktree check hook blocks any other session
+working in the folder. The lease lapses after --ttl unless renewed with
+heartbeat; claiming again as the same owner also renews it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorktreeClaim,
+}
+
+var worktreeReleaseCmd = &cobra.Command{
+	Use:   "release <folder>",
+	Short: "Release a worktree lease",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWorktreeRelease,
+}
+
+var worktreeHeartbeatCmd = &cobra.Command{
+	Use:   "heartbeat <folder>",
+	Short: "Renew a worktree lease for another TTL",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runWorktreeHeartbeat,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{worktreeClaimCmd, worktreeReleaseCmd, worktreeHeartbeatCmd} {
+		c.Flags().StringVar(&leaseOwner, "owner", "", "lease owner, e.g. the agent session ID")
+		worktreeCmd.AddCommand(c)
+	}
+	worktreeClaimCmd.MarkFlagRequired("owner")
+	worktreeHeartbeatCmd.MarkFlagRequired("owner")
+	worktreeClaimCmd.Flags().DurationVar(&leaseTTL, "ttl", lease.DefaultTTL, "how long the lease lasts without a heartbeat")
+	worktreeReleaseCmd.Flags().BoolVar(&leaseForce, "force", false, "release the lease whoever holds it")
+}
+
+func runWorktreeClaim(cmd *cobra.Command, args []string) error {
+	folder := args[0]
+	store := openStore()
+	e, err := store.FindLocal(folder)
+	if err != nil {
+		return fmt.Errorf("failed to read local.jsonl: %w", err)
+	}
+	if e == nil {
+		return fmt.Errorf("folder not registered: %s", folder)
+	}
+
+	l, err := lease.Claim(store, folder, leaseOwner, leaseTTL, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Claimed %s for %s until %s\n", folder, l.Owner, l.Expires.Local().Format(time.DateTime))
+	return nil
+}
+
+func runWorktreeRelease(cmd *cobra.Command, args []string) error {
+	if leaseOwner == "" && !leaseForce {
+		return fmt.Errorf("--owner is required unless --force is set")
+	}
+	if err := lease.Release(openStore(), args[0], leaseOwner, leaseForce); err != nil {
+		return err
+	}
+	fmt.Printf("Released %s\n", args[0])
+	return nil
+}
+
+func runWorktreeHeartbeat(cmd *cobra.Command, args []string) error {
+	l, err := lease.Heartbeat(openStore(), args[0], leaseOwner, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Renewed %s until %s\n", args[0], l.Expires.Local().Format(time.DateTime))
+	return nil
+}
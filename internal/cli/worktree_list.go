@@ -30,7 +30,7 @@ func init() {
 }
 
 func runWorktreeList(cmd *cobra.Command, args []string) error {
-	store := jsonl.NewStore(WorkspaceDir())
+	store := openStore()
 
 	if listWorkflow {
 		return listWorkflowEntries(store)
@@ -38,7 +38,7 @@ func runWorktreeList(cmd *cobra.Command, args []string) error {
 	return listLocalEntries(store)
 }
 
-func listLocalEntries(store *jsonl.Store) error {
+func listLocalEntries(store jsonl.Store) error {
 	entries, err := store.ReadLocal()
 	if err != nil {
 		return err
@@ -60,7 +60,7 @@ func listLocalEntries(store *jsonl.Store) error {
 	return w.Flush()
 }
 
-func listWorkflowEntries(store *jsonl.Store) error {
+func listWorkflowEntries(store jsonl.Store) error {
 	entries, err := store.ReadWorkflow()
 	if err != nil {
 		return err
@@ -2,18 +2,21 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/joshribakoff/bearing/internal/alloc"
 	"github.com/joshribakoff/bearing/internal/git"
 	"github.com/joshribakoff/bearing/internal/jsonl"
 	"github.com/spf13/cobra"
 )
 
 var (
-	newBasedOn string
-	newPurpose string
+	newBasedOn     string
+	newPurpose     string
+	newNoBootstrap bool
 )
 
 var worktreeNewCmd = &cobra.Command{
@@ -26,66 +29,113 @@ var worktreeNewCmd = &cobra.Command{
 func init() {
 	worktreeNewCmd.Flags().StringVar(&newBasedOn, "based-on", "", "base branch (default: main)")
 	worktreeNewCmd.Flags().StringVar(&newPurpose, "purpose", "", "purpose description")
+	worktreeNewCmd.Flags().BoolVar(&newNoBootstrap, "no-bootstrap", false, "skip bootstrapping the new worktree")
 	worktreeCmd.AddCommand(worktreeNewCmd)
 }
 
 func runWorktreeNew(cmd *cobra.Command, args []string) error {
-	repoName := args[0]
-	branch := args[1]
+	fmt.Printf("Creating worktree: %s\n", filepath.Join(WorkspaceDir(), worktreeFolderName(args[0], args[1])))
+	result, err := createWorktree(args[0], args[1], newBasedOn, newPurpose)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Done. Worktree created at: %s\n", result.Path)
+	if a := result.Allocation; a != nil {
+		fmt.Printf("Allocated ports %d-%d, database %s (see %s)\n", a.Port, a.Port+a.PortCount-1, a.Database, alloc.EnvFile)
+	}
+
+	if newNoBootstrap {
+		return nil
+	}
+	_, err = bootstrapWorktree(result.Repo, result.Branch, result.Folder, os.Stdout)
+	return err
+}
+
+// newWorktreeResult describes a worktree created by createWorktree
+type newWorktreeResult struct {
+	Folder  string `json:"folder"`
+	Path    string `json:"path"`
+	Repo    string `json:"repo"`
+	Branch  string `json:"branch"`
+	BasedOn string `json:"basedOn"`
+
+	Allocation *jsonl.AllocationEntry `json:"allocation,omitempty"`
+	Bootstrap  *jsonl.BootstrapStatus `json:"bootstrap,omitempty"`
+}
 
+// createWorktree adds a worktree for branch and records it in workflow.jsonl
+// and local.jsonl
+func createWorktree(repoName, branch, basedOn, purpose string) (*newWorktreeResult, error) {
 	// Validate arguments
 	if strings.TrimSpace(repoName) == "" {
-		return fmt.Errorf("repo name cannot be empty")
+		return nil, fmt.Errorf("repo name cannot be empty")
 	}
 	if strings.TrimSpace(branch) == "" {
-		return fmt.Errorf("branch name cannot be empty")
+		return nil, fmt.Errorf("branch name cannot be empty")
 	}
 	if strings.HasPrefix(branch, "-") {
-		return fmt.Errorf("branch name cannot start with a hyphen")
+		return nil, fmt.Errorf("branch name cannot start with a hyphen")
 	}
 
-	// Sanitize branch name for folder (replace / with -)
-	folderBranch := strings.ReplaceAll(branch, "/", "-")
-	folderName := fmt.Sprintf("%s-%s", repoName, folderBranch)
-
+	folderName := worktreeFolderName(repoName, branch)
 	baseRepo := filepath.Join(WorkspaceDir(), repoName)
 	worktreePath := filepath.Join(WorkspaceDir(), folderName)
-	store := jsonl.NewStore(WorkspaceDir())
+	store := openStore()
 
 	repo := git.NewRepo(baseRepo)
 
 	// Determine start point
-	basedOn := newBasedOn
 	if basedOn == "" {
-		basedOn = "main"
+		basedOn = DefaultBranch(repoName)
 	}
 
 	// Create the worktree from basedOn branch
-	fmt.Printf("Creating worktree: %s\n", worktreePath)
 	if err := repo.WorktreeAdd(worktreePath, branch, basedOn); err != nil {
-		return fmt.Errorf("failed to create worktree: %w", err)
+		return nil, fmt.Errorf("failed to create worktree: %w", err)
+	}
+
+	// Record in workflow.jsonl and local.jsonl together
+	err := store.Update(func(tx jsonl.Tx) error {
+		workflows, err := tx.Workflow()
+		if err != nil {
+			return err
+		}
+		tx.SetWorkflow(append(workflows, jsonl.WorkflowEntry{
+			Repo:    repoName,
+			Branch:  branch,
+			BasedOn: basedOn,
+			Purpose: purpose,
+			Status:  "active",
+			Created: time.Now(),
+		}))
+
+		locals, err := tx.Local()
+		if err != nil {
+			return err
+		}
+		tx.SetLocal(append(locals, jsonl.LocalEntry{
+			Folder: folderName,
+			Repo:   repoName,
+			Branch: branch,
+			Base:   false,
+		}))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update manifests: %w", err)
 	}
-	if err := store.AppendWorkflow(jsonl.WorkflowEntry{
+
+	result := &newWorktreeResult{
+		Folder:  folderName,
+		Path:    worktreePath,
 		Repo:    repoName,
 		Branch:  branch,
 		BasedOn: basedOn,
-		Purpose: newPurpose,
-		Status:  "active",
-		Created: time.Now(),
-	}); err != nil {
-		return fmt.Errorf("failed to update workflow.jsonl: %w", err)
 	}
-
-	// Add to local.jsonl
-	if err := store.AppendLocal(jsonl.LocalEntry{
-		Folder: folderName,
-		Repo:   repoName,
-		Branch: branch,
-		Base:   false,
-	}); err != nil {
-		return fmt.Errorf("failed to update local.jsonl: %w", err)
+	if settings().Allocations.Enabled {
+		if result.Allocation, err = allocateWorktree(repoName, folderName); err != nil {
+			return nil, err
+		}
 	}
-
-	fmt.Printf("Done. Worktree created at: %s\n", worktreePath)
-	return nil
+	return result, nil
 }
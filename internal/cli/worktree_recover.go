@@ -30,7 +30,7 @@ func runWorktreeRecover(cmd *cobra.Command, args []string) error {
 	baseFolder := args[0]
 	basePath := filepath.Join(WorkspaceDir(), baseFolder)
 	repo := git.NewRepo(basePath)
-	store := jsonl.NewStore(WorkspaceDir())
+	store := openStore()
 
 	// Fetch latest remote state
 	fmt.Println("Fetching remote branches...")
@@ -58,8 +58,8 @@ func runWorktreeRecover(cmd *cobra.Command, args []string) error {
 	// Find branches that have remote but no local worktree
 	var toRecover []string
 	for _, branch := range remoteBranches {
-		// Skip main/master
-		if branch == "main" || branch == "master" {
+		// Skip base branches
+		if isBaseBranch(baseFolder, branch) {
 			continue
 		}
 		if !existingBranches[branch] {
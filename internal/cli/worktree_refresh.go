@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/daemon"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/spf13/cobra"
+)
+
+var refreshJSON bool
+
+var worktreeRefreshCmd = &cobra.Command{
+	Use:   "refresh <folder>",
+	Short: "Re-check one worktree's health now",
+	Long: `Re-check one worktree's health now and merge the result into health.jsonl.
+
+When the daemon is running the check jumps its queue, so the dashboard
+updates too. Otherwise the check runs in this process.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWorktreeRefresh,
+}
+
+func init() {
+	worktreeRefreshCmd.Flags().BoolVar(&refreshJSON, "json", false, "output as JSON")
+	worktreeCmd.AddCommand(worktreeRefreshCmd)
+}
+
+func runWorktreeRefresh(cmd *cobra.Command, args []string) error {
+	folder := args[0]
+
+	h, reached, err := refreshViaDaemon(folder)
+	if !reached {
+		h, err = refreshLocally(folder)
+	}
+	if err != nil {
+		return err
+	}
+
+	if refreshJSON {
+		return json.NewEncoder(os.Stdout).Encode(h)
+	}
+	fmt.Println(formatHealth(h))
+	return nil
+}
+
+// refreshFallbackTimeout bounds a daemon refresh when daemon.timeout is 0
+const refreshFallbackTimeout = 2 * time.Minute
+
+// refreshViaDaemon asks a running daemon to refresh folder. reached is
+// false when there is no daemon to ask.
+func refreshViaDaemon(folder string) (h jsonl.HealthEntry, reached bool, err error) {
+	base, ok := daemon.New(daemon.Config{BearingDir: BearingDir()}).URL()
+	if !ok {
+		return h, false, nil
+	}
+
+	// A refresh makes several git and gh calls, each allowed daemon.timeout
+	timeout := 4 * time.Duration(settings().Daemon.Timeout) * time.Second
+	if timeout == 0 {
+		timeout = refreshFallbackTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Post(base+"/api/worktrees/"+url.PathEscape(folder)+"/refresh", "application/json", nil)
+	if err != nil {
+		return h, false, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return h, true, fmt.Errorf("daemon refused refresh: %s", strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		return h, true, fmt.Errorf("failed to read daemon response: %w", err)
+	}
+	return h, true, nil
+}
+
+// refreshLocally checks folder in this process and merges the result into
+// health.jsonl
+func refreshLocally(folder string) (jsonl.HealthEntry, error) {
+	store := openStore()
+	e, err := store.FindLocal(folder)
+	if err != nil {
+		return jsonl.HealthEntry{}, fmt.Errorf("failed to read local.jsonl: %w", err)
+	}
+	if e == nil {
+		return jsonl.HealthEntry{}, fmt.Errorf("unknown worktree: %s", folder)
+	}
+
+	h := daemon.CheckWorktree(context.Background(), WorkspaceDir(), *e, daemon.LoadCheckOptions(store, 0))
+	err = store.UpdateHealth(func(existing []jsonl.HealthEntry) ([]jsonl.HealthEntry, error) {
+		return daemon.MergeHealth(existing, []jsonl.HealthEntry{h}), nil
+	})
+	if err != nil {
+		return h, fmt.Errorf("failed to write health.jsonl: %w", err)
+	}
+	return h, nil
+}
+
+// formatHealth summarizes a health entry on one line
+func formatHealth(h jsonl.HealthEntry) string {
+	parts := []string{"clean"}
+	if h.Dirty {
+		parts[0] = "dirty"
+	}
+	if h.Unpushed > 0 {
+		parts = append(parts, fmt.Sprintf("%d unpushed", h.Unpushed))
+	}
+	if h.Merged {
+		parts = append(parts, "merged into "+h.BasedOn)
+	}
+	if h.PRState != nil {
+		parts = append(parts, "PR "+*h.PRState)
+	}
+	line := fmt.Sprintf("%s: %s (%v)", h.Folder, strings.Join(parts, ", "), time.Duration(h.DurationMs)*time.Millisecond)
+	if h.Error != "" {
+		line += "\nerror: " + h.Error
+	}
+	return line
+}
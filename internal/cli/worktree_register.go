@@ -24,18 +24,16 @@ func init() {
 func runWorktreeRegister(cmd *cobra.Command, args []string) error {
 	folder := args[0]
 	folderPath := filepath.Join(WorkspaceDir(), folder)
-	store := jsonl.NewStore(WorkspaceDir())
+	store := openStore()
 
 	// Check if already registered
-	entries, err := store.ReadLocal()
+	existing, err := store.FindLocal(folder)
 	if err != nil {
 		return fmt.Errorf("failed to read local.jsonl: %w", err)
 	}
-	for _, e := range entries {
-		if e.Folder == folder {
-			fmt.Printf("Already registered: %s\n", folder)
-			return nil
-		}
+	if existing != nil {
+		fmt.Printf("Already registered: %s\n", folder)
+		return nil
 	}
 
 	repo := git.NewRepo(folderPath)
@@ -46,8 +44,8 @@ func runWorktreeRegister(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get branch: %w", err)
 	}
 
-	// Determine if this is a base folder (branch is main/master)
-	isBase := branch == "main" || branch == "master"
+	// Determine if this is a base folder (on the repo's default branch)
+	isBase := isBaseBranch(folder, branch)
 
 	// Infer repo name (folder name without branch suffix for worktrees)
 	repoName := folder
@@ -67,9 +65,24 @@ func runWorktreeRegister(cmd *cobra.Command, args []string) error {
 		Base:   isBase,
 	}
 
-	if err := store.AppendLocal(entry); err != nil {
+	// Re-check under the lock in case another process registered it meanwhile
+	registered := false
+	err = store.UpdateLocal(func(locals []jsonl.LocalEntry) ([]jsonl.LocalEntry, error) {
+		for _, e := range locals {
+			if e.Folder == folder {
+				registered = true
+				return locals, nil
+			}
+		}
+		return append(locals, entry), nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update local.jsonl: %w", err)
 	}
+	if registered {
+		fmt.Printf("Already registered: %s\n", folder)
+		return nil
+	}
 
 	baseStr := ""
 	if isBase {
@@ -1,16 +1,17 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
 	"text/tabwriter"
 	"time"
 
-	"github.com/joshribakoff/bearing/internal/gh"
-	"github.com/joshribakoff/bearing/internal/git"
+	"github.com/joshribakoff/bearing/internal/daemon"
 	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -34,27 +35,160 @@ func init() {
 }
 
 type worktreeStatus struct {
-	Folder    string    `json:"folder"`
-	Repo      string    `json:"repo"`
-	Branch    string    `json:"branch"`
-	Base      bool      `json:"base"`
-	Dirty     bool      `json:"dirty"`
-	Unpushed  int       `json:"unpushed"`
-	PRState   *string   `json:"prState,omitempty"`
-	PRTitle   *string   `json:"prTitle,omitempty"`
-	LastCheck time.Time `json:"lastCheck,omitempty"`
+	Folder        string    `json:"folder"`
+	Repo          string    `json:"repo"`
+	Branch        string    `json:"branch"`
+	Base          bool      `json:"base"`
+	DefaultBranch string    `json:"defaultBranch,omitempty"` // base folders only
+	Dirty         bool      `json:"dirty"`
+	Unpushed      int       `json:"unpushed"`
+	PRState       *string   `json:"prState,omitempty"`
+	PRTitle       *string   `json:"prTitle,omitempty"`
+	LastCheck     time.Time `json:"lastCheck,omitempty"`
+	Error         string    `json:"error,omitempty"` // from the last check
+	jsonl.GitStatus
+	Session      *jsonl.SessionEntry `json:"session,omitempty"`      // latest agent session in the folder
+	SessionState string              `json:"sessionState,omitempty"` // active, idle or stopped
+	Unattended   bool                `json:"unattended,omitempty"`   // dirty with no live session
 }
 
 func runWorktreeStatus(cmd *cobra.Command, args []string) error {
-	store := jsonl.NewStore(WorkspaceDir())
-	entries, err := store.ReadLocal()
+	statuses, err := collectWorktreeStatus(statusRefresh, statusCached)
 	if err != nil {
 		return err
 	}
 
+	if statusJSON {
+		return json.NewEncoder(os.Stdout).Encode(statuses)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FOLDER\tBRANCH\tCHANGES\tUPSTREAM\tBASE\tPR\tSESSION\tSTATE")
+	for _, s := range statuses {
+		pr := "-"
+		if s.PRState != nil {
+			pr = *s.PRState
+		}
+		branch := s.Branch
+		if s.DefaultBranch != "" && s.Branch != s.DefaultBranch {
+			branch += fmt.Sprintf(" (default: %s)", s.DefaultBranch)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Folder, branch, formatChanges(s), formatUpstream(s), formatBase(s.GitStatus), pr, formatSession(s), formatState(s))
+	}
+	return w.Flush()
+}
+
+// formatChanges summarizes changed paths, e.g. "+2 ~1 ?3" for 2 staged,
+// 1 modified and 3 untracked
+func formatChanges(s worktreeStatus) string {
+	var parts []string
+	for _, c := range []struct {
+		n    int
+		sign string
+	}{{s.Staged, "+"}, {s.Modified, "~"}, {s.Untracked, "?"}, {s.Conflicted, "!"}} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%s%d", c.sign, c.n))
+		}
+	}
+	if len(parts) == 0 && s.Dirty {
+		// Cached before changes were counted
+		return "yes"
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatUpstream shows commits ahead and behind the upstream
+func formatUpstream(s worktreeStatus) string {
+	if s.Upstream == "" {
+		if s.Unpushed > 0 {
+			return fmt.Sprintf("unpushed %d", s.Unpushed)
+		}
+		return "-"
+	}
+	return aheadBehind(s.Ahead, s.Behind)
+}
+
+// formatBase shows commits ahead and behind the base branch
+func formatBase(g jsonl.GitStatus) string {
+	if g.BasedOn == "" {
+		return "-"
+	}
+	return g.BasedOn + " " + aheadBehind(g.AheadBase, g.BehindBase)
+}
+
+func aheadBehind(ahead, behind int) string {
+	switch {
+	case ahead == 0 && behind == 0:
+		return "even"
+	case behind == 0:
+		return fmt.Sprintf("↑%d", ahead)
+	case ahead == 0:
+		return fmt.Sprintf("↓%d", behind)
+	}
+	return fmt.Sprintf("↑%d ↓%d", ahead, behind)
+}
+
+// formatSession shows the latest session's state and how long ago it was
+// last active, e.g. "active 2m"
+func formatSession(s worktreeStatus) string {
+	if s.Session == nil {
+		return "-"
+	}
+	return s.SessionState + " " + shortDuration(time.Since(s.Session.LastActivity))
+}
+
+// shortDuration rounds d to its largest unit, e.g. 3h
+func shortDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+}
+
+// formatState lists anything unusual: an operation in progress, a
+// detached HEAD, a merged branch, stashes, changes left without a live
+// session
+func formatState(s worktreeStatus) string {
+	g := s.GitStatus
+	var parts []string
+	if g.Operation != "" {
+		parts = append(parts, g.Operation+" in progress")
+	}
+	if g.Detached {
+		parts = append(parts, "detached")
+	}
+	if g.Merged {
+		parts = append(parts, "merged into "+g.BasedOn)
+	}
+	if g.Stashes > 0 {
+		parts = append(parts, fmt.Sprintf("%d stashed", g.Stashes))
+	}
+	if s.Unattended {
+		parts = append(parts, "unattended")
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// collectWorktreeStatus combines local.jsonl with cached or freshly queried
+// health data. refresh ignores the cache; cached skips live queries.
+func collectWorktreeStatus(refresh, cached bool) ([]worktreeStatus, error) {
+	store := openStore()
+	entries, err := store.ReadLocal()
+	if err != nil {
+		return nil, err
+	}
+
 	// Try to load cached health data
 	healthMap := make(map[string]jsonl.HealthEntry)
-	if !statusRefresh {
+	if !refresh {
 		health, _ := store.ReadHealth()
 		for _, h := range health {
 			healthMap[h.Folder] = h
@@ -62,7 +196,8 @@ func runWorktreeStatus(cmd *cobra.Command, args []string) error {
 	}
 
 	var statuses []worktreeStatus
-	var updatedHealth []jsonl.HealthEntry
+	var stale []jsonl.LocalEntry // entries needing live queries
+	var staleIndex []int         // their positions in statuses
 
 	for _, e := range entries {
 		s := worktreeStatus{
@@ -71,76 +206,62 @@ func runWorktreeStatus(cmd *cobra.Command, args []string) error {
 			Branch: e.Branch,
 			Base:   e.Base,
 		}
+		// Detection may ask the remote, so --cached skips it
+		if e.Base && !cached {
+			s.DefaultBranch = DefaultBranch(e.Repo)
+		}
 
 		// Use cached data if available and not forcing refresh
-		if cached, ok := healthMap[e.Folder]; ok && !statusRefresh {
-			s.Dirty = cached.Dirty
-			s.Unpushed = cached.Unpushed
-			s.PRState = cached.PRState
-			s.PRTitle = cached.PRTitle
-			s.LastCheck = cached.LastCheck
+		if h, ok := healthMap[e.Folder]; ok && !refresh {
+			s.applyHealth(h)
 			statuses = append(statuses, s)
 			continue
 		}
 
 		// Skip live queries if --cached flag is set
-		if statusCached {
-			statuses = append(statuses, s)
-			continue
-		}
-
-		{
-			// Fetch fresh data
-			folderPath := filepath.Join(WorkspaceDir(), e.Folder)
-			repo := git.NewRepo(folderPath)
-
-			s.Dirty, _ = repo.IsDirty()
-			s.Unpushed, _ = repo.UnpushedCount(e.Branch)
-
-			if !e.Base {
-				ghClient := gh.NewClient(folderPath)
-				if pr, _ := ghClient.GetPR(e.Branch); pr != nil {
-					s.PRState = &pr.State
-					s.PRTitle = &pr.Title
-				}
-			}
-			s.LastCheck = time.Now()
-
-			// Update health cache
-			updatedHealth = append(updatedHealth, jsonl.HealthEntry{
-				Folder:    e.Folder,
-				Dirty:     s.Dirty,
-				Unpushed:  s.Unpushed,
-				PRState:   s.PRState,
-				PRTitle:   s.PRTitle,
-				LastCheck: s.LastCheck,
-			})
+		if !cached {
+			stale = append(stale, e)
+			staleIndex = append(staleIndex, len(statuses))
 		}
-
 		statuses = append(statuses, s)
 	}
 
-	// Write updated health data
-	if len(updatedHealth) > 0 {
-		store.WriteHealth(updatedHealth)
-	}
-
-	if statusJSON {
-		return json.NewEncoder(os.Stdout).Encode(statuses)
+	if len(stale) > 0 {
+		// Fetch fresh data and merge it into the health cache
+		opts := daemon.LoadCheckOptions(store, 0)
+		health := daemon.CheckWorktrees(context.Background(), WorkspaceDir(), stale, settings().Daemon.Workers, opts)
+		for i, h := range health {
+			statuses[staleIndex[i]].applyHealth(h)
+		}
+		err := store.UpdateHealth(func(existing []jsonl.HealthEntry) ([]jsonl.HealthEntry, error) {
+			return daemon.MergeHealth(existing, health), nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to update health.jsonl: %v\n", err)
+		}
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "FOLDER\tBRANCH\tDIRTY\tUNPUSHED\tPR")
-	for _, s := range statuses {
-		dirty := ""
-		if s.Dirty {
-			dirty = "yes"
+	sessions, _ := store.ReadSessions()
+	latest := session.ByFolder(sessions)
+	now := time.Now()
+	for i := range statuses {
+		s := &statuses[i]
+		if sess, ok := latest[s.Folder]; ok {
+			s.Session = &sess
+			s.SessionState = session.State(sess, now)
 		}
-		pr := "-"
-		if s.PRState != nil {
-			pr = *s.PRState
-		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", s.Folder, s.Branch, dirty, s.Unpushed, pr)
+		s.Unattended = session.Unattended(s.Dirty, s.Session, now)
 	}
-	return w.Flush()
+
+	return statuses, nil
+}
+
+func (s *worktreeStatus) applyHealth(h jsonl.HealthEntry) {
+	s.Dirty = h.Dirty
+	s.Unpushed = h.Unpushed
+	s.PRState = h.PRState
+	s.PRTitle = h.PRTitle
+	s.LastCheck = h.LastCheck
+	s.Error = h.Error
+	s.GitStatus = h.GitStatus
 }
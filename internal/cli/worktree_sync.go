@@ -22,7 +22,7 @@ func init() {
 }
 
 func runWorktreeSync(cmd *cobra.Command, args []string) error {
-	store := jsonl.NewStore(WorkspaceDir())
+	store := openStore()
 
 	// Find all git repos in workspace
 	entries, err := os.ReadDir(WorkspaceDir())
@@ -52,8 +52,8 @@ func runWorktreeSync(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		// Determine if base folder
-		isBase := branch == "main" || branch == "master"
+		// Determine if base folder; base folders are named after their repo
+		isBase := isBaseBranch(folder, branch)
 
 		// Infer repo name
 		repoName := folder
@@ -0,0 +1,435 @@
+// Package config loads bearing settings from layered YAML files.
+//
+// Settings are resolved from, lowest precedence first: built-in defaults,
+// the user file ~/.bearing/config.yaml, and the workspace file .bearing.yaml.
+// Each layer may override settings for a single project under
+// projects.<name>.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// File names of the config layers
+const (
+	WorkspaceFile = ".bearing.yaml"
+	UserFile      = "config.yaml"
+)
+
+// Config holds resolved settings
+type Config struct {
+	DefaultBranch string                   `yaml:"defaultBranch"` // start point for new worktrees
+	BaseBranches  []string                 `yaml:"baseBranches"`  // branches whose folders are base folders
+	GitHubOwner   string                   `yaml:"githubOwner"`   // owner for repos missing from projects.jsonl
+	PlansDir      string                   `yaml:"plansDir"`      // where plan create writes plans
+	Store         string                   `yaml:"store"`         // jsonl or sqlite
+	Daemon        DaemonConfig             `yaml:"daemon"`
+	Git           GitConfig                `yaml:"git"`
+	Guard         GuardConfig              `yaml:"guard"`
+	Bootstrap     BootstrapConfig          `yaml:"bootstrap"` // for projects without their own
+	Allocations   AllocationsConfig        `yaml:"allocations"`
+	Cleanup       CleanupConfig            `yaml:"cleanup"`
+	GC            GCConfig                 `yaml:"gc"`
+	Projects      map[string]ProjectConfig `yaml:"projects,omitempty"`
+
+	layers []layer
+}
+
+// DaemonConfig holds settings for bearing daemon
+type DaemonConfig struct {
+	Port     int `yaml:"port"`     // preferred HTTP port
+	Interval int `yaml:"interval"` // seconds between health checks
+	Workers  int `yaml:"workers"`  // worktrees checked concurrently
+	Timeout  int `yaml:"timeout"`  // seconds allowed per git or gh call
+}
+
+// GitConfig holds settings for git queries
+type GitConfig struct {
+	Backend string `yaml:"backend"` // exec or native (go-git, read-only queries)
+}
+
+// GuardConfig sets how the pre-tool-use hook treats risky actions in
+// base folders. Each rule is allow, ask or deny.
+type GuardConfig struct {
+	BranchSwitch string `yaml:"branchSwitch"` // git checkout or switch
+	ResetHard    string `yaml:"resetHard"`    // git reset --hard
+	Commit       string `yaml:"commit"`       // git commit, merge, cherry-pick, revert or am
+	Write        string `yaml:"write"`        // file edits by the agent
+	Audit        bool   `yaml:"audit"`        // record decisions in audit.jsonl
+}
+
+// BootstrapConfig prepares new worktrees. Paths are relative to the repo
+// root and may be globs.
+type BootstrapConfig struct {
+	Copy    []string `yaml:"copy,omitempty"`    // copied from the base folder, e.g. .env
+	Symlink []string `yaml:"symlink,omitempty"` // linked to the base folder, e.g. node_modules
+	Run     []string `yaml:"run,omitempty"`     // shell commands run in the worktree
+	Timeout int      `yaml:"timeout,omitempty"` // seconds allowed for all commands
+}
+
+// AllocationsConfig sets the resources handed out to each worktree
+type AllocationsConfig struct {
+	Enabled          bool   `yaml:"enabled"`          // allocate on worktree new
+	PortBase         int    `yaml:"portBase"`         // first port handed out
+	PortsPerWorktree int    `yaml:"portsPerWorktree"` // size of each worktree's port range
+	CacheDir         string `yaml:"cacheDir"`         // parent of per-worktree cache dirs; empty for the user cache dir
+}
+
+// CleanupConfig sets how worktree cleanup treats branches it deletes
+type CleanupConfig struct {
+	Archive string `yaml:"archive"` // none, tag or bundle
+}
+
+// GCConfig sets which worktrees worktree gc removes and whether the daemon
+// runs it
+type GCConfig struct {
+	IdleDays int  `yaml:"idleDays"` // remove worktrees idle this long; 0 never
+	Auto     bool `yaml:"auto"`     // let the daemon run gc
+	Interval int  `yaml:"interval"` // hours between the daemon's runs
+}
+
+// ProjectConfig overrides settings for one project. Empty fields inherit.
+type ProjectConfig struct {
+	DefaultBranch string           `yaml:"defaultBranch,omitempty"`
+	BaseBranches  []string         `yaml:"baseBranches,omitempty"`
+	GitHubOwner   string           `yaml:"githubOwner,omitempty"`
+	Bootstrap     *BootstrapConfig `yaml:"bootstrap,omitempty"` // replaces the top-level bootstrap
+}
+
+// Default returns the built-in settings
+func Default() *Config {
+	return &Config{
+		DefaultBranch: "main",
+		BaseBranches:  []string{"main", "master"},
+		PlansDir:      "~/Projects/plans",
+		Store:         "jsonl",
+		Daemon: DaemonConfig{
+			Port:     8374,
+			Interval: 300,
+			Workers:  4,
+			Timeout:  30,
+		},
+		Git: GitConfig{Backend: "exec"},
+		Guard: GuardConfig{
+			BranchSwitch: "deny",
+			ResetHard:    "deny",
+			Commit:       "deny",
+			Write:        "deny",
+			Audit:        true,
+		},
+		Bootstrap: BootstrapConfig{Timeout: 600},
+		Allocations: AllocationsConfig{
+			Enabled:          true,
+			PortBase:         3100,
+			PortsPerWorktree: 10,
+		},
+		Cleanup: CleanupConfig{Archive: "none"},
+		GC:      GCConfig{IdleDays: 30, Interval: 24},
+	}
+}
+
+// layer is one source of settings
+type layer struct {
+	name string // default, user or workspace
+	path string
+	data []byte // the file's contents, for locating errors
+	tree map[string]any
+}
+
+// defaultLayer holds the built-in defaults
+func defaultLayer() (layer, error) {
+	tree, err := toTree(Default())
+	return layer{name: "default", tree: tree}, err
+}
+
+// Load resolves settings for workspaceDir, reading the user file from
+// userDir (normally ~/.bearing). Missing files are skipped. Errors name
+// the file and line at fault.
+func Load(workspaceDir, userDir string) (*Config, error) {
+	defaults, err := defaultLayer()
+	if err != nil {
+		return nil, err
+	}
+	layers := []layer{defaults}
+
+	for _, l := range []layer{
+		{name: "user", path: filepath.Join(userDir, UserFile)},
+		{name: "workspace", path: filepath.Join(workspaceDir, WorkspaceFile)},
+	} {
+		data, err := os.ReadFile(l.path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if l.tree, err = parseLayer(l.path, data); err != nil {
+			return nil, err
+		}
+		l.data = data
+		layers = append(layers, l)
+	}
+	return resolve(layers)
+}
+
+// parseLayer decodes one config file into a generic tree, rejecting
+// unknown keys and values of the wrong type with the file's own line
+// numbers
+func parseLayer(path string, data []byte) (map[string]any, error) {
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var c Config
+	if err := dec.Decode(&c); err != nil && err != io.EOF {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+		// Each error reads "line N: ..."; make that "path:N: ..."
+		msgs := make([]string, len(typeErr.Errors))
+		for i, e := range typeErr.Errors {
+			msgs[i] = path + ":" + strings.TrimPrefix(e, "line ")
+		}
+		return nil, fmt.Errorf("invalid config: %s", strings.Join(msgs, "; "))
+	}
+	return tree, nil
+}
+
+// settingError is a setting with an invalid value
+type settingError struct {
+	key string
+	msg string
+	loc string // path:line of the setting, if it comes from a file
+}
+
+func (e *settingError) Error() string {
+	if e.loc != "" {
+		return fmt.Sprintf("invalid config: %s: %s %s", e.loc, e.key, e.msg)
+	}
+	return fmt.Sprintf("invalid config: %s %s", e.key, e.msg)
+}
+
+func invalid(key, format string, args ...any) error {
+	return &settingError{key: key, msg: fmt.Sprintf(format, args...)}
+}
+
+// resolve merges layers in order and decodes the result. An invalid value
+// is traced to the file, and line, that set it.
+func resolve(layers []layer) (*Config, error) {
+	c, err := decodeLayers(layers)
+	var se *settingError
+	if errors.As(err, &se) {
+		se.loc = locate(layers, se.key)
+	}
+	return c, err
+}
+
+// decodeLayers merges layers in order, decodes and validates the result
+func decodeLayers(layers []layer) (*Config, error) {
+	merged := map[string]any{}
+	for _, l := range layers {
+		merged = mergeTrees(merged, l.tree)
+	}
+
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	c := &Config{}
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if c.Store != "jsonl" && c.Store != "sqlite" {
+		return nil, invalid("store", "must be jsonl or sqlite, got %q", c.Store)
+	}
+	if c.Daemon.Port < 1 || c.Daemon.Port > 65535 {
+		return nil, invalid("daemon.port", "must be between 1 and 65535, got %d", c.Daemon.Port)
+	}
+	if c.Daemon.Interval < 1 {
+		return nil, invalid("daemon.interval", "must be at least 1, got %d", c.Daemon.Interval)
+	}
+	if c.Daemon.Workers < 1 {
+		return nil, invalid("daemon.workers", "must be at least 1, got %d", c.Daemon.Workers)
+	}
+	if c.Daemon.Timeout < 0 {
+		return nil, invalid("daemon.timeout", "must not be negative, got %d", c.Daemon.Timeout)
+	}
+	if c.Git.Backend != "exec" && c.Git.Backend != "native" {
+		return nil, invalid("git.backend", "must be exec or native, got %q", c.Git.Backend)
+	}
+	for _, rule := range []struct{ key, action string }{
+		{"guard.branchSwitch", c.Guard.BranchSwitch},
+		{"guard.resetHard", c.Guard.ResetHard},
+		{"guard.commit", c.Guard.Commit},
+		{"guard.write", c.Guard.Write},
+	} {
+		if rule.action != "allow" && rule.action != "ask" && rule.action != "deny" {
+			return nil, invalid(rule.key, "must be allow, ask or deny, got %q", rule.action)
+		}
+	}
+	if c.Bootstrap.Timeout < 0 {
+		return nil, invalid("bootstrap.timeout", "must not be negative, got %d", c.Bootstrap.Timeout)
+	}
+	if c.Allocations.PortBase < 1024 || c.Allocations.PortBase > 65535 {
+		return nil, invalid("allocations.portBase", "must be between 1024 and 65535, got %d", c.Allocations.PortBase)
+	}
+	if c.Allocations.PortsPerWorktree < 1 {
+		return nil, invalid("allocations.portsPerWorktree", "must be at least 1, got %d", c.Allocations.PortsPerWorktree)
+	}
+	if c.Cleanup.Archive != "none" && c.Cleanup.Archive != "tag" && c.Cleanup.Archive != "bundle" {
+		return nil, invalid("cleanup.archive", "must be none, tag or bundle, got %q", c.Cleanup.Archive)
+	}
+	if c.GC.IdleDays < 0 {
+		return nil, invalid("gc.idleDays", "must not be negative, got %d", c.GC.IdleDays)
+	}
+	if c.GC.Interval < 1 {
+		return nil, invalid("gc.interval", "must be at least 1, got %d", c.GC.Interval)
+	}
+	c.layers = layers
+	return c, nil
+}
+
+// IsBaseBranch reports whether a folder of project on branch is a base folder
+func (c *Config) IsBaseBranch(project, branch string) bool {
+	branches := c.BaseBranches
+	if p, ok := c.Projects[project]; ok && len(p.BaseBranches) > 0 {
+		branches = p.BaseBranches
+	}
+	for _, b := range branches {
+		if b == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// GitHubOwnerFor returns the GitHub owner assumed for project, or ""
+func (c *Config) GitHubOwnerFor(project string) string {
+	if p, ok := c.Projects[project]; ok && p.GitHubOwner != "" {
+		return p.GitHubOwner
+	}
+	return c.GitHubOwner
+}
+
+// BootstrapFor returns how to prepare new worktrees of project. A project's
+// own bootstrap replaces the top-level one, except for an unset timeout.
+func (c *Config) BootstrapFor(project string) BootstrapConfig {
+	b := c.Bootstrap
+	if p, ok := c.Projects[project]; ok && p.Bootstrap != nil {
+		b = *p.Bootstrap
+		if b.Timeout == 0 {
+			b.Timeout = c.Bootstrap.Timeout
+		}
+	}
+	return b
+}
+
+// PlansPath returns PlansDir with a leading ~ expanded
+func (c *Config) PlansPath() (string, error) {
+	return expandHome(c.PlansDir)
+}
+
+// AllocationCacheDir returns Allocations.CacheDir with a leading ~
+// expanded, or bearing's directory in the user cache dir if it is unset
+func (c *Config) AllocationCacheDir() (string, error) {
+	if c.Allocations.CacheDir != "" {
+		return expandHome(c.Allocations.CacheDir)
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	return filepath.Join(cache, "bearing"), nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// locate returns path:line of key in the highest layer that sets it, or
+// "" if only the defaults do
+func locate(layers []layer, key string) string {
+	for i := len(layers) - 1; i >= 0; i-- {
+		l := layers[i]
+		if l.path == "" {
+			continue
+		}
+		var doc yaml.Node
+		if yaml.Unmarshal(l.data, &doc) != nil || len(doc.Content) == 0 {
+			continue
+		}
+		if line := keyLine(doc.Content[0], strings.Split(key, ".")); line > 0 {
+			return fmt.Sprintf("%s:%d", l.path, line)
+		}
+	}
+	return ""
+}
+
+// keyLine returns the line of the dotted key within mapping m, or 0
+func keyLine(m *yaml.Node, keys []string) int {
+	if m.Kind != yaml.MappingNode {
+		return 0
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			return m.Content[i].Line
+		}
+		return keyLine(m.Content[i+1], keys[1:])
+	}
+	return 0
+}
+
+func toTree(v any) (map[string]any, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	err = yaml.Unmarshal(data, &tree)
+	return tree, err
+}
+
+// mergeTrees returns base overlaid with over. Nested maps merge key by
+// key; any other value, including lists, replaces the base value.
+func mergeTrees(base, over map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		if v == nil {
+			continue // an empty key inherits
+		}
+		if bm, ok := out[k].(map[string]any); ok {
+			if om, ok := v.(map[string]any); ok {
+				out[k] = mergeTrees(bm, om)
+				continue
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
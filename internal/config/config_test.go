@@ -0,0 +1,202 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, err := Load(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DefaultBranch != "main" || c.Daemon.Port != 8374 || c.Store != "jsonl" {
+		t.Errorf("unexpected defaults: %+v", c)
+	}
+	if !c.IsBaseBranch("any", "master") || c.IsBaseBranch("any", "feature") {
+		t.Error("expected main and master to be base branches by default")
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	ws, user := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(user, UserFile), `
+githubOwner: alice
+daemon:
+  port: 9000
+  interval: 60
+`)
+	writeFile(t, filepath.Join(ws, WorkspaceFile), `
+daemon:
+  port: 9100
+projects:
+  legacy:
+    defaultBranch: master
+    baseBranches: [master]
+    githubOwner: acme
+`)
+
+	c, err := Load(ws, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Daemon.Port != 9100 || c.Daemon.Interval != 60 {
+		t.Errorf("expected workspace port over user interval, got %+v", c.Daemon)
+	}
+	if c.GitHubOwnerFor("app") != "alice" || c.GitHubOwnerFor("legacy") != "acme" {
+		t.Errorf("unexpected owners: %q, %q", c.GitHubOwnerFor("app"), c.GitHubOwnerFor("legacy"))
+	}
+	if c.DefaultBranch != "main" || c.Projects["legacy"].DefaultBranch != "master" {
+		t.Error("expected per-project default branch override")
+	}
+	if c.IsBaseBranch("legacy", "main") {
+		t.Error("expected project baseBranches to replace the global list")
+	}
+
+	s, ok := c.Get("daemon.port")
+	if !ok || s.Source != "workspace" {
+		t.Errorf("unexpected setting: %+v", s)
+	}
+	if s, _ := c.Get("daemon.interval"); s.Source != "user" {
+		t.Errorf("expected interval from user layer, got %+v", s)
+	}
+}
+
+func TestBootstrapFor(t *testing.T) {
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, WorkspaceFile), `
+bootstrap:
+  copy: [.env]
+  timeout: 120
+projects:
+  web:
+    bootstrap:
+      symlink: [node_modules]
+      run: [npm install]
+`)
+	c, err := Load(ws, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := c.BootstrapFor("api"); len(b.Copy) != 1 || b.Timeout != 120 {
+		t.Errorf("expected top-level bootstrap, got %+v", b)
+	}
+	b := c.BootstrapFor("web")
+	if len(b.Copy) != 0 || len(b.Symlink) != 1 || len(b.Run) != 1 || b.Timeout != 120 {
+		t.Errorf("expected project bootstrap inheriting the timeout, got %+v", b)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, WorkspaceFile), "defaultBrnach: main\n")
+	if _, err := Load(ws, t.TempDir()); err == nil {
+		t.Error("expected error for misspelled key")
+	}
+}
+
+func TestLoadRejectsUnknownGuardAction(t *testing.T) {
+	ws := t.TempDir()
+	writeFile(t, filepath.Join(ws, WorkspaceFile), "guard:\n  commit: block\n")
+	if _, err := Load(ws, t.TempDir()); err == nil || !strings.Contains(err.Error(), "guard.commit") {
+		t.Errorf("expected guard.commit error, got %v", err)
+	}
+}
+
+func TestLoadRejectsBadDaemonSettings(t *testing.T) {
+	for _, c := range []struct{ yaml, key string }{
+		{"daemon:\n  port: 70000\n", "daemon.port"},
+		{"daemon:\n  port: 0\n", "daemon.port"},
+		{"daemon:\n  interval: 0\n", "daemon.interval"},
+	} {
+		ws := t.TempDir()
+		writeFile(t, filepath.Join(ws, WorkspaceFile), c.yaml)
+		if _, err := Load(ws, t.TempDir()); err == nil || !strings.Contains(err.Error(), c.key) {
+			t.Errorf("%q: expected %s error, got %v", c.yaml, c.key, err)
+		}
+	}
+}
+
+func TestLoadErrorsNameFileAndLine(t *testing.T) {
+	ws, user := t.TempDir(), t.TempDir()
+	userPath := filepath.Join(user, UserFile)
+	writeFile(t, userPath, "githubOwner: alice\ndefaultBrnach: main\n")
+	if _, err := Load(ws, user); err == nil || !strings.Contains(err.Error(), userPath+":2: field defaultBrnach not found") {
+		t.Errorf("expected unknown key located in the user file, got %v", err)
+	}
+
+	// A bad value is found in the layer that set it, not the merged tree
+	writeFile(t, userPath, "githubOwner: alice\n")
+	wsPath := filepath.Join(ws, WorkspaceFile)
+	writeFile(t, wsPath, "daemon:\n  workers: 0\n")
+	if _, err := Load(ws, user); err == nil || !strings.Contains(err.Error(), wsPath+":2: daemon.workers must be at least 1") {
+		t.Errorf("expected daemon.workers located in the workspace file, got %v", err)
+	}
+}
+
+func TestSetOnDefaults(t *testing.T) {
+	// A Config that fell back to defaults can still repair a broken file
+	path := filepath.Join(t.TempDir(), WorkspaceFile)
+	writeFile(t, path, "daemon:\n  workers: 0\n")
+	if err := Default().Set(path, "daemon.workers", "2"); err != nil {
+		t.Fatalf("Set = %v", err)
+	}
+	if err := Default().Set(path, "daemon.port", "-"); err == nil || !strings.Contains(err.Error(), path+":") {
+		t.Errorf("expected a located type error, got %v", err)
+	}
+	if len(Default().List()) == 0 {
+		t.Error("expected defaults listed")
+	}
+}
+
+func TestSet(t *testing.T) {
+	ws := t.TempDir()
+	path := filepath.Join(ws, WorkspaceFile)
+	writeFile(t, path, "# team settings\ndefaultBranch: main # trunk\n")
+
+	c, err := Load(ws, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(path, "daemon.port", "9001"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(path, "projects.legacy.baseBranches", "[master, trunk]"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# team settings") || !strings.Contains(string(data), "# trunk") {
+		t.Errorf("expected comments preserved, got:\n%s", data)
+	}
+
+	c, err = Load(ws, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Daemon.Port != 9001 || !c.IsBaseBranch("legacy", "trunk") {
+		t.Errorf("settings not applied: %+v", c)
+	}
+
+	// Invalid values leave the file untouched
+	before, _ := os.ReadFile(path)
+	if err := c.Set(path, "daemon.port", "abc"); err == nil {
+		t.Error("expected error for non-numeric port")
+	}
+	if err := c.Set(path, "store", "mysql"); err == nil {
+		t.Error("expected error for unknown store")
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Error("rejected set modified the file")
+	}
+}
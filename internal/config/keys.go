@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Setting is one resolved leaf value and the layer it came from
+type Setting struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source string `json:"source"` // default, user or workspace
+	Path   string `json:"path,omitempty"`
+}
+
+// allLayers returns the layers c was resolved from; for a Config not
+// loaded from files, just the defaults
+func (c *Config) allLayers() ([]layer, error) {
+	if len(c.layers) > 0 {
+		return c.layers, nil
+	}
+	defaults, err := defaultLayer()
+	return []layer{defaults}, err
+}
+
+// List returns every resolved setting sorted by key
+func (c *Config) List() []Setting {
+	layers, _ := c.allLayers()
+	byKey := map[string]Setting{}
+	for _, l := range layers {
+		flatten("", l.tree, func(key string, value any) {
+			byKey[key] = Setting{Key: key, Value: value, Source: l.name, Path: l.path}
+		})
+	}
+
+	settings := make([]Setting, 0, len(byKey))
+	for _, s := range byKey {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
+
+// Get returns the resolved setting for a dotted key such as daemon.port
+func (c *Config) Get(key string) (Setting, bool) {
+	var found Setting
+	ok := false
+	for _, s := range c.List() {
+		if s.Key == key {
+			return s, true
+		}
+		// A parent key such as "daemon" collects its children
+		if strings.HasPrefix(s.Key, key+".") {
+			if !ok {
+				found = Setting{Key: key, Value: map[string]any{}, Source: s.Source, Path: s.Path}
+				ok = true
+			}
+			found.Value.(map[string]any)[strings.TrimPrefix(s.Key, key+".")] = s.Value
+		}
+	}
+	return found, ok
+}
+
+func flatten(prefix string, tree map[string]any, fn func(key string, value any)) {
+	for k, v := range tree {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if m, ok := v.(map[string]any); ok && len(m) > 0 {
+			flatten(key, m, fn)
+			continue
+		}
+		if v != nil {
+			fn(key, v)
+		}
+	}
+}
+
+// Set writes key=value into the config file at path, creating it if
+// needed. value is parsed as YAML, so "8080" is a number and "[a, b]" a
+// list. The edit is validated against the other layers of c before
+// anything is written. Comments elsewhere in the file are preserved.
+func (c *Config) Set(path, key, value string) error {
+	parts := strings.Split(key, ".")
+	for _, p := range parts {
+		if p == "" {
+			return fmt.Errorf("invalid key %q", key)
+		}
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level is not a mapping", path)
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if len(parsed.Content) > 0 {
+		valueNode = parsed.Content[0]
+	}
+
+	setNode(doc.Content[0], parts, valueNode)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	out := buf.Bytes()
+
+	// Validate the edited file together with the other layers
+	tree, err := parseLayer(path, out)
+	if err != nil {
+		return fmt.Errorf("rejected %s=%s: %w", key, value, err)
+	}
+	layers, err := c.allLayers()
+	if err != nil {
+		return err
+	}
+	layers = append([]layer(nil), layers...)
+	replaced := false
+	for i := range layers {
+		if layers[i].path == path {
+			layers[i].tree, layers[i].data = tree, out
+			replaced = true
+		}
+	}
+	if !replaced {
+		layers = append(layers, layer{name: "workspace", path: path, data: out, tree: tree})
+	}
+	if _, err := resolve(layers); err != nil {
+		return fmt.Errorf("rejected %s=%s: %w", key, value, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// setNode sets the value at keys inside mapping m, creating mappings along
+// the way
+func setNode(m *yaml.Node, keys []string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			m.Content[i+1] = value
+			return
+		}
+		child := m.Content[i+1]
+		if child.Kind != yaml.MappingNode {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			m.Content[i+1] = child
+		}
+		setNode(child, keys[1:], value)
+		return
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Value: keys[0]}
+	if len(keys) == 1 {
+		m.Content = append(m.Content, keyNode, value)
+		return
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	m.Content = append(m.Content, keyNode, child)
+	setNode(child, keys[1:], value)
+}
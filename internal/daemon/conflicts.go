@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joshribakoff/bearing/internal/git"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+// Conflict describes two branches of a repo that touched the same files.
+// Only committed changes are compared.
+type Conflict struct {
+	Repo        string   `json:"repo"`
+	Folder      string   `json:"folder"`
+	Branch      string   `json:"branch"`
+	Other       string   `json:"other,omitempty"` // folder of the other branch; empty for the base
+	OtherBranch string   `json:"otherBranch"`
+	Base        bool     `json:"base,omitempty"`      // OtherBranch is the base branch
+	Overlap     []string `json:"overlap"`             // changed on both sides since they diverged
+	Conflicts   []string `json:"conflicts,omitempty"` // would not merge cleanly
+	Error       string   `json:"error,omitempty"`
+}
+
+// Likely reports whether merging the two branches is expected to conflict
+func (c Conflict) Likely() bool {
+	return len(c.Conflicts) > 0
+}
+
+// FindConflicts compares every pair of active branches in each repo, and
+// each branch against its base, returning the pairs that changed the same
+// files or fail to merge. Base folders and detached worktrees are skipped.
+func FindConflicts(ctx context.Context, workspaceDir string, entries []jsonl.LocalEntry, opts CheckOptions) []Conflict {
+	byRepo := make(map[string][]jsonl.LocalEntry)
+	dirs := make(map[string]string) // where to run git, by repo
+	for _, e := range entries {
+		if e.Base {
+			dirs[e.Repo] = filepath.Join(workspaceDir, e.Folder)
+			continue
+		}
+		if e.Branch == "" || e.Branch == "HEAD" {
+			continue
+		}
+		byRepo[e.Repo] = append(byRepo[e.Repo], e)
+	}
+
+	repos := make([]string, 0, len(byRepo))
+	for name := range byRepo {
+		repos = append(repos, name)
+	}
+	sort.Strings(repos)
+
+	conflicts := make([]Conflict, 0)
+	for _, name := range repos {
+		active := byRepo[name]
+		sort.Slice(active, func(i, j int) bool { return active[i].Folder < active[j].Folder })
+		dir, ok := dirs[name]
+		if !ok {
+			dir = filepath.Join(workspaceDir, active[0].Folder)
+		}
+		repo := git.NewRepo(dir).WithTimeout(ctx, opts.Timeout)
+
+		for i, a := range active {
+			if basedOn := opts.basedOn(name, a.Branch); basedOn != "" && basedOn != a.Branch {
+				ref := basedOn
+				if repo.RemoteBranchExists(basedOn) {
+					ref = "origin/" + basedOn
+				}
+				c := compareBranches(repo, a.Branch, ref)
+				c.OtherBranch, c.Base = basedOn, true
+				conflicts = appendConflict(conflicts, c, name, a)
+			}
+			for _, b := range active[i+1:] {
+				if a.Branch == b.Branch {
+					continue
+				}
+				c := compareBranches(repo, a.Branch, b.Branch)
+				c.Other, c.OtherBranch = b.Folder, b.Branch
+				conflicts = appendConflict(conflicts, c, name, a)
+			}
+		}
+	}
+	return conflicts
+}
+
+// appendConflict fills in the first side of c and keeps it if there is
+// anything to report
+func appendConflict(conflicts []Conflict, c Conflict, repo string, e jsonl.LocalEntry) []Conflict {
+	if len(c.Overlap) == 0 && len(c.Conflicts) == 0 && c.Error == "" {
+		return conflicts
+	}
+	c.Repo, c.Folder, c.Branch = repo, e.Folder, e.Branch
+	return append(conflicts, c)
+}
+
+// compareBranches finds the files both a and b changed since their merge
+// base, and the files merge-tree reports as conflicting
+func compareBranches(repo *git.Repo, a, b string) Conflict {
+	var c Conflict
+	var errs []string
+
+	ours, err := repo.ChangedFiles(b, a)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	theirs, err := repo.ChangedFiles(a, b)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	changed := make(map[string]bool, len(ours))
+	for _, f := range ours {
+		changed[f] = true
+	}
+	for _, f := range theirs {
+		if changed[f] {
+			c.Overlap = append(c.Overlap, f)
+		}
+	}
+
+	if c.Conflicts, err = repo.MergeConflicts(a, b); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		c.Error = strings.TrimSpace(strings.Join(errs, "\n"))
+	}
+	return c
+}
+
+// ConflictsWith returns, by folder, the other folders (or base branches)
+// each worktree is likely to conflict with
+func ConflictsWith(conflicts []Conflict) map[string][]string {
+	with := make(map[string][]string)
+	for _, c := range conflicts {
+		if !c.Likely() {
+			continue
+		}
+		if c.Base {
+			with[c.Folder] = append(with[c.Folder], c.OtherBranch)
+			continue
+		}
+		with[c.Folder] = append(with[c.Folder], c.Other)
+		with[c.Other] = append(with[c.Other], c.Folder)
+	}
+	for _, others := range with {
+		sort.Strings(others)
+	}
+	return with
+}
@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+// setupConflictWorkspace creates repo "proj" with worktrees on branches a,
+// b and c: a and b change shared.txt differently, main later makes a's
+// change too, and c only adds its own file
+func setupConflictWorkspace(t *testing.T) (string, []jsonl.LocalEntry) {
+	t.Helper()
+	workspace := t.TempDir()
+	base := filepath.Join(workspace, "proj")
+	initRepo(t, base)
+
+	commit := func(dir, file, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gitRun(t, dir, "add", file)
+		gitRun(t, dir, "commit", "-q", "-m", filepath.Base(dir)+": "+file)
+	}
+	commit(base, "shared.txt", "original\n")
+
+	for _, b := range []string{"a", "b", "c"} {
+		gitRun(t, base, "worktree", "add", "-q", "-b", b, filepath.Join(workspace, "proj-"+b))
+	}
+	commit(filepath.Join(workspace, "proj-a"), "shared.txt", "from a\n")
+	commit(filepath.Join(workspace, "proj-b"), "shared.txt", "from b\n")
+	commit(filepath.Join(workspace, "proj-c"), "c.txt", "c\n")
+	commit(base, "shared.txt", "from a\n")
+
+	return workspace, []jsonl.LocalEntry{
+		{Folder: "proj", Repo: "proj", Branch: "main", Base: true},
+		{Folder: "proj-a", Repo: "proj", Branch: "a"},
+		{Folder: "proj-b", Repo: "proj", Branch: "b"},
+		{Folder: "proj-c", Repo: "proj", Branch: "c"},
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	workspace, entries := setupConflictWorkspace(t)
+	opts := CheckOptions{DefaultBranch: map[string]string{"proj": "main"}}
+
+	conflicts := FindConflicts(context.Background(), workspace, entries, opts)
+
+	type pair struct{ folder, other string }
+	got := make(map[pair]Conflict)
+	for _, c := range conflicts {
+		if c.Error != "" {
+			t.Fatalf("unexpected error: %s", c.Error)
+		}
+		other := c.Other
+		if c.Base {
+			other = "base:" + c.OtherBranch
+		}
+		got[pair{c.Folder, other}] = c
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 overlapping pairs, got %+v", conflicts)
+	}
+
+	shared := []string{"shared.txt"}
+	if c := got[pair{"proj-a", "base:main"}]; !reflect.DeepEqual(c.Overlap, shared) || c.Likely() {
+		t.Errorf("a vs main: same change on both sides should overlap without conflict, got %+v", c)
+	}
+	if c := got[pair{"proj-a", "proj-b"}]; !reflect.DeepEqual(c.Conflicts, shared) {
+		t.Errorf("a vs b: expected shared.txt to conflict, got %+v", c)
+	}
+	if c := got[pair{"proj-b", "base:main"}]; !reflect.DeepEqual(c.Conflicts, shared) {
+		t.Errorf("b vs main: expected shared.txt to conflict, got %+v", c)
+	}
+
+	with := ConflictsWith(conflicts)
+	want := map[string][]string{
+		"proj-a": {"proj-b"},
+		"proj-b": {"main", "proj-a"},
+	}
+	if !reflect.DeepEqual(with, want) {
+		t.Errorf("ConflictsWith = %v, want %v", with, want)
+	}
+}
+
+func TestFindConflictsSkipsInactive(t *testing.T) {
+	workspace, entries := setupConflictWorkspace(t)
+	entries[2].Branch = "HEAD" // detached
+
+	conflicts := FindConflicts(context.Background(), workspace, entries, CheckOptions{})
+	if len(conflicts) != 0 {
+		t.Errorf("expected no conflicts without proj-b or a base branch, got %+v", conflicts)
+	}
+}
@@ -1,6 +1,8 @@
 package daemon
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net"
@@ -9,23 +11,35 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/joshribakoff/bearing/internal/gh"
 	"github.com/joshribakoff/bearing/internal/git"
 	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/lease"
+	"github.com/joshribakoff/bearing/internal/session"
 )
 
 // DefaultHTTPPort is the preferred port for the HTTP server
 const DefaultHTTPPort = 8374
 
+// DefaultWorkers is the number of worktrees checked concurrently
+const DefaultWorkers = 4
+
 // Config holds daemon configuration
 type Config struct {
 	WorkspaceDir string
 	BearingDir   string
 	Interval     time.Duration
-	StaticFS     fs.FS // Optional: embedded static files for web dashboard
+	HTTPPort     int           // Preferred HTTP port; defaults to DefaultHTTPPort
+	Workers      int           // Worktrees checked concurrently; defaults to DefaultWorkers
+	Timeout      time.Duration // Limit for each git or gh call; 0 means none
+	StaticFS     fs.FS         // Optional: embedded static files for web dashboard
+	Store        jsonl.Store   // Optional: defaults to the JSONL files in WorkspaceDir
+	GC           func() int    // Optional: cleans up finished worktrees, returning how many
+	GCInterval   time.Duration // Time between GC runs
 }
 
 // Daemon manages the health monitoring background process
@@ -33,21 +47,62 @@ type Daemon struct {
 	config     Config
 	stop       chan struct{}
 	httpServer *HTTPServer
+	watcher    *Watcher
+	queue      *refreshQueue
+
+	mu        sync.Mutex
+	worktrees map[string]jsonl.LocalEntry // by folder, from the last discovery
+	conflicts []Conflict                  // from the last full health check
+	lastGC    time.Time
 }
 
+// ErrUnknownWorktree is returned for a refresh of a folder that is neither
+// discovered nor in local.jsonl
+var ErrUnknownWorktree = errors.New("unknown worktree")
+
 // New creates a new daemon instance
 func New(config Config) *Daemon {
 	return &Daemon{
 		config: config,
 		stop:   make(chan struct{}),
+		queue:  newRefreshQueue(),
 	}
 }
 
+// store returns the configured store, defaulting to the JSONL files
+func (d *Daemon) store() jsonl.Store {
+	if d.config.Store != nil {
+		return d.config.Store
+	}
+	return jsonl.NewStore(d.config.WorkspaceDir)
+}
+
 // PIDFile returns the path to the PID file
 func (d *Daemon) PIDFile() string {
 	return filepath.Join(d.config.BearingDir, "bearing.pid")
 }
 
+// PortFile returns the path to the file holding the HTTP port
+func (d *Daemon) PortFile() string {
+	return filepath.Join(d.config.BearingDir, "http.port")
+}
+
+// URL returns the base URL of a running daemon's HTTP server
+func (d *Daemon) URL() (string, bool) {
+	if running, _ := d.IsRunning(); !running {
+		return "", false
+	}
+	data, err := os.ReadFile(d.PortFile())
+	if err != nil {
+		return "", false
+	}
+	port, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("http://localhost:%d", port), true
+}
+
 // LogFile returns the path to the log file
 func (d *Daemon) LogFile() string {
 	return filepath.Join(d.config.BearingDir, "daemon.log")
@@ -142,12 +197,18 @@ func (d *Daemon) run() error {
 	}
 
 	// Start HTTP server for web dashboard
-	store := jsonl.NewStore(d.config.WorkspaceDir)
+	store := d.store()
 	d.httpServer = NewHTTPServer(store, d.config.WorkspaceDir, d.config.StaticFS)
+	d.httpServer.refresh = d.RequestRefresh
+	d.httpServer.conflicts = d.Conflicts
 
 	go func() {
 		// Try preferred port first, fall back to any available port
-		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", DefaultHTTPPort))
+		preferred := d.config.HTTPPort
+		if preferred == 0 {
+			preferred = DefaultHTTPPort
+		}
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", preferred))
 		if err != nil {
 			listener, err = net.Listen("tcp", ":0")
 			if err != nil {
@@ -157,8 +218,7 @@ func (d *Daemon) run() error {
 		}
 
 		port := listener.Addr().(*net.TCPAddr).Port
-		portFile := filepath.Join(d.config.BearingDir, "http.port")
-		os.WriteFile(portFile, []byte(fmt.Sprintf("%d", port)), 0644)
+		os.WriteFile(d.PortFile(), []byte(fmt.Sprintf("%d", port)), 0644)
 
 		fmt.Printf("HTTP server listening on http://localhost:%d\n", port)
 		if err := http.Serve(listener, d.httpServer.Handler()); err != nil {
@@ -170,11 +230,30 @@ func (d *Daemon) run() error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
+	// Watch for commits, checkouts and state edits between ticks
+	var changes <-chan Change
+	if w, err := NewWatcher(d.config.WorkspaceDir, DefaultDebounce); err != nil {
+		fmt.Printf("File watching disabled: %v\n", err)
+	} else {
+		d.watcher = w
+		defer w.Close()
+		w.Start()
+		changes = w.Changes()
+	}
+
 	ticker := time.NewTicker(d.config.Interval)
 	defer ticker.Stop()
 
 	fmt.Printf("Daemon running (PID %d), checking every %v\n", os.Getpid(), d.config.Interval)
 
+	workers := d.config.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	for range workers {
+		go d.refreshWorker()
+	}
+
 	// Initial check
 	d.runHealthCheck()
 
@@ -182,6 +261,8 @@ func (d *Daemon) run() error {
 		select {
 		case <-ticker.C:
 			d.runHealthCheck()
+		case c := <-changes:
+			d.handleChange(c)
 		case sig := <-sigChan:
 			fmt.Printf("Received signal %v, stopping...\n", sig)
 			os.Remove(d.PIDFile())
@@ -194,49 +275,275 @@ func (d *Daemon) run() error {
 	}
 }
 
+// runHealthCheck queues every worktree for a check, those needing
+// attention first, and drops health entries for worktrees that are gone,
+// leases that have expired and long-finished sessions. Results are merged
+// into health.jsonl by the refresh workers.
 func (d *Daemon) runHealthCheck() {
-	store := jsonl.NewStore(d.config.WorkspaceDir)
-	entries := d.discoverWorktrees(store)
+	start := time.Now()
+	store := d.store()
+	entries := d.discover(store)
+	d.reapLeases(store)
+	if _, err := session.Prune(store, time.Now()); err != nil {
+		fmt.Printf("Error pruning sessions.jsonl: %v\n", err)
+	}
 
-	var health []jsonl.HealthEntry
+	previous, err := store.ReadHealth()
+	if err != nil {
+		fmt.Printf("Error reading health.jsonl: %v\n", err)
+	}
+	last := make(map[string]jsonl.HealthEntry, len(previous))
+	for _, h := range previous {
+		last[h.Folder] = h
+	}
+	current := make(map[string]bool, len(entries))
 	for _, e := range entries {
-		folderPath := filepath.Join(d.config.WorkspaceDir, e.Folder)
-		h := jsonl.HealthEntry{
-			Folder:    e.Folder,
-			LastCheck: time.Now(),
+		current[e.Folder] = true
+	}
+
+	err = store.UpdateHealth(func(existing []jsonl.HealthEntry) ([]jsonl.HealthEntry, error) {
+		kept := existing[:0]
+		for _, h := range existing {
+			if current[h.Folder] {
+				kept = append(kept, h)
+			}
 		}
+		return kept, nil
+	})
+	if err != nil {
+		fmt.Printf("Error writing health.jsonl: %v\n", err)
+	}
 
-		repo := git.NewRepo(folderPath)
-		h.Dirty, _ = repo.IsDirty()
-		h.Unpushed, _ = repo.UnpushedCount(e.Branch)
+	results := make([]<-chan jsonl.HealthEntry, 0, len(entries))
+	for _, e := range entries {
+		h, checked := last[e.Folder]
+		priority := PriorityRoutine
+		if checked && (h.Error != "" || NeedsAttention(h, e)) {
+			priority = PriorityAttention
+		}
+		results = append(results, d.queue.push(e, priority, h.LastCheck))
+	}
 
-		if !e.Base {
-			ghClient := gh.NewClient(folderPath)
-			if pr, _ := ghClient.GetPR(e.Branch); pr != nil {
-				h.PRState = &pr.State
-				h.PRTitle = &pr.Title
-			}
+	go func() {
+		for _, r := range results {
+			<-r
+		}
+		d.updateConflicts(entries)
+		d.maybeGC()
+		if elapsed := time.Since(start); elapsed > d.config.Interval {
+			fmt.Printf("Health check took %v, longer than the %v interval\n", elapsed.Round(time.Second), d.config.Interval)
 		}
 
-		health = append(health, h)
+		// Broadcast update to connected web clients
+		d.broadcast("health", map[string]interface{}{
+			"timestamp":     time.Now(),
+			"worktreeCount": len(results),
+		})
+	}()
+}
+
+// refreshWorker checks queued worktrees until the daemon stops, merging
+// each result into health.jsonl as soon as it is ready
+func (d *Daemon) refreshWorker() {
+	for {
+		r := d.queue.pop(d.stop)
+		if r == nil {
+			return
+		}
+		opts := LoadCheckOptions(d.store(), d.config.Timeout)
+		h := CheckWorktree(context.Background(), d.config.WorkspaceDir, r.entry, opts)
+		err := d.store().UpdateHealth(func(existing []jsonl.HealthEntry) ([]jsonl.HealthEntry, error) {
+			return MergeHealth(existing, []jsonl.HealthEntry{h}), nil
+		})
+		if err != nil {
+			fmt.Printf("Error writing health.jsonl: %v\n", err)
+		}
+		r.finish(h)
+
+		d.broadcast("worktree", map[string]interface{}{
+			"folder": h.Folder,
+			"health": h,
+		})
 	}
+}
 
-	if err := store.WriteHealth(health); err != nil {
+// maybeGC runs the configured GC once GCInterval has passed since the
+// last run, after a health check so it sees fresh state
+func (d *Daemon) maybeGC() {
+	if d.config.GC == nil {
+		return
+	}
+	d.mu.Lock()
+	due := time.Since(d.lastGC) >= d.config.GCInterval
+	if due {
+		d.lastGC = time.Now()
+	}
+	d.mu.Unlock()
+	if !due {
+		return
+	}
+	if removed := d.config.GC(); removed > 0 {
+		d.broadcast("gc", map[string]interface{}{
+			"timestamp": time.Now(),
+			"removed":   removed,
+		})
+	}
+}
+
+// reapLeases removes expired worktree leases
+func (d *Daemon) reapLeases(store jsonl.Store) {
+	reaped, err := lease.Reap(store, time.Now())
+	if err != nil {
+		fmt.Printf("Error reaping leases.jsonl: %v\n", err)
+		return
+	}
+	for _, l := range reaped {
+		fmt.Printf("Lease on %s held by %s expired\n", l.Folder, l.Owner)
+	}
+}
+
+// updateConflicts predicts merge conflicts between entries, records each
+// worktree's conflicts in health.jsonl and notifies web clients
+func (d *Daemon) updateConflicts(entries []jsonl.LocalEntry) {
+	conflicts := FindConflicts(context.Background(), d.config.WorkspaceDir, entries, LoadCheckOptions(d.store(), d.config.Timeout))
+	d.mu.Lock()
+	d.conflicts = conflicts
+	d.mu.Unlock()
+
+	with := ConflictsWith(conflicts)
+	err := d.store().UpdateHealth(func(existing []jsonl.HealthEntry) ([]jsonl.HealthEntry, error) {
+		for i := range existing {
+			existing[i].ConflictsWith = with[existing[i].Folder]
+		}
+		return existing, nil
+	})
+	if err != nil {
 		fmt.Printf("Error writing health.jsonl: %v\n", err)
 	}
 
-	// Broadcast update to connected web clients
+	d.broadcast("conflicts", map[string]interface{}{
+		"timestamp": time.Now(),
+		"count":     len(conflicts),
+	})
+}
+
+// Conflicts returns the conflicts predicted by the last full health
+// check, or finds them now if none has finished yet
+func (d *Daemon) Conflicts(ctx context.Context) []Conflict {
+	d.mu.Lock()
+	conflicts := d.conflicts
+	d.mu.Unlock()
+	if conflicts != nil {
+		return conflicts
+	}
+	store := d.store()
+	return FindConflicts(ctx, d.config.WorkspaceDir, d.discover(store), LoadCheckOptions(store, d.config.Timeout))
+}
+
+// RequestRefresh checks folder ahead of everything else queued and
+// returns its health once merged into health.jsonl
+func (d *Daemon) RequestRefresh(ctx context.Context, folder string) (jsonl.HealthEntry, error) {
+	e, ok := d.worktree(folder)
+	if !ok {
+		return jsonl.HealthEntry{}, fmt.Errorf("%w: %s", ErrUnknownWorktree, folder)
+	}
+	select {
+	case h := <-d.queue.push(e, PriorityRequested, time.Time{}):
+		return h, nil
+	case <-ctx.Done():
+		return jsonl.HealthEntry{}, ctx.Err()
+	}
+}
+
+// handleChange reacts to a batch of file changes from the watcher
+func (d *Daemon) handleChange(c Change) {
+	folders := c.Worktrees
+	if c.HasState("local.jsonl") || c.HasState("projects.jsonl") {
+		d.mu.Lock()
+		known := d.worktrees
+		d.mu.Unlock()
+		for _, e := range d.discover(d.store()) {
+			// Check new worktrees now rather than on the next tick
+			if _, ok := known[e.Folder]; !ok {
+				folders = append(folders, e.Folder)
+			}
+		}
+		d.broadcast("worktrees", map[string]interface{}{"timestamp": time.Now()})
+	}
+	if c.HasState("workflow.jsonl") {
+		d.broadcast("workflow", map[string]interface{}{"timestamp": time.Now()})
+	}
+	if c.HasState("leases.jsonl") {
+		d.broadcast("leases", map[string]interface{}{"timestamp": time.Now()})
+	}
+	if c.HasState("sessions.jsonl") {
+		d.broadcast("sessions", map[string]interface{}{"timestamp": time.Now()})
+	}
+	if c.Plans {
+		d.broadcast("plans", map[string]interface{}{"timestamp": time.Now()})
+	}
+
+	for _, folder := range folders {
+		if e, ok := d.worktree(folder); ok {
+			d.queue.push(e, PriorityTouched, time.Time{})
+		}
+	}
+}
+
+// worktree returns the known entry for folder with its current branch,
+// which a checkout may have changed since discovery
+func (d *Daemon) worktree(folder string) (jsonl.LocalEntry, bool) {
+	d.mu.Lock()
+	e, ok := d.worktrees[folder]
+	d.mu.Unlock()
+	if !ok {
+		// Registered since the last discovery
+		found, err := d.store().FindLocal(folder)
+		if err != nil || found == nil {
+			return jsonl.LocalEntry{}, false
+		}
+		e = *found
+	}
+
+	repo := git.NewRepo(filepath.Join(d.config.WorkspaceDir, folder)).WithTimeout(context.Background(), d.config.Timeout)
+	if branch, err := repo.CurrentBranch(); err == nil && branch != "HEAD" && branch != e.Branch {
+		e.Branch = branch
+		d.mu.Lock()
+		if _, known := d.worktrees[folder]; known {
+			d.worktrees[folder] = e
+		}
+		d.mu.Unlock()
+	}
+	return e, true
+}
+
+// broadcast sends an event to connected web clients, if serving
+func (d *Daemon) broadcast(eventType string, data interface{}) {
 	if d.httpServer != nil {
-		d.httpServer.Broadcast("health", map[string]interface{}{
-			"timestamp":     time.Now(),
-			"worktreeCount": len(health),
-		})
+		d.httpServer.Broadcast(eventType, data)
+	}
+}
+
+// discover finds all worktrees, remembers them for targeted refreshes and
+// updates the watched git directories
+func (d *Daemon) discover(store jsonl.Store) []jsonl.LocalEntry {
+	entries := d.discoverWorktrees(store)
+	worktrees := make(map[string]jsonl.LocalEntry, len(entries))
+	for _, e := range entries {
+		worktrees[e.Folder] = e
+	}
+	d.mu.Lock()
+	d.worktrees = worktrees
+	d.mu.Unlock()
+	if d.watcher != nil {
+		d.watcher.WatchWorktrees(entries)
 	}
+	return entries
 }
 
 // discoverWorktrees finds all worktrees by scanning projects from projects.jsonl
 // and running `git worktree list` for each. Merges with local.jsonl for local-only entries.
-func (d *Daemon) discoverWorktrees(store *jsonl.Store) []jsonl.LocalEntry {
+func (d *Daemon) discoverWorktrees(store jsonl.Store) []jsonl.LocalEntry {
 	discovered := make(map[string]jsonl.LocalEntry) // keyed by folder name
 
 	// Read projects and discover worktrees via git
@@ -247,7 +554,7 @@ func (d *Daemon) discoverWorktrees(store *jsonl.Store) []jsonl.LocalEntry {
 
 	for _, proj := range projects {
 		basePath := filepath.Join(d.config.WorkspaceDir, proj.Path)
-		repo := git.NewRepo(basePath)
+		repo := git.NewRepo(basePath).WithTimeout(context.Background(), d.config.Timeout)
 		worktrees, err := repo.WorktreeList()
 		if err != nil {
 			fmt.Printf("Error listing worktrees for %s: %v\n", proj.Name, err)
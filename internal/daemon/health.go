@@ -1,18 +1,25 @@
 package daemon
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/joshribakoff/bearing/internal/gh"
+	"github.com/joshribakoff/bearing/internal/git"
 	"github.com/joshribakoff/bearing/internal/jsonl"
 )
 
 // HealthChecker performs health checks on worktrees
 type HealthChecker struct {
-	store *jsonl.Store
+	store jsonl.Store
 }
 
 // NewHealthChecker creates a new health checker
-func NewHealthChecker(store *jsonl.Store) *HealthChecker {
+func NewHealthChecker(store jsonl.Store) *HealthChecker {
 	return &HealthChecker{store: store}
 }
 
@@ -28,8 +35,23 @@ func NeedsAttention(entry jsonl.HealthEntry, local jsonl.LocalEntry) bool {
 		return true
 	}
 
-	// Non-base folders with unpushed commits
-	if !local.Base && entry.Unpushed > 0 {
+	// Non-base folders with unpushed commits, unless already merged
+	if !local.Base && entry.Unpushed > 0 && !entry.Merged {
+		return true
+	}
+
+	// Conflicts, or a merge, rebase or similar left half done
+	if entry.Conflicted > 0 || entry.Operation != "" {
+		return true
+	}
+
+	// Merging would conflict with another worktree or the base
+	if len(entry.ConflictsWith) > 0 {
+		return true
+	}
+
+	// Commits made on a detached HEAD are easy to lose
+	if !local.Base && entry.Detached {
 		return true
 	}
 
@@ -40,3 +62,179 @@ func NeedsAttention(entry jsonl.HealthEntry, local jsonl.LocalEntry) bool {
 
 	return false
 }
+
+// MergeHealth replaces the entries in existing that have a counterpart in
+// updates, by folder, and appends the rest. ConflictsWith is found across
+// worktrees rather than by CheckWorktree, so an update without it keeps
+// the existing value.
+func MergeHealth(existing, updates []jsonl.HealthEntry) []jsonl.HealthEntry {
+	index := make(map[string]int, len(existing))
+	for i, h := range existing {
+		index[h.Folder] = i
+	}
+	merged := append([]jsonl.HealthEntry(nil), existing...)
+	for _, h := range updates {
+		if i, ok := index[h.Folder]; ok {
+			if h.ConflictsWith == nil {
+				h.ConflictsWith = merged[i].ConflictsWith
+			}
+			merged[i] = h
+		} else {
+			index[h.Folder] = len(merged)
+			merged = append(merged, h)
+		}
+	}
+	return merged
+}
+
+// CheckOptions configures worktree health checks
+type CheckOptions struct {
+	Timeout       time.Duration     // per git or gh call; 0 means no limit
+	BasedOn       map[string]string // base branch by "repo/branch", from workflow.jsonl
+	DefaultBranch map[string]string // by repo, from projects.jsonl; used when BasedOn has no entry
+}
+
+// LoadCheckOptions reads the base branches recorded in store
+func LoadCheckOptions(store jsonl.Store, timeout time.Duration) CheckOptions {
+	opts := CheckOptions{
+		Timeout:       timeout,
+		BasedOn:       make(map[string]string),
+		DefaultBranch: make(map[string]string),
+	}
+	workflow, _ := store.ReadWorkflow()
+	for _, wf := range workflow {
+		if wf.BasedOn != "" {
+			opts.BasedOn[wf.Repo+"/"+wf.Branch] = wf.BasedOn
+		}
+	}
+	projects, _ := store.ReadProjects()
+	for _, p := range projects {
+		if p.DefaultBranch != "" {
+			opts.DefaultBranch[p.Name] = p.DefaultBranch
+		}
+	}
+	return opts
+}
+
+// basedOn returns the branch a worktree's branch is compared against
+func (o CheckOptions) basedOn(repo, branch string) string {
+	if b := o.BasedOn[repo+"/"+branch]; b != "" {
+		return b
+	}
+	return o.DefaultBranch[repo]
+}
+
+// CheckWorktrees checks entries on a bounded pool of workers and returns
+// their health in the same order
+func CheckWorktrees(ctx context.Context, workspaceDir string, entries []jsonl.LocalEntry, workers int, opts CheckOptions) []jsonl.HealthEntry {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	health := make([]jsonl.HealthEntry, len(entries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(entries)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				health[i] = CheckWorktree(ctx, workspaceDir, entries[i], opts)
+			}
+		}()
+	}
+	for i := range entries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return health
+}
+
+// CheckWorktree gathers health for one worktree. A failed or timed-out
+// call is recorded in the entry's Error and the remaining checks still run.
+func CheckWorktree(ctx context.Context, workspaceDir string, e jsonl.LocalEntry, opts CheckOptions) jsonl.HealthEntry {
+	start := time.Now()
+	folderPath := filepath.Join(workspaceDir, e.Folder)
+	h := jsonl.HealthEntry{
+		Folder:    e.Folder,
+		LastCheck: start,
+	}
+
+	var errs []error
+	record := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	repo := git.NewRepo(folderPath).WithTimeout(ctx, opts.Timeout)
+
+	branch := e.Branch
+	if current, err := repo.CurrentBranch(); err != nil {
+		record(err)
+	} else if current == "HEAD" {
+		h.Detached = true
+	} else {
+		branch = current
+	}
+
+	counts, err := repo.Status()
+	record(err)
+	h.Staged, h.Modified, h.Untracked, h.Conflicted = counts.Staged, counts.Modified, counts.Untracked, counts.Conflicted
+	h.Dirty = counts.Dirty()
+
+	if !h.Detached {
+		upstream, err := repo.Upstream(branch)
+		record(err)
+		if upstream != "" {
+			h.Upstream = upstream
+			h.Ahead, h.Behind, err = repo.AheadBehind("HEAD", upstream)
+			record(err)
+			h.Unpushed = h.Ahead
+		}
+	}
+
+	if !e.Base {
+		if basedOn := opts.basedOn(e.Repo, branch); basedOn != "" {
+			h.BasedOn = basedOn
+			ref := basedOn
+			if repo.RemoteBranchExists(basedOn) {
+				ref = "origin/" + basedOn
+			}
+			h.AheadBase, h.BehindBase, err = repo.AheadBehind("HEAD", ref)
+			record(err)
+			if h.AheadBase > 0 {
+				// Squash and rebase merges leave the branch's own commits
+				// behind, though their changes are in the base
+				h.Merged, err = repo.IsMergedInto("HEAD", ref)
+				record(err)
+			}
+			if h.Upstream == "" {
+				// Never pushed: everything since the base is local only
+				h.Unpushed = h.AheadBase
+			}
+		}
+	}
+
+	h.Stashes, err = repo.StashCount()
+	record(err)
+	h.Operation, err = repo.Operation()
+	record(err)
+
+	if !e.Base && !h.Detached {
+		ghClient := gh.NewClient(folderPath).WithTimeout(ctx, opts.Timeout)
+		pr, err := ghClient.GetPR(branch)
+		if err != nil && !gh.IsUnavailable(err) {
+			record(err)
+		} else if pr != nil {
+			h.PRState = &pr.State
+			h.PRTitle = &pr.Title
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		h.Error = strings.TrimSpace(err.Error())
+	}
+	h.DurationMs = time.Since(start).Milliseconds()
+	return h
+}
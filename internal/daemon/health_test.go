@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.email=t@t", "-c", "user.name=T"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// initRepo creates a repository at path with one commit on main
+func initRepo(t *testing.T, path string) {
+	t.Helper()
+	os.MkdirAll(path, 0755)
+	gitRun(t, path, "init", "-q", "--initial-branch=main")
+	gitRun(t, path, "commit", "-q", "--allow-empty", "-m", "initial")
+}
+
+func TestCheckWorktreesRecordsErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, folder := range []string{"app", "lib"} {
+		initRepo(t, filepath.Join(dir, folder))
+	}
+
+	entries := []jsonl.LocalEntry{
+		{Folder: "app", Repo: "app", Branch: "main", Base: true},
+		{Folder: "missing", Repo: "missing", Branch: "main", Base: true},
+		{Folder: "lib", Repo: "lib", Branch: "main", Base: true},
+	}
+	health := CheckWorktrees(context.Background(), dir, entries, 2, CheckOptions{Timeout: 10 * time.Second})
+
+	if len(health) != len(entries) {
+		t.Fatalf("expected %d entries, got %d", len(entries), len(health))
+	}
+	for i, h := range health {
+		if h.Folder != entries[i].Folder {
+			t.Errorf("entry %d: expected %s, got %s", i, entries[i].Folder, h.Folder)
+		}
+	}
+	if health[0].Error != "" || health[2].Error != "" {
+		t.Errorf("unexpected errors: %q, %q", health[0].Error, health[2].Error)
+	}
+	if health[1].Error == "" {
+		t.Error("expected error for missing folder")
+	}
+}
+
+func TestCheckWorktreeTimeout(t *testing.T) {
+	dir := t.TempDir()
+	initRepo(t, filepath.Join(dir, "app"))
+
+	h := CheckWorktree(context.Background(), dir, jsonl.LocalEntry{Folder: "app", Branch: "main", Base: true}, CheckOptions{Timeout: time.Nanosecond})
+	if h.Error == "" {
+		t.Error("expected timeout to be recorded")
+	}
+}
+
+func TestCheckWorktreeWithoutGH(t *testing.T) {
+	dir := t.TempDir()
+	initRepo(t, filepath.Join(dir, "app"))
+	gitRun(t, filepath.Join(dir, "app"), "checkout", "-q", "-b", "feature")
+
+	// Only git on PATH: no gh is like no pull request, not a failure
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, "bin")
+	os.MkdirAll(bin, 0755)
+	if err := os.Symlink(gitPath, filepath.Join(bin, "git")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	h := CheckWorktree(context.Background(), dir, jsonl.LocalEntry{Folder: "app", Repo: "app", Branch: "feature"}, CheckOptions{Timeout: 10 * time.Second})
+	if h.Error != "" || h.PRState != nil {
+		t.Errorf("expected no error and no pull request, got %+v", h)
+	}
+}
+
+func TestMergeHealth(t *testing.T) {
+	existing := []jsonl.HealthEntry{{Folder: "a"}, {Folder: "b", Unpushed: 1}}
+	merged := MergeHealth(existing, []jsonl.HealthEntry{{Folder: "b", Unpushed: 3}, {Folder: "c"}})
+
+	var folders []string
+	for _, h := range merged {
+		folders = append(folders, h.Folder)
+	}
+	if !reflect.DeepEqual(folders, []string{"a", "b", "c"}) || merged[1].Unpushed != 3 {
+		t.Errorf("unexpected merge: %+v", merged)
+	}
+	if existing[1].Unpushed != 1 {
+		t.Error("MergeHealth modified its input")
+	}
+
+	// Conflicts are found separately and survive a fresh check
+	existing = []jsonl.HealthEntry{{Folder: "a", ConflictsWith: []string{"b"}}}
+	merged = MergeHealth(existing, []jsonl.HealthEntry{{Folder: "a", Dirty: true}})
+	if !merged[0].Dirty || !reflect.DeepEqual(merged[0].ConflictsWith, []string{"b"}) {
+		t.Errorf("unexpected merge: %+v", merged)
+	}
+}
+
+func TestCheckWorktreeGitStatus(t *testing.T) {
+	dir := t.TempDir()
+	origin := filepath.Join(dir, "origin")
+	initRepo(t, origin)
+	gitRun(t, dir, "clone", "-q", origin, "app")
+	app := filepath.Join(dir, "app")
+
+	// A worktree never pushed, two commits past main, while main moved on
+	gitRun(t, app, "worktree", "add", "-q", "-b", "feature", filepath.Join(dir, "app-feature"))
+	wt := filepath.Join(dir, "app-feature")
+	gitRun(t, wt, "commit", "-q", "--allow-empty", "-m", "f1")
+	gitRun(t, wt, "commit", "-q", "--allow-empty", "-m", "f2")
+	gitRun(t, origin, "commit", "-q", "--allow-empty", "-m", "m1")
+	gitRun(t, app, "fetch", "-q")
+	os.WriteFile(filepath.Join(wt, "stash.txt"), []byte("x"), 0644)
+	gitRun(t, wt, "stash", "-q", "-u")
+	os.WriteFile(filepath.Join(wt, "new.txt"), []byte("x"), 0644)
+
+	opts := CheckOptions{BasedOn: map[string]string{"app/feature": "main"}}
+	h := CheckWorktree(context.Background(), dir, jsonl.LocalEntry{Folder: "app-feature", Repo: "app", Branch: "feature"}, opts)
+
+	want := jsonl.GitStatus{BasedOn: "main", AheadBase: 2, BehindBase: 1, Untracked: 1, Stashes: 1}
+	if h.GitStatus != want {
+		t.Errorf("expected %+v, got %+v", want, h.GitStatus)
+	}
+	if h.Unpushed != 2 || !h.Dirty {
+		t.Errorf("expected 2 unpushed and dirty, got %+v", h)
+	}
+
+	// Base folder: compared with its upstream only
+	h = CheckWorktree(context.Background(), dir, jsonl.LocalEntry{Folder: "app", Repo: "app", Branch: "main", Base: true}, opts)
+	want = jsonl.GitStatus{Upstream: "origin/main", Behind: 1, Stashes: 1}
+	if h.GitStatus != want || h.Error != "" {
+		t.Errorf("expected %+v, got %+v (%s)", want, h.GitStatus, h.Error)
+	}
+}
+
+func TestNeedsAttention(t *testing.T) {
+	base := jsonl.LocalEntry{Folder: "app", Base: true}
+	feature := jsonl.LocalEntry{Folder: "app-x"}
+	cases := []struct {
+		name   string
+		health jsonl.HealthEntry
+		local  jsonl.LocalEntry
+		want   bool
+	}{
+		{"clean", jsonl.HealthEntry{}, feature, false},
+		{"rebase", jsonl.HealthEntry{GitStatus: jsonl.GitStatus{Operation: "rebase"}}, base, true},
+		{"conflicts", jsonl.HealthEntry{GitStatus: jsonl.GitStatus{Conflicted: 2}}, feature, true},
+		{"detached worktree", jsonl.HealthEntry{GitStatus: jsonl.GitStatus{Detached: true}}, feature, true},
+		{"detached base", jsonl.HealthEntry{GitStatus: jsonl.GitStatus{Detached: true}}, base, false},
+		{"stash only", jsonl.HealthEntry{GitStatus: jsonl.GitStatus{Stashes: 3}}, feature, false},
+	}
+	for _, c := range cases {
+		if got := NeedsAttention(c.health, c.local); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
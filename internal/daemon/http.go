@@ -1,8 +1,10 @@
 package daemon
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
@@ -15,20 +17,23 @@ import (
 	"time"
 
 	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/session"
 )
 
 // HTTPServer serves the web dashboard API and static files
 type HTTPServer struct {
-	store     *jsonl.Store
+	store     jsonl.Store
 	workspace string
 	mu        sync.RWMutex
 	staticFS  fs.FS
 	clients   map[chan []byte]bool
 	clientsMu sync.RWMutex
+	refresh   func(ctx context.Context, folder string) (jsonl.HealthEntry, error) // set by the daemon
+	conflicts func(ctx context.Context) []Conflict                                // set by the daemon
 }
 
 // NewHTTPServer creates a new HTTP server for the dashboard
-func NewHTTPServer(store *jsonl.Store, workspace string, staticFS fs.FS) *HTTPServer {
+func NewHTTPServer(store jsonl.Store, workspace string, staticFS fs.FS) *HTTPServer {
 	return &HTTPServer{
 		store:     store,
 		workspace: workspace,
@@ -44,6 +49,8 @@ func (s *HTTPServer) Handler() http.Handler {
 	// API endpoints
 	mux.HandleFunc("/api/projects", s.handleProjects)
 	mux.HandleFunc("/api/worktrees", s.handleWorktrees)
+	mux.HandleFunc("POST /api/worktrees/{folder}/refresh", s.handleRefresh)
+	mux.HandleFunc("GET /api/conflicts", s.handleConflicts)
 	mux.HandleFunc("/api/plans", s.handlePlans)
 	mux.HandleFunc("/api/issues", s.handleIssues)
 	mux.HandleFunc("/api/prs", s.handlePRs)
@@ -106,15 +113,23 @@ func (s *HTTPServer) handleProjects(w http.ResponseWriter, r *http.Request) {
 
 // WorktreeResponse combines local and health data for API response
 type WorktreeResponse struct {
-	Folder   string  `json:"folder"`
-	Repo     string  `json:"repo"`
-	Branch   string  `json:"branch"`
-	Base     bool    `json:"base"`
-	Purpose  string  `json:"purpose,omitempty"`
-	Status   string  `json:"status,omitempty"`
-	Dirty    bool    `json:"dirty"`
-	Unpushed int     `json:"unpushed"`
-	PRState  *string `json:"prState,omitempty"`
+	Folder      string  `json:"folder"`
+	Repo        string  `json:"repo"`
+	Branch      string  `json:"branch"`
+	Base        bool    `json:"base"`
+	Purpose     string  `json:"purpose,omitempty"`
+	Status      string  `json:"status,omitempty"`
+	Dirty       bool    `json:"dirty"`
+	Unpushed    int     `json:"unpushed"`
+	PRState     *string `json:"prState,omitempty"`
+	HealthError string  `json:"healthError,omitempty"`
+	jsonl.GitStatus
+	ConflictsWith []string            `json:"conflictsWith,omitempty"`
+	LeaseOwner    string              `json:"leaseOwner,omitempty"` // session holding a live lease
+	LeaseExpires  *time.Time          `json:"leaseExpires,omitempty"`
+	Session       *jsonl.SessionEntry `json:"session,omitempty"`      // latest agent session in the folder
+	SessionState  string              `json:"sessionState,omitempty"` // active, idle or stopped
+	Unattended    bool                `json:"unattended,omitempty"`   // dirty with no live session
 }
 
 func (s *HTTPServer) handleWorktrees(w http.ResponseWriter, r *http.Request) {
@@ -136,6 +151,9 @@ func (s *HTTPServer) handleWorktrees(w http.ResponseWriter, r *http.Request) {
 
 	workflow, _ := s.store.ReadWorkflow()
 	health, _ := s.store.ReadHealth()
+	leases, _ := s.store.ReadLeases()
+	sessions, _ := s.store.ReadSessions()
+	latestSession := session.ByFolder(sessions)
 
 	// Build lookup maps
 	workflowMap := make(map[string]jsonl.WorkflowEntry)
@@ -149,6 +167,14 @@ func (s *HTTPServer) handleWorktrees(w http.ResponseWriter, r *http.Request) {
 		healthMap[h.Folder] = h
 	}
 
+	now := time.Now()
+	leaseMap := make(map[string]jsonl.LeaseEntry)
+	for _, l := range leases {
+		if !l.Expired(now) {
+			leaseMap[l.Folder] = l
+		}
+	}
+
 	// Combine data - always return array, not null
 	resp := make([]WorktreeResponse, 0)
 	for _, l := range local {
@@ -172,8 +198,22 @@ func (s *HTTPServer) handleWorktrees(w http.ResponseWriter, r *http.Request) {
 			wt.Dirty = h.Dirty
 			wt.Unpushed = h.Unpushed
 			wt.PRState = h.PRState
+			wt.HealthError = h.Error
+			wt.GitStatus = h.GitStatus
+			wt.ConflictsWith = h.ConflictsWith
+		}
+
+		if l, ok := leaseMap[l.Folder]; ok {
+			wt.LeaseOwner = l.Owner
+			wt.LeaseExpires = &l.Expires
 		}
 
+		if sess, ok := latestSession[l.Folder]; ok {
+			wt.Session = &sess
+			wt.SessionState = session.State(sess, now)
+		}
+		wt.Unattended = session.Unattended(wt.Dirty, wt.Session, now)
+
 		resp = append(resp, wt)
 	}
 
@@ -181,6 +221,56 @@ func (s *HTTPServer) handleWorktrees(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// handleRefresh re-checks one worktree ahead of the daemon's queue and
+// responds with its new health entry
+func (s *HTTPServer) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if s.refresh == nil {
+		http.Error(w, "Refresh not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	h, err := s.refresh(r.Context(), r.PathValue("folder"))
+	if errors.Is(err, ErrUnknownWorktree) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h)
+}
+
+// handleConflicts lists predicted merge conflicts between worktrees,
+// optionally for one repo. Without a daemon they are found on request
+// from local.jsonl.
+func (s *HTTPServer) handleConflicts(w http.ResponseWriter, r *http.Request) {
+	var conflicts []Conflict
+	if s.conflicts != nil {
+		conflicts = s.conflicts(r.Context())
+	} else {
+		local, err := s.store.ReadLocal()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		conflicts = FindConflicts(r.Context(), s.workspace, local, LoadCheckOptions(s.store, 0))
+	}
+
+	resp := make([]Conflict, 0, len(conflicts))
+	project := r.URL.Query().Get("project")
+	for _, c := range conflicts {
+		if project == "" || c.Repo == project {
+			resp = append(resp, c)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // PlanResponse for API
 type PlanResponse struct {
 	Project string `json:"project"`
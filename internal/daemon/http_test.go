@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -8,11 +9,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/joshribakoff/bearing/internal/jsonl"
 )
 
-func setupTestStore(t *testing.T) (*jsonl.Store, string) {
+func setupTestStore(t *testing.T) (jsonl.Store, string) {
 	t.Helper()
 	dir := t.TempDir()
 
@@ -552,3 +554,129 @@ Some content.
 		t.Errorf("expected default status 'draft', got %s", fm2["status"])
 	}
 }
+
+func TestHandleRefresh(t *testing.T) {
+	store, dir := setupTestStore(t)
+	server := NewHTTPServer(store, dir, nil)
+	server.refresh = func(ctx context.Context, folder string) (jsonl.HealthEntry, error) {
+		if folder != "project-feature" {
+			return jsonl.HealthEntry{}, ErrUnknownWorktree
+		}
+		return jsonl.HealthEntry{Folder: folder, Unpushed: 5}, nil
+	}
+	handler := server.Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/worktrees/project-feature/refresh", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var h jsonl.HealthEntry
+	if err := json.NewDecoder(rec.Body).Decode(&h); err != nil {
+		t.Fatal(err)
+	}
+	if h.Folder != "project-feature" || h.Unpushed != 5 {
+		t.Errorf("unexpected health: %+v", h)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/worktrees/nope/refresh", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/worktrees/project-feature/refresh", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status 405, got %d", rec.Code)
+	}
+}
+
+func TestHandleConflicts(t *testing.T) {
+	store, dir := setupTestStore(t)
+	server := NewHTTPServer(store, dir, nil)
+	server.conflicts = func(ctx context.Context) []Conflict {
+		return []Conflict{
+			{Repo: "project", Folder: "project-feature", Other: "project-other", Conflicts: []string{"a.go"}},
+			{Repo: "other", Folder: "other-x", Base: true, OtherBranch: "main", Overlap: []string{"b.go"}},
+		}
+	}
+	handler := server.Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/conflicts?project=project", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var conflicts []Conflict
+	if err := json.NewDecoder(rec.Body).Decode(&conflicts); err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Folder != "project-feature" || !conflicts[0].Likely() {
+		t.Errorf("unexpected conflicts: %+v", conflicts)
+	}
+}
+
+func TestHandleWorktreesLease(t *testing.T) {
+	store, dir := setupTestStore(t)
+	now := time.Now()
+	store.WriteLeases([]jsonl.LeaseEntry{
+		{Folder: "project-feature", Owner: "s1", Expires: now.Add(time.Hour)},
+		{Folder: "project-main", Owner: "s2", Expires: now.Add(-time.Minute)},
+	})
+	server := NewHTTPServer(store, dir, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/worktrees", nil)
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+
+	var worktrees []WorktreeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&worktrees); err != nil {
+		t.Fatal(err)
+	}
+	for _, wt := range worktrees {
+		want := ""
+		if wt.Folder == "project-feature" {
+			want = "s1"
+		}
+		if wt.LeaseOwner != want {
+			t.Errorf("%s: expected lease owner %q, got %q", wt.Folder, want, wt.LeaseOwner)
+		}
+	}
+}
+
+func TestHandleWorktreesSession(t *testing.T) {
+	store, dir := setupTestStore(t)
+	now := time.Now()
+	store.WriteSessions([]jsonl.SessionEntry{
+		{ID: "s1", Folder: "project-main", Started: now, LastActivity: now},
+		{ID: "s2", Folder: "project-feature", Started: now, LastActivity: now, Ended: &now},
+	})
+	server := NewHTTPServer(store, dir, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/worktrees", nil)
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+
+	var worktrees []WorktreeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&worktrees); err != nil {
+		t.Fatal(err)
+	}
+	for _, wt := range worktrees {
+		switch wt.Folder {
+		case "project-main":
+			if wt.Session == nil || wt.SessionState != "active" || wt.Unattended {
+				t.Errorf("expected active session on project-main, got %+v", wt)
+			}
+		case "project-feature":
+			// Dirty in the fixture, and its session stopped
+			if wt.SessionState != "stopped" || !wt.Unattended {
+				t.Errorf("expected unattended project-feature, got %+v", wt)
+			}
+		}
+	}
+}
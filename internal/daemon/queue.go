@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+// Refresh priorities, lowest first. Queued worktrees are checked in
+// priority order, then least recently checked first.
+const (
+	PriorityRoutine   = iota // periodic tick
+	PriorityAttention        // last check found something to act on
+	PriorityTouched          // its HEAD or index just changed
+	PriorityRequested        // asked for through the CLI or API
+)
+
+// refreshRequest is one queued worktree check
+type refreshRequest struct {
+	entry     jsonl.LocalEntry
+	priority  int
+	lastCheck time.Time
+	seq       uint64
+	waiters   []chan jsonl.HealthEntry
+	index     int // in the heap
+}
+
+// refreshQueue orders pending worktree checks. A worktree is queued at
+// most once; pushing it again raises its priority and refreshes its entry.
+type refreshQueue struct {
+	mu       sync.Mutex
+	items    refreshHeap
+	byFolder map[string]*refreshRequest
+	seq      uint64
+	ready    chan struct{}
+}
+
+func newRefreshQueue() *refreshQueue {
+	return &refreshQueue{
+		byFolder: make(map[string]*refreshRequest),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// push queues a check of e and returns a channel that receives its result
+func (q *refreshQueue) push(e jsonl.LocalEntry, priority int, lastCheck time.Time) <-chan jsonl.HealthEntry {
+	result := make(chan jsonl.HealthEntry, 1)
+
+	q.mu.Lock()
+	if r, ok := q.byFolder[e.Folder]; ok {
+		r.entry = e
+		r.waiters = append(r.waiters, result)
+		if priority > r.priority {
+			r.priority = priority
+			heap.Fix(&q.items, r.index)
+		}
+	} else {
+		q.seq++
+		r := &refreshRequest{entry: e, priority: priority, lastCheck: lastCheck, seq: q.seq, waiters: []chan jsonl.HealthEntry{result}}
+		heap.Push(&q.items, r)
+		q.byFolder[e.Folder] = r
+	}
+	q.mu.Unlock()
+
+	q.signal()
+	return result
+}
+
+// pop removes the most urgent request, waiting until there is one. It
+// returns nil once stop is closed.
+func (q *refreshQueue) pop(stop <-chan struct{}) *refreshRequest {
+	for {
+		q.mu.Lock()
+		if q.items.Len() > 0 {
+			r := heap.Pop(&q.items).(*refreshRequest)
+			delete(q.byFolder, r.entry.Folder)
+			more := q.items.Len() > 0
+			q.mu.Unlock()
+			if more {
+				// Wake another worker for the rest
+				q.signal()
+			}
+			return r
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// len returns the number of queued requests
+func (q *refreshQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.Len()
+}
+
+func (q *refreshQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// finish delivers the result of r to everyone waiting on it
+func (r *refreshRequest) finish(h jsonl.HealthEntry) {
+	for _, w := range r.waiters {
+		w <- h
+	}
+}
+
+// refreshHeap is a max-heap of requests by priority, then staleness
+type refreshHeap []*refreshRequest
+
+func (h refreshHeap) Len() int { return len(h) }
+func (h refreshHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	if !h[i].lastCheck.Equal(h[j].lastCheck) {
+		return h[i].lastCheck.Before(h[j].lastCheck)
+	}
+	return h[i].seq < h[j].seq
+}
+func (h refreshHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *refreshHeap) Push(x any) {
+	r := x.(*refreshRequest)
+	r.index = len(*h)
+	*h = append(*h, r)
+}
+func (h *refreshHeap) Pop() any {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+func TestRefreshQueueOrder(t *testing.T) {
+	q := newRefreshQueue()
+	now := time.Now()
+	first := q.push(jsonl.LocalEntry{Folder: "a"}, PriorityRoutine, now)
+	q.push(jsonl.LocalEntry{Folder: "b"}, PriorityRoutine, now.Add(-time.Hour))
+	q.push(jsonl.LocalEntry{Folder: "c"}, PriorityAttention, now)
+	again := q.push(jsonl.LocalEntry{Folder: "a", Branch: "moved"}, PriorityRequested, time.Time{})
+
+	if q.len() != 3 {
+		t.Fatalf("expected duplicates to merge, got %d queued", q.len())
+	}
+
+	stop := make(chan struct{})
+	var order []string
+	for q.len() > 0 {
+		r := q.pop(stop)
+		order = append(order, r.entry.Folder)
+		if r.entry.Folder == "a" && r.entry.Branch != "moved" {
+			t.Error("expected the latest entry for a")
+		}
+		r.finish(jsonl.HealthEntry{Folder: r.entry.Folder})
+	}
+	if want := []string{"a", "c", "b"}; len(order) != 3 || order[0] != want[0] || order[1] != want[1] || order[2] != want[2] {
+		t.Errorf("expected order %v, got %v", want, order)
+	}
+	if (<-first).Folder != "a" || (<-again).Folder != "a" {
+		t.Error("expected both waiters on a to get its result")
+	}
+
+	close(stop)
+	if q.pop(stop) != nil {
+		t.Error("expected nil from a stopped, empty queue")
+	}
+}
+
+func TestRequestRefreshMergesHealth(t *testing.T) {
+	dir := t.TempDir()
+	initRepo(t, filepath.Join(dir, "app"))
+	os.WriteFile(filepath.Join(dir, "app", "new.txt"), []byte("x"), 0644)
+
+	store := jsonl.NewStore(dir)
+	store.WriteLocal([]jsonl.LocalEntry{{Folder: "app", Repo: "app", Branch: "main", Base: true}})
+	store.WriteHealth([]jsonl.HealthEntry{{Folder: "other", Unpushed: 2}, {Folder: "app"}})
+
+	d := New(Config{WorkspaceDir: dir, Timeout: 10 * time.Second})
+	go d.refreshWorker()
+	defer close(d.stop)
+
+	h, err := d.RequestRefresh(context.Background(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Dirty {
+		t.Errorf("expected app to be dirty, got %+v", h)
+	}
+
+	health, err := store.ReadHealth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(health) != 2 || health[0].Unpushed != 2 || !health[1].Dirty {
+		t.Errorf("expected app merged next to other, got %+v", health)
+	}
+
+	if _, err := d.RequestRefresh(context.Background(), "missing"); !errors.Is(err, ErrUnknownWorktree) {
+		t.Errorf("expected ErrUnknownWorktree, got %v", err)
+	}
+}
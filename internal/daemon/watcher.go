@@ -1,62 +1,263 @@
 package daemon
 
 import (
+	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/joshribakoff/bearing/internal/git"
+	"github.com/joshribakoff/bearing/internal/jsonl"
 )
 
-// Watcher wraps fsnotify to watch for file changes
+// DefaultDebounce is how long the watcher waits for a burst of file
+// changes to settle before reporting them
+const DefaultDebounce = 250 * time.Millisecond
+
+// maxDebounceDelay bounds how long a steady stream of changes can hold
+// back a report
+const maxDebounceDelay = time.Second
+
+// watchedStateFiles are the JSONL files whose changes are reported.
+// health.jsonl is left out because the daemon writes it itself.
+var watchedStateFiles = map[string]bool{
+	"local.jsonl":    true,
+	"workflow.jsonl": true,
+	"projects.jsonl": true,
+	"leases.jsonl":   true,
+	"sessions.jsonl": true,
+}
+
+// Change is a debounced batch of file system changes
+type Change struct {
+	Worktrees []string // folders whose HEAD or index changed
+	State     []string // JSONL files that changed, e.g. local.jsonl
+	Plans     bool     // something under plans/ changed
+}
+
+// HasState reports whether the named JSONL file changed
+func (c Change) HasState(name string) bool {
+	for _, s := range c.State {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Watcher reports changes to the workspace JSONL files, each worktree's
+// HEAD and index, and the plans tree.
+//
+// Directories are watched rather than files: git and the JSONL store
+// replace files by renaming a temp file over them, which would silently
+// end a watch on the old inode. State kept in the SQLite store is not
+// watched; the daemon picks it up on its next tick.
 type Watcher struct {
 	watcher   *fsnotify.Watcher
-	onChange  func()
-	watchPath string
+	workspace string
+	plansDir  string
+	debounce  time.Duration
+	changes   chan Change
+	done      chan struct{}
+
+	mu      sync.Mutex
+	gitDirs map[string]string // watched git admin dir (and its logs dir) -> folder
 }
 
-// NewWatcher creates a new file watcher for the workspace
-func NewWatcher(workspaceDir string, onChange func()) (*Watcher, error) {
-	w, err := fsnotify.NewWatcher()
+// NewWatcher creates a watcher for the workspace. Worktrees are added with
+// WatchWorktrees.
+func NewWatcher(workspaceDir string, debounce time.Duration) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
+	if err := fw.Add(workspaceDir); err != nil {
+		fw.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", workspaceDir, err)
+	}
 
-	watcher := &Watcher{
-		watcher:   w,
-		onChange:  onChange,
-		watchPath: workspaceDir,
+	w := &Watcher{
+		watcher:   fw,
+		workspace: filepath.Clean(workspaceDir),
+		plansDir:  filepath.Join(filepath.Clean(workspaceDir), "plans"),
+		debounce:  debounce,
+		changes:   make(chan Change),
+		done:      make(chan struct{}),
+		gitDirs:   make(map[string]string),
 	}
+	w.watchTree(w.plansDir)
+	return w, nil
+}
+
+// Changes returns the channel debounced changes are delivered on
+func (w *Watcher) Changes() <-chan Change {
+	return w.changes
+}
 
-	// Watch local.jsonl for changes
-	localPath := filepath.Join(workspaceDir, "local.jsonl")
-	if err := w.Add(localPath); err != nil {
-		// File might not exist yet, that's ok
+// WatchWorktrees makes the watched git directories match entries, adding
+// new worktrees and dropping removed ones
+func (w *Watcher) WatchWorktrees(entries []jsonl.LocalEntry) {
+	want := make(map[string]string)
+	for _, e := range entries {
+		gitDir, err := git.GitDir(filepath.Join(w.workspace, e.Folder))
+		if err != nil {
+			continue
+		}
+		want[gitDir] = e.Folder
+		want[filepath.Join(gitDir, "logs")] = e.Folder
 	}
 
-	return watcher, nil
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for dir := range w.gitDirs {
+		if _, ok := want[dir]; !ok {
+			w.watcher.Remove(dir)
+			delete(w.gitDirs, dir)
+		}
+	}
+	for dir, folder := range want {
+		if _, ok := w.gitDirs[dir]; ok {
+			w.gitDirs[dir] = folder
+			continue
+		}
+		if err := w.watcher.Add(dir); err == nil {
+			w.gitDirs[dir] = folder
+		}
+	}
 }
 
-// Start begins watching for changes
-func (w *Watcher) Start() {
-	go func() {
-		for {
-			select {
-			case event, ok := <-w.watcher.Events:
-				if !ok {
-					return
-				}
-				if event.Op&fsnotify.Write == fsnotify.Write {
-					w.onChange()
-				}
-			case _, ok := <-w.watcher.Errors:
-				if !ok {
-					return
-				}
-			}
+// watchTree watches root and every directory below it
+func (w *Watcher) watchTree(root string) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
 		}
-	}()
+		if d.IsDir() {
+			w.watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// Start begins delivering changes
+func (w *Watcher) Start() {
+	go w.run()
 }
 
 // Close stops watching
 func (w *Watcher) Close() error {
+	close(w.done)
 	return w.watcher.Close()
 }
+
+func (w *Watcher) run() {
+	pending := newBatch()
+	var timer *time.Timer
+	var fire <-chan time.Time
+	var deadline time.Time
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !w.classify(event, pending) {
+				continue
+			}
+			now := time.Now()
+			if fire == nil {
+				deadline = now.Add(maxDebounceDelay)
+			}
+			wait := min(w.debounce, deadline.Sub(now))
+			if timer == nil {
+				timer = time.NewTimer(wait)
+			} else {
+				timer.Reset(wait)
+			}
+			fire = timer.C
+		case <-fire:
+			fire = nil
+			select {
+			case w.changes <- pending.change():
+			case <-w.done:
+				return
+			}
+			pending = newBatch()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("Watcher error: %v\n", err)
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// classify records event in b, returning false if it is not of interest
+func (w *Watcher) classify(event fsnotify.Event, b *batch) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	dir, name := filepath.Dir(event.Name), filepath.Base(event.Name)
+
+	if event.Name == w.plansDir || strings.HasPrefix(event.Name, w.plansDir+string(filepath.Separator)) {
+		if event.Op&fsnotify.Create != 0 {
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				w.watchTree(event.Name)
+			}
+		}
+		b.plans = true
+		return true
+	}
+
+	if dir == w.workspace && watchedStateFiles[name] {
+		b.state[name] = true
+		return true
+	}
+
+	if name != "HEAD" && name != "index" {
+		return false
+	}
+	w.mu.Lock()
+	folder, ok := w.gitDirs[dir]
+	w.mu.Unlock()
+	if ok {
+		b.worktrees[folder] = true
+	}
+	return ok
+}
+
+// batch accumulates changes until the debounce timer fires
+type batch struct {
+	worktrees map[string]bool
+	state     map[string]bool
+	plans     bool
+}
+
+func newBatch() *batch {
+	return &batch{worktrees: map[string]bool{}, state: map[string]bool{}}
+}
+
+func (b *batch) change() Change {
+	return Change{
+		Worktrees: sortedKeys(b.worktrees),
+		State:     sortedKeys(b.state),
+		Plans:     b.plans,
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+// waitFor waits for a change matching ok, skipping stragglers from
+// earlier steps
+func waitFor(t *testing.T, w *Watcher, what string, ok func(Change) bool) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case c := <-w.Changes():
+			if ok(c) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestWatcherReportsChanges(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app")
+	for _, args := range [][]string{
+		{"init", "-q", "--initial-branch=main", app},
+		{"-C", app, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	w, err := NewWatcher(dir, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.WatchWorktrees([]jsonl.LocalEntry{{Folder: "app", Repo: "app", Branch: "main", Base: true}})
+	w.Start()
+
+	// A checkout rewrites HEAD through a rename
+	if out, err := exec.Command("git", "-C", app, "checkout", "-q", "-b", "feature").CombinedOutput(); err != nil {
+		t.Fatalf("checkout: %v\n%s", err, out)
+	}
+	waitFor(t, w, "app worktree change", func(c Change) bool {
+		return reflect.DeepEqual(c.Worktrees, []string{"app"})
+	})
+
+	// The store replaces local.jsonl through a rename too
+	if err := jsonl.NewStore(dir).WriteLocal([]jsonl.LocalEntry{{Folder: "app", Repo: "app", Branch: "feature"}}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, w, "local.jsonl change", func(c Change) bool { return c.HasState("local.jsonl") })
+
+	// New plan directories are picked up
+	os.MkdirAll(filepath.Join(dir, "plans", "app"), 0755)
+	waitFor(t, w, "plans directory change", func(c Change) bool { return c.Plans })
+	os.WriteFile(filepath.Join(dir, "plans", "app", "idea.md"), []byte("# Idea\n"), 0644)
+	waitFor(t, w, "plan file change", func(c Change) bool { return c.Plans })
+}
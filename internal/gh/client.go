@@ -2,20 +2,96 @@ package gh
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Client wraps GitHub CLI operations
 type Client struct {
 	repoPath string
+	ctx      context.Context
+	timeout  time.Duration // per call; 0 means no limit
 }
 
 // NewClient creates a Client for the given repo path
 func NewClient(repoPath string) *Client {
-	return &Client{repoPath: repoPath}
+	return &Client{repoPath: repoPath, ctx: context.Background()}
+}
+
+// WithTimeout returns a copy of c whose calls are cancelled when ctx is
+// done and each limited to timeout (0 means no limit)
+func (c *Client) WithTimeout(ctx context.Context, timeout time.Duration) *Client {
+	cc := *c
+	cc.ctx, cc.timeout = ctx, timeout
+	return &cc
+}
+
+// run executes gh in the repo directory and returns its stdout
+func (c *Client) run(args ...string) ([]byte, error) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(c.ctx, c.timeout)
+	}
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = c.repoPath
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("gh %s: %w", args[0], ctx.Err())
+		}
+		return nil, &Error{Args: args, Stderr: stderr.String(), Err: err}
+	}
+	return stdout.Bytes(), nil
+}
+
+// Error is a failed gh invocation
+type Error struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := strings.TrimSpace(e.Stderr)
+	if msg == "" {
+		msg = e.Err.Error()
+	}
+	return fmt.Sprintf("gh %s: %s", strings.Join(e.Args[:min(2, len(e.Args))], " "), msg)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// IsUnavailable reports whether err means gh can't be used here at all:
+// it isn't installed or logged in, or the repo has no GitHub remote.
+// Callers treat that like having no pull request rather than a failure.
+func IsUnavailable(err error) bool {
+	if errors.Is(err, exec.ErrNotFound) {
+		return true
+	}
+	var ghErr *Error
+	if !errors.As(err, &ghErr) {
+		return false
+	}
+	for _, msg := range []string{
+		"no git remotes found",
+		"none of the git remotes configured for this repository point to a known GitHub host",
+		"gh auth login",
+	} {
+		if strings.Contains(ghErr.Stderr, msg) {
+			return true
+		}
+	}
+	return false
 }
 
 // PRInfo contains PR information
@@ -24,26 +100,25 @@ type PRInfo struct {
 	Number int    `json:"number"`
 	URL    string `json:"url"`
 	Title  string `json:"title"`
+	// HeadRefOid is the PR's head commit; for a merged PR, the last
+	// commit that was merged
+	HeadRefOid string `json:"headRefOid"`
 }
 
 // GetPR gets PR info for the given branch
 func (c *Client) GetPR(branch string) (*PRInfo, error) {
-	cmd := exec.Command("gh", "pr", "view", branch, "--json", "state,number,url,title")
-	cmd.Dir = c.repoPath
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
+	out, err := c.run("pr", "view", branch, "--json", "state,number,url,title,headRefOid")
+	if err != nil {
 		// No PR exists
-		if strings.Contains(stderr.String(), "no pull requests found") {
+		var ghErr *Error
+		if errors.As(err, &ghErr) && strings.Contains(ghErr.Stderr, "no pull requests found") {
 			return nil, nil
 		}
 		return nil, err
 	}
 
 	var info PRInfo
-	if err := json.Unmarshal(stdout.Bytes(), &info); err != nil {
+	if err := json.Unmarshal(out, &info); err != nil {
 		return nil, err
 	}
 	return &info, nil
@@ -60,17 +135,13 @@ type Issue struct {
 
 // GetIssue fetches an issue by number
 func (c *Client) GetIssue(number int) (*Issue, error) {
-	cmd := exec.Command("gh", "issue", "view", strconv.Itoa(number), "--json", "number,title,body,state,labels")
-	cmd.Dir = c.repoPath
-	var stdout bytes.Buffer
-	cmd.Stdout = &stdout
-
-	if err := cmd.Run(); err != nil {
+	out, err := c.run("issue", "view", strconv.Itoa(number), "--json", "number,title,body,state,labels")
+	if err != nil {
 		return nil, err
 	}
 
 	var issue Issue
-	if err := json.Unmarshal(stdout.Bytes(), &issue); err != nil {
+	if err := json.Unmarshal(out, &issue); err != nil {
 		return nil, err
 	}
 	return &issue, nil
@@ -88,18 +159,13 @@ func (c *Client) CreateIssue(title, body string, labels []string) (*CreateIssueR
 	for _, label := range labels {
 		args = append(args, "--label", label)
 	}
-	cmd := exec.Command("gh", args...)
-	cmd.Dir = c.repoPath
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
+	out, err := c.run(args...)
+	if err != nil {
 		return nil, err
 	}
 
 	var result CreateIssueResult
-	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+	if err := json.Unmarshal(out, &result); err != nil {
 		return nil, err
 	}
 	return &result, nil
@@ -107,7 +173,6 @@ func (c *Client) CreateIssue(title, body string, labels []string) (*CreateIssueR
 
 // UpdateIssue updates an existing issue
 func (c *Client) UpdateIssue(number int, body string) error {
-	cmd := exec.Command("gh", "issue", "edit", strconv.Itoa(number), "--body", body)
-	cmd.Dir = c.repoPath
-	return cmd.Run()
+	_, err := c.run("issue", "edit", strconv.Itoa(number), "--body", body)
+	return err
 }
@@ -0,0 +1,101 @@
+package git
+
+import (
+	"context"
+	"fmt"
+)
+
+// Backend answers read-only queries about a repository at path. Repo
+// uses it for everything that does not modify the repo. Implementations
+// stop early and return ctx.Err() once ctx is done.
+type Backend interface {
+	CurrentBranch(ctx context.Context, path string) (string, error)
+	IsDirty(ctx context.Context, path string) (bool, error)
+	Status(ctx context.Context, path string) (StatusCounts, error)
+	AheadBehind(ctx context.Context, path, ref, other string) (ahead, behind int, err error)
+	UnpushedCount(ctx context.Context, path, branch string) (int, error)
+	WorktreeList(ctx context.Context, path string) ([]WorktreeInfo, error)
+}
+
+// Backend names accepted by BackendByName
+const (
+	BackendExec   = "exec"
+	BackendNative = "native"
+)
+
+var defaultBackend Backend = ExecBackend{}
+
+// SetDefaultBackend sets the backend used by repos created with NewRepo
+func SetDefaultBackend(b Backend) {
+	defaultBackend = b
+}
+
+// BackendByName returns the backend for a git.backend setting
+func BackendByName(name string) (Backend, error) {
+	switch name {
+	case "", BackendExec:
+		return ExecBackend{}, nil
+	case BackendNative:
+		return NativeBackend{}, nil
+	}
+	return nil, fmt.Errorf("unknown git backend %q (want %s or %s)", name, BackendExec, BackendNative)
+}
+
+// ExecBackend runs the git binary for every query
+type ExecBackend struct{}
+
+func (ExecBackend) CurrentBranch(ctx context.Context, path string) (string, error) {
+	return runGit(ctx, path, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+func (ExecBackend) IsDirty(ctx context.Context, path string) (bool, error) {
+	// Without optional locks status doesn't rewrite the index, which
+	// would otherwise wake anything watching it
+	out, err := runGit(ctx, path, "--no-optional-locks", "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+func (ExecBackend) Status(ctx context.Context, path string) (StatusCounts, error) {
+	out, err := runGitRaw(ctx, path, "--no-optional-locks", "status", "--porcelain")
+	if err != nil {
+		return StatusCounts{}, err
+	}
+	return parseStatusCounts(out), nil
+}
+
+func (ExecBackend) AheadBehind(ctx context.Context, path, ref, other string) (int, int, error) {
+	out, err := runGit(ctx, path, "rev-list", "--left-right", "--count", ref+"..."+other)
+	if err != nil {
+		return 0, 0, err
+	}
+	var ahead, behind int
+	if _, err := fmt.Sscanf(out, "%d %d", &ahead, &behind); err != nil {
+		return 0, 0, fmt.Errorf("unexpected rev-list output %q", out)
+	}
+	return ahead, behind, nil
+}
+
+func (ExecBackend) UnpushedCount(ctx context.Context, path, branch string) (int, error) {
+	out, err := runGit(ctx, path, "rev-list", "--count", fmt.Sprintf("origin/%s..%s", branch, branch))
+	if ctx.Err() != nil {
+		return 0, err
+	}
+	if err != nil {
+		// Branch might not have upstream
+		return 0, nil
+	}
+	var count int
+	fmt.Sscanf(out, "%d", &count)
+	return count, nil
+}
+
+func (ExecBackend) WorktreeList(ctx context.Context, path string) ([]WorktreeInfo, error) {
+	out, err := runGit(ctx, path, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	return parseWorktreeList(out), nil
+}
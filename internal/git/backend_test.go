@@ -0,0 +1,120 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.email=t@t", "-c", "user.name=T"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// TestBackendsAgree checks NativeBackend against ExecBackend on the same repos
+func TestBackendsAgree(t *testing.T) {
+	dir := t.TempDir()
+	origin := filepath.Join(dir, "origin")
+	clone := filepath.Join(dir, "clone")
+	os.MkdirAll(origin, 0755)
+	gitRun(t, origin, "init", "--initial-branch=main")
+	gitRun(t, origin, "commit", "--allow-empty", "-m", "initial")
+	gitRun(t, dir, "clone", "--quiet", origin, clone)
+
+	// feature/x: two local commits plus a merge of main, then pushed once
+	gitRun(t, clone, "checkout", "-q", "-b", "feature/x")
+	gitRun(t, clone, "commit", "--allow-empty", "-m", "f1")
+	gitRun(t, clone, "push", "-q", "origin", "feature/x")
+	gitRun(t, clone, "commit", "--allow-empty", "-m", "f2")
+	gitRun(t, clone, "checkout", "-q", "main")
+	gitRun(t, clone, "commit", "--allow-empty", "-m", "m1")
+	gitRun(t, clone, "checkout", "-q", "feature/x")
+	gitRun(t, clone, "merge", "-q", "--no-edit", "main")
+
+	wt := filepath.Join(dir, "clone-wt")
+	gitRun(t, clone, "checkout", "-q", "main")
+	gitRun(t, clone, "worktree", "add", "-q", wt, "feature/x")
+	os.WriteFile(filepath.Join(wt, "new.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(wt, "staged.txt"), []byte("x"), 0644)
+	gitRun(t, wt, "add", "staged.txt")
+	os.WriteFile(filepath.Join(wt, "staged.txt"), []byte("y"), 0644)
+
+	// Make sure the native results below don't come from the exec fallback
+	repo, err := openNative(wt)
+	if err != nil {
+		t.Fatalf("go-git cannot open linked worktree: %v", err)
+	}
+	if w, err := repo.Worktree(); err != nil {
+		t.Fatal(err)
+	} else if _, err := w.Status(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	backends := []Backend{ExecBackend{}, NativeBackend{}}
+	var results [][]interface{}
+	for _, b := range backends {
+		mainBranch, err1 := b.CurrentBranch(ctx, clone)
+		wtBranch, err2 := b.CurrentBranch(ctx, wt)
+		cleanMain, err3 := b.IsDirty(ctx, clone)
+		dirtyWT, err4 := b.IsDirty(ctx, wt)
+		ahead, err5 := b.UnpushedCount(ctx, wt, "feature/x")
+		noUpstream, err6 := b.UnpushedCount(ctx, clone, "missing")
+		list, err7 := b.WorktreeList(ctx, wt)
+		status, err8 := b.Status(ctx, wt)
+		mainAhead, mainBehind, err9 := b.AheadBehind(ctx, clone, "main", "feature/x")
+		for _, err := range []error{err1, err2, err3, err4, err5, err6, err7, err8, err9} {
+			if err != nil {
+				t.Fatalf("%T: %v", b, err)
+			}
+		}
+		for i := range list {
+			list[i].Path, _ = filepath.EvalSymlinks(list[i].Path)
+		}
+		results = append(results, []interface{}{mainBranch, wtBranch, cleanMain, dirtyWT, ahead, noUpstream, list, status, mainAhead, mainBehind})
+	}
+
+	want := results[0]
+	if want[0] != "main" || want[1] != "feature/x" || want[2] != false || want[3] != true || want[4] != 3 || want[5] != 0 {
+		t.Fatalf("unexpected exec results: %v", want)
+	}
+	if want[7] != (StatusCounts{Staged: 1, Modified: 1, Untracked: 1}) || want[8] != 0 || want[9] != 3 {
+		t.Fatalf("unexpected exec results: %v", want)
+	}
+	if !reflect.DeepEqual(results[1], want) {
+		t.Errorf("native backend disagrees:\n native: %v\n exec:   %v", results[1], want)
+	}
+}
+
+func TestBackendByName(t *testing.T) {
+	if b, err := BackendByName("native"); err != nil || b != (NativeBackend{}) {
+		t.Errorf("expected NativeBackend, got %v, %v", b, err)
+	}
+	if b, err := BackendByName(""); err != nil || b != (ExecBackend{}) {
+		t.Errorf("expected ExecBackend by default, got %v, %v", b, err)
+	}
+	if _, err := BackendByName("libgit2"); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+// TestBackendsHonorContext checks that a cancelled context is reported
+// rather than mistaken for a branch without upstream
+func TestBackendsHonorContext(t *testing.T) {
+	repoPath := createTestRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, b := range []Backend{ExecBackend{}, NativeBackend{}} {
+		if _, err := b.UnpushedCount(ctx, repoPath, "main"); err == nil {
+			t.Errorf("%T: expected error for cancelled context", b)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package git
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// NativeBackend answers queries in-process with go-git instead of forking
+// git, which matters when the daemon polls dozens of worktrees. Anything
+// go-git cannot handle (unusual repo extensions, missing objects in
+// partial clones) falls back to ExecBackend.
+//
+// go-git does not read the global core.excludesFile, so files ignored only
+// there count as untracked and make IsDirty report true.
+//
+// go-git calls cannot be interrupted, so ctx is only checked between steps.
+type NativeBackend struct{}
+
+func openNative(path string) (*gogit.Repository, error) {
+	return gogit.PlainOpenWithOptions(path, &gogit.PlainOpenOptions{EnableDotGitCommonDir: true})
+}
+
+func (NativeBackend) CurrentBranch(ctx context.Context, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	repo, err := openNative(path)
+	if err != nil {
+		return ExecBackend{}.CurrentBranch(ctx, path)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return ExecBackend{}.CurrentBranch(ctx, path)
+	}
+	if !head.Name().IsBranch() {
+		return "HEAD", nil
+	}
+	return head.Name().Short(), nil
+}
+
+func (NativeBackend) IsDirty(ctx context.Context, path string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	repo, err := openNative(path)
+	if err != nil {
+		return ExecBackend{}.IsDirty(ctx, path)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return ExecBackend{}.IsDirty(ctx, path)
+	}
+	status, err := wt.Status()
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err != nil {
+		return ExecBackend{}.IsDirty(ctx, path)
+	}
+	return !status.IsClean(), nil
+}
+
+func (NativeBackend) UnpushedCount(ctx context.Context, path, branch string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	repo, err := openNative(path)
+	if err != nil {
+		return ExecBackend{}.UnpushedCount(ctx, path, branch)
+	}
+	local, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return 0, nil
+	}
+	remote, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err != nil {
+		// Branch might not have upstream
+		return 0, nil
+	}
+	count, err := countAhead(ctx, repo, local.Hash(), remote.Hash())
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if err != nil {
+		return ExecBackend{}.UnpushedCount(ctx, path, branch)
+	}
+	return count, nil
+}
+
+func (NativeBackend) Status(ctx context.Context, path string) (StatusCounts, error) {
+	if err := ctx.Err(); err != nil {
+		return StatusCounts{}, err
+	}
+	repo, err := openNative(path)
+	if err != nil {
+		return ExecBackend{}.Status(ctx, path)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return ExecBackend{}.Status(ctx, path)
+	}
+	status, err := wt.Status()
+	if ctx.Err() != nil {
+		return StatusCounts{}, ctx.Err()
+	}
+	if err != nil {
+		return ExecBackend{}.Status(ctx, path)
+	}
+
+	var c StatusCounts
+	for _, s := range status {
+		switch {
+		case s.Worktree == gogit.Untracked:
+			c.Untracked++
+		case s.Staging == gogit.UpdatedButUnmerged || s.Worktree == gogit.UpdatedButUnmerged:
+			c.Conflicted++
+		default:
+			if s.Staging != gogit.Unmodified {
+				c.Staged++
+			}
+			if s.Worktree != gogit.Unmodified {
+				c.Modified++
+			}
+		}
+	}
+	return c, nil
+}
+
+func (NativeBackend) AheadBehind(ctx context.Context, path, ref, other string) (int, int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	repo, err := openNative(path)
+	if err != nil {
+		return ExecBackend{}.AheadBehind(ctx, path, ref, other)
+	}
+	a, err1 := repo.ResolveRevision(plumbing.Revision(ref))
+	b, err2 := repo.ResolveRevision(plumbing.Revision(other))
+	if err1 != nil || err2 != nil {
+		return ExecBackend{}.AheadBehind(ctx, path, ref, other)
+	}
+	ahead, err1 := countAhead(ctx, repo, *a, *b)
+	behind, err2 := countAhead(ctx, repo, *b, *a)
+	if ctx.Err() != nil {
+		return 0, 0, ctx.Err()
+	}
+	if err1 != nil || err2 != nil {
+		return ExecBackend{}.AheadBehind(ctx, path, ref, other)
+	}
+	return ahead, behind, nil
+}
+
+// WorktreeList reads the worktree administrative files directly, in the
+// same order as `git worktree list`: the main worktree, then linked ones
+func (NativeBackend) WorktreeList(ctx context.Context, path string) ([]WorktreeInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	common, err := commonDir(path)
+	if err != nil {
+		return ExecBackend{}.WorktreeList(ctx, path)
+	}
+
+	repo, err := openNative(path)
+	if err != nil {
+		return ExecBackend{}.WorktreeList(ctx, path)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return ExecBackend{}.WorktreeList(ctx, path)
+	}
+
+	var worktrees []WorktreeInfo
+	if cfg.Core.IsBare {
+		worktrees = append(worktrees, WorktreeInfo{Path: common, Bare: true})
+	} else {
+		worktrees = append(worktrees, WorktreeInfo{
+			Path:   filepath.Dir(common),
+			Branch: headBranch(filepath.Join(common, "HEAD")),
+		})
+	}
+
+	entries, err := os.ReadDir(filepath.Join(common, "worktrees"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		admin := filepath.Join(common, "worktrees", e.Name())
+		gitdir, err := os.ReadFile(filepath.Join(admin, "gitdir"))
+		if err != nil {
+			continue
+		}
+		worktrees = append(worktrees, WorktreeInfo{
+			Path:   filepath.Dir(strings.TrimSpace(string(gitdir))),
+			Branch: headBranch(filepath.Join(admin, "HEAD")),
+		})
+	}
+	return worktrees, nil
+}
+
+// GitDir returns the absolute administrative directory of the worktree at
+// path, where its HEAD and index live: .git for a main worktree,
+// .git/worktrees/<name> in the main repo for a linked one
+func GitDir(path string) (string, error) {
+	dotGit := filepath.Join(path, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return filepath.Abs(dotGit)
+	}
+
+	// Linked worktree: .git is a file pointing at its admin directory
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", err
+	}
+	gitdir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", errors.New("malformed .git file")
+	}
+	gitdir = strings.TrimSpace(gitdir)
+	if !filepath.IsAbs(gitdir) {
+		gitdir = filepath.Join(path, gitdir)
+	}
+	return filepath.Abs(gitdir)
+}
+
+// commonDir returns the absolute git directory shared by all worktrees of
+// the repo containing path
+func commonDir(path string) (string, error) {
+	gitdir, err := GitDir(path)
+	if err != nil {
+		// path may itself be a bare repository
+		if _, herr := os.Stat(filepath.Join(path, "HEAD")); herr == nil {
+			return filepath.Abs(path)
+		}
+		return "", err
+	}
+
+	// A linked worktree's admin directory names the common dir in its
+	// commondir file
+	rel, err := os.ReadFile(filepath.Join(gitdir, "commondir"))
+	if os.IsNotExist(err) {
+		return gitdir, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	common := strings.TrimSpace(string(rel))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitdir, common)
+	}
+	return filepath.Abs(common)
+}
+
+// headBranch returns the branch a HEAD file points at, or "" if detached
+func headBranch(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimPrefix(ref, "refs/heads/")
+}
+
+// countAhead counts commits reachable from tip but not from base, like
+// `git rev-list --count base..tip`. Commits are visited newest first and
+// the walk stops once every pending commit is reachable from base, so only
+// the history since the branches diverged is read.
+func countAhead(ctx context.Context, repo *gogit.Repository, tip, base plumbing.Hash) (int, error) {
+	if tip == base {
+		return 0, nil
+	}
+
+	uninteresting := map[plumbing.Hash]bool{}
+	parents := map[plumbing.Hash][]plumbing.Hash{} // of commits already visited
+	seen := map[plumbing.Hash]bool{}
+	var candidates []plumbing.Hash
+	queue := &commitQueue{}
+
+	// exclude marks hash reachable from base, along with any ancestors
+	// already visited
+	var exclude func(hash plumbing.Hash)
+	exclude = func(hash plumbing.Hash) {
+		if uninteresting[hash] {
+			return
+		}
+		uninteresting[hash] = true
+		for _, p := range parents[hash] {
+			exclude(p)
+		}
+	}
+
+	push := func(hash plumbing.Hash, excluded bool) error {
+		if excluded {
+			exclude(hash)
+		}
+		if seen[hash] {
+			return nil
+		}
+		seen[hash] = true
+		c, err := repo.CommitObject(hash)
+		if err != nil {
+			return err
+		}
+		heap.Push(queue, c)
+		return nil
+	}
+	if err := push(base, true); err != nil {
+		return 0, err
+	}
+	if err := push(tip, false); err != nil {
+		return 0, err
+	}
+
+	for queue.Len() > 0 && !queue.allExcluded(uninteresting) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		c := heap.Pop(queue).(*object.Commit)
+		parents[c.Hash] = c.ParentHashes
+		excluded := uninteresting[c.Hash]
+		if !excluded {
+			candidates = append(candidates, c.Hash)
+		}
+		for _, parent := range c.ParentHashes {
+			if err := push(parent, excluded); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// A commit reached first from tip may later turn out to be reachable
+	// from base as well
+	count := 0
+	for _, h := range candidates {
+		if !uninteresting[h] {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// commitQueue is a max-heap of commits by committer time
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+func (q commitQueue) allExcluded(uninteresting map[plumbing.Hash]bool) bool {
+	for _, c := range q {
+		if !uninteresting[c.Hash] {
+			return false
+		}
+	}
+	return true
+}
@@ -2,59 +2,417 @@ package git
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// Repo wraps git operations for a repository
+// Repo wraps git operations for a repository. Read-only queries go
+// through a Backend; everything that modifies the repo runs git.
 type Repo struct {
-	path string
+	path    string
+	backend Backend
+	ctx     context.Context
+	timeout time.Duration // per call; 0 means no limit
 }
 
-// NewRepo creates a Repo for the given path
+// NewRepo creates a Repo for the given path using the default backend
 func NewRepo(path string) *Repo {
-	return &Repo{path: path}
+	return &Repo{path: path, backend: defaultBackend, ctx: context.Background()}
+}
+
+// WithTimeout returns a copy of r whose calls are cancelled when ctx is
+// done and each limited to timeout (0 means no limit)
+func (r *Repo) WithTimeout(ctx context.Context, timeout time.Duration) *Repo {
+	c := *r
+	c.ctx, c.timeout = ctx, timeout
+	return &c
+}
+
+// callContext returns the context for one call
+func (r *Repo) callContext() (context.Context, context.CancelFunc) {
+	if r.timeout > 0 {
+		return context.WithTimeout(r.ctx, r.timeout)
+	}
+	return context.WithCancel(r.ctx)
 }
 
 // run executes a git command in the repo directory
 func (r *Repo) run(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = r.path
+	ctx, cancel := r.callContext()
+	defer cancel()
+	return runGit(ctx, r.path, args...)
+}
+
+// runGit executes a git command in dir, killing it when ctx is done, and
+// returns its trimmed output
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := runGitRaw(ctx, dir, args...)
+	return strings.TrimSpace(out), err
+}
+
+// runGitRaw is runGit without trimming, for output where leading
+// whitespace matters. When git exits non-zero its output is still
+// returned alongside the error, which wraps the *exec.ExitError.
+func runGitRaw(ctx context.Context, dir string, args ...string) (string, error) {
+	return runGitEnv(ctx, dir, nil, args...)
+}
+
+// runGitEnv is runGitRaw with env added to the environment
+func runGitEnv(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("git %s: %w\n%s", strings.Join(args, " "), err, stderr.String())
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), ctx.Err())
+		}
+		return stdout.String(), fmt.Errorf("git %s: %w\n%s", strings.Join(args, " "), err, stderr.String())
 	}
-	return strings.TrimSpace(stdout.String()), nil
+	return stdout.String(), nil
 }
 
-// CurrentBranch returns the current branch name
+// CurrentBranch returns the current branch name, or HEAD when detached
 func (r *Repo) CurrentBranch() (string, error) {
-	return r.run("rev-parse", "--abbrev-ref", "HEAD")
+	ctx, cancel := r.callContext()
+	defer cancel()
+	return r.backend.CurrentBranch(ctx, r.path)
 }
 
-// IsDirty returns true if there are uncommitted changes
-func (r *Repo) IsDirty() (bool, error) {
-	out, err := r.run("status", "--porcelain")
+// DefaultBranch returns the default branch of origin. It reads the local
+// origin/HEAD ref and only asks the remote (git remote show origin) when
+// that ref is missing, e.g. in clones made with older git or --single-branch.
+func (r *Repo) DefaultBranch() (string, error) {
+	if out, err := r.run("symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		if branch := strings.TrimPrefix(out, "origin/"); branch != "" && branch != out {
+			return branch, nil
+		}
+	}
+
+	out, err := r.run("remote", "show", "origin")
 	if err != nil {
-		return false, err
+		return "", fmt.Errorf("failed to determine default branch: %w", err)
+	}
+	if branch := parseRemoteHEAD(out); branch != "" {
+		return branch, nil
 	}
-	return out != "", nil
+	return "", fmt.Errorf("failed to determine default branch: origin has no HEAD")
+}
+
+// parseRemoteHEAD extracts the branch from `git remote show` output
+func parseRemoteHEAD(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if branch, ok := strings.CutPrefix(line, "HEAD branch:"); ok {
+			branch = strings.TrimSpace(branch)
+			if branch == "(unknown)" {
+				return ""
+			}
+			return branch
+		}
+	}
+	return ""
+}
+
+// IsDirty returns true if there are uncommitted changes
+func (r *Repo) IsDirty() (bool, error) {
+	ctx, cancel := r.callContext()
+	defer cancel()
+	return r.backend.IsDirty(ctx, r.path)
 }
 
 // UnpushedCount returns the number of commits ahead of origin
 func (r *Repo) UnpushedCount(branch string) (int, error) {
-	out, err := r.run("rev-list", "--count", fmt.Sprintf("origin/%s..%s", branch, branch))
+	ctx, cancel := r.callContext()
+	defer cancel()
+	return r.backend.UnpushedCount(ctx, r.path, branch)
+}
+
+// Status counts changed paths by kind
+func (r *Repo) Status() (StatusCounts, error) {
+	ctx, cancel := r.callContext()
+	defer cancel()
+	return r.backend.Status(ctx, r.path)
+}
+
+// AheadBehind counts the commits reachable from ref but not other, and
+// from other but not ref
+func (r *Repo) AheadBehind(ref, other string) (ahead, behind int, err error) {
+	ctx, cancel := r.callContext()
+	defer cancel()
+	return r.backend.AheadBehind(ctx, r.path, ref, other)
+}
+
+// Upstream returns the remote-tracking branch for branch, e.g.
+// origin/feature. Without configured tracking it falls back to
+// origin/<branch>, and returns "" if that doesn't exist either.
+func (r *Repo) Upstream(branch string) (string, error) {
+	out, err := r.run("rev-parse", "--abbrev-ref", "--symbolic-full-name", branch+"@{upstream}")
+	if err == nil {
+		return out, nil
+	}
+	if isContextErr(err) {
+		return "", err
+	}
+	if _, err := r.run("rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch); err == nil {
+		return "origin/" + branch, nil
+	} else if isContextErr(err) {
+		return "", err
+	}
+	return "", nil
+}
+
+// StashCount returns the number of stash entries. The stash is shared by
+// all worktrees of a repository.
+func (r *Repo) StashCount() (int, error) {
+	common, err := commonDir(r.path)
 	if err != nil {
-		// Branch might not have upstream
+		return 0, err
+	}
+	data, err := os.ReadFile(filepath.Join(common, "logs", "refs", "stash"))
+	if os.IsNotExist(err) {
 		return 0, nil
 	}
-	var count int
-	fmt.Sscanf(out, "%d", &count)
-	return count, nil
+	if err != nil {
+		return 0, err
+	}
+	return strings.Count(strings.TrimSpace(string(data)), "\n") + 1, nil
+}
+
+// Exclude adds pattern to the repo's info/exclude file, shared by all its
+// worktrees, unless it is already there
+func (r *Repo) Exclude(pattern string) error {
+	common, err := commonDir(r.path)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(common, "info", "exclude")
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == pattern {
+			return nil
+		}
+	}
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	data = append(data, pattern+"\n"...)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Operation returns the operation in progress in the worktree: merge,
+// rebase, cherry-pick, revert or bisect, or "" if none
+func (r *Repo) Operation() (string, error) {
+	gitDir, err := GitDir(r.path)
+	if err != nil {
+		return "", err
+	}
+	markers := []struct{ file, op string }{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+		{"BISECT_LOG", "bisect"},
+	}
+	for _, m := range markers {
+		if _, err := os.Stat(filepath.Join(gitDir, m.file)); err == nil {
+			return m.op, nil
+		}
+	}
+	return "", nil
+}
+
+// IsAncestor reports whether ref is an ancestor of other, or the same
+// commit, so everything on ref is already in other
+func (r *Repo) IsAncestor(ref, other string) (bool, error) {
+	_, err := r.run("merge-base", "--is-ancestor", ref, other)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// IsMergedInto reports whether everything on branch is already in base,
+// however it got there. Merges keep branch's commits, so it is an ancestor;
+// rebase merges rewrite them but keep their patches, which git cherry
+// matches; squash merges make one commit of all of branch's changes, which
+// is matched by squashing branch the same way. Finally, if merging that
+// squash would leave base's tree unchanged, base already has the changes,
+// though later commits there may have touched them.
+func (r *Repo) IsMergedInto(branch, base string) (bool, error) {
+	if ok, err := r.IsAncestor(branch, base); err != nil || ok {
+		return ok, err
+	}
+
+	mergeBase, err := r.run("merge-base", base, branch)
+	if err != nil {
+		return false, err
+	}
+	// Commits that change nothing, such as empty ones, would match any
+	// base, but their absence from it is no sign of a merge
+	_, err = r.run("diff", "--quiet", mergeBase, branch)
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		return false, err
+	}
+
+	out, err := r.run("cherry", base, branch)
+	if err != nil {
+		return false, err
+	}
+	if !hasUnmergedPatches(out) {
+		return true, nil
+	}
+
+	squash, err := r.squashCommit(branch, mergeBase)
+	if err != nil {
+		return false, err
+	}
+	out, err = r.run("cherry", base, squash)
+	if err != nil {
+		return false, err
+	}
+	if !hasUnmergedPatches(out) {
+		return true, nil
+	}
+
+	baseTree, err := r.run("rev-parse", base+"^{tree}")
+	if err != nil {
+		return false, err
+	}
+	merged, err := r.run("merge-tree", "--write-tree", "--no-messages", base, squash)
+	if err != nil {
+		// Conflicts, or git older than 2.38: either way no proof
+		return false, nil
+	}
+	return firstLine(merged) == baseTree, nil
+}
+
+// squashCommit creates, without touching any branch, a commit on mergeBase
+// with branch's tree, as a squash merge would. Its identity and date are
+// fixed so repeated calls create no new objects.
+func (r *Repo) squashCommit(branch, mergeBase string) (string, error) {
+	ctx, cancel := r.callContext()
+	defer cancel()
+	env := []string{
+		"GIT_AUTHOR_NAME=bearing", "GIT_AUTHOR_EMAIL=bearing@localhost", "GIT_AUTHOR_DATE=2000-01-01T00:00:00Z",
+		"GIT_COMMITTER_NAME=bearing", "GIT_COMMITTER_EMAIL=bearing@localhost", "GIT_COMMITTER_DATE=2000-01-01T00:00:00Z",
+	}
+	out, err := runGitEnv(ctx, r.path, env, "commit-tree", branch+"^{tree}", "-p", mergeBase, "-m", "squash "+branch)
+	return strings.TrimSpace(out), err
+}
+
+// hasUnmergedPatches reports whether `git cherry` output lists a commit
+// whose change is not upstream, marked with +
+func hasUnmergedPatches(output string) bool {
+	for _, line := range splitLines(output) {
+		if strings.HasPrefix(line, "+") {
+			return true
+		}
+	}
+	return false
+}
+
+// firstLine returns output up to its first newline
+func firstLine(output string) string {
+	line, _, _ := strings.Cut(output, "\n")
+	return line
+}
+
+// CommitTime returns the committer date of ref
+func (r *Repo) CommitTime(ref string) (time.Time, error) {
+	out, err := r.run("log", "-1", "--format=%ct", ref, "--")
+	if err != nil {
+		return time.Time{}, err
+	}
+	sec, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected commit time %q", out)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// ChangedFiles returns the paths changed on ref since it diverged from
+// base, like `git diff --name-only base...ref`
+func (r *Repo) ChangedFiles(base, ref string) ([]string, error) {
+	out, err := r.run("diff", "--name-only", "--no-renames", base+"..."+ref)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+// MergeConflicts merges ours and theirs in memory with `git merge-tree`,
+// without touching any worktree, and returns the paths that would
+// conflict. Needs git 2.38 or later.
+func (r *Repo) MergeConflicts(ours, theirs string) ([]string, error) {
+	ctx, cancel := r.callContext()
+	defer cancel()
+	out, err := runGit(ctx, r.path, "merge-tree", "--write-tree", "--name-only", "--no-messages", ours, theirs)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && out != "" {
+		// Exit status 1 with output means conflicts: the tree ID is
+		// followed by one conflicted path per line. Bad arguments also
+		// exit 1, but print nothing to stdout.
+		return parseMergeTreeConflicts(out), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// parseMergeTreeConflicts reads the conflicted paths from `git merge-tree
+// --write-tree --name-only` output, dropping the leading tree ID
+func parseMergeTreeConflicts(output string) []string {
+	lines := splitLines(output)
+	if len(lines) == 0 {
+		return nil
+	}
+	var paths []string
+	seen := make(map[string]bool)
+	for _, p := range lines[1:] {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// splitLines splits output into its non-empty lines
+func splitLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// isContextErr reports whether err comes from a cancelled or expired call
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
 
 // WorktreeAdd creates a new worktree with optional start point
@@ -73,19 +431,22 @@ func (r *Repo) WorktreeAddExisting(path, branch string) error {
 	return err
 }
 
-// WorktreeRemove removes a worktree
-func (r *Repo) WorktreeRemove(path string) error {
-	_, err := r.run("worktree", "remove", path)
+// WorktreeRemove removes a worktree. git refuses a worktree with changes
+// unless force is set.
+func (r *Repo) WorktreeRemove(path string, force bool) error {
+	args := []string{"worktree", "remove", path}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err := r.run(args...)
 	return err
 }
 
 // WorktreeList lists all worktrees
 func (r *Repo) WorktreeList() ([]WorktreeInfo, error) {
-	out, err := r.run("worktree", "list", "--porcelain")
-	if err != nil {
-		return nil, err
-	}
-	return parseWorktreeList(out), nil
+	ctx, cancel := r.callContext()
+	defer cancel()
+	return r.backend.WorktreeList(ctx, r.path)
 }
 
 // BranchDelete deletes a branch
@@ -98,6 +459,23 @@ func (r *Repo) BranchDelete(branch string, force bool) error {
 	return err
 }
 
+// Tag creates a lightweight tag name pointing at ref
+func (r *Repo) Tag(name, ref string) error {
+	_, err := r.run("tag", name, ref)
+	return err
+}
+
+// Bundle writes ref and its history to a bundle file at path
+func (r *Repo) Bundle(path, ref string) error {
+	_, err := r.run("bundle", "create", path, ref)
+	return err
+}
+
+// RemoteURL returns the URL of the named remote
+func (r *Repo) RemoteURL(name string) (string, error) {
+	return r.run("remote", "get-url", name)
+}
+
 // Fetch fetches from origin
 func (r *Repo) Fetch() error {
 	_, err := r.run("fetch", "--prune")
@@ -130,6 +508,45 @@ func (r *Repo) ListRemoteBranches() ([]string, error) {
 	return branches, nil
 }
 
+// StatusCounts counts changed paths by kind. A path staged and then
+// modified again counts as both staged and modified.
+type StatusCounts struct {
+	Staged     int
+	Modified   int
+	Untracked  int
+	Conflicted int
+}
+
+// Dirty reports whether anything is changed
+func (c StatusCounts) Dirty() bool {
+	return c.Staged+c.Modified+c.Untracked+c.Conflicted > 0
+}
+
+// parseStatusCounts counts `git status --porcelain` lines by their XY code
+func parseStatusCounts(output string) StatusCounts {
+	var c StatusCounts
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 3 {
+			continue
+		}
+		x, y := line[0], line[1]
+		switch {
+		case x == '?' && y == '?':
+			c.Untracked++
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			c.Conflicted++
+		default:
+			if x != ' ' {
+				c.Staged++
+			}
+			if y != ' ' {
+				c.Modified++
+			}
+		}
+	}
+	return c
+}
+
 // WorktreeInfo contains information about a git worktree
 type WorktreeInfo struct {
 	Path   string
@@ -152,8 +569,7 @@ func parseWorktreeList(output string) []WorktreeInfo {
 		if strings.HasPrefix(line, "worktree ") {
 			current.Path = strings.TrimPrefix(line, "worktree ")
 		} else if strings.HasPrefix(line, "branch ") {
-			ref := strings.TrimPrefix(line, "branch ")
-			current.Branch = filepath.Base(ref)
+			current.Branch = strings.TrimPrefix(strings.TrimPrefix(line, "branch "), "refs/heads/")
 		} else if line == "bare" {
 			current.Bare = true
 		}
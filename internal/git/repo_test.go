@@ -1,10 +1,14 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func createTestRepo(t *testing.T) string {
@@ -99,8 +103,12 @@ func TestWorktreeAddRemove(t *testing.T) {
 		t.Errorf("expected 2 worktrees, got %d", len(worktrees))
 	}
 
-	// Remove worktree
-	if err := repo.WorktreeRemove(worktreePath); err != nil {
+	// Remove worktree; changes need force
+	os.WriteFile(filepath.Join(worktreePath, "wip.txt"), []byte("wip"), 0644)
+	if err := repo.WorktreeRemove(worktreePath, false); err == nil {
+		t.Fatal("expected WorktreeRemove to refuse a dirty worktree")
+	}
+	if err := repo.WorktreeRemove(worktreePath, true); err != nil {
 		t.Fatalf("WorktreeRemove failed: %v", err)
 	}
 
@@ -128,3 +136,326 @@ branch refs/heads/feature
 		t.Errorf("expected feature, got %s", worktrees[1].Branch)
 	}
 }
+
+func TestDefaultBranch(t *testing.T) {
+	dir := t.TempDir()
+	origin := filepath.Join(dir, "origin")
+	clone := filepath.Join(dir, "clone")
+
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	os.MkdirAll(origin, 0755)
+	run(origin, "init", "--initial-branch=develop")
+	run(origin, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "--allow-empty", "-m", "initial")
+	run(dir, "clone", "--quiet", origin, clone)
+
+	repo := NewRepo(clone)
+	branch, err := repo.DefaultBranch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch != "develop" {
+		t.Errorf("expected develop from origin/HEAD, got %s", branch)
+	}
+
+	// Without origin/HEAD the remote is asked
+	run(clone, "remote", "set-head", "origin", "--delete")
+	if branch, err := repo.DefaultBranch(); err != nil || branch != "develop" {
+		t.Errorf("expected develop from remote show, got %q, %v", branch, err)
+	}
+
+	// No origin at all
+	if _, err := NewRepo(createTestRepo(t)).DefaultBranch(); err == nil {
+		t.Error("expected error for repo without origin")
+	}
+}
+
+func TestParseRemoteHEAD(t *testing.T) {
+	out := `* remote origin
+  Fetch URL: git@github.com:acme/app.git
+  HEAD branch: trunk
+  Remote branches:
+    trunk tracked`
+	if got := parseRemoteHEAD(out); got != "trunk" {
+		t.Errorf("expected trunk, got %q", got)
+	}
+	if got := parseRemoteHEAD("  HEAD branch: (unknown)"); got != "" {
+		t.Errorf("expected empty for unknown HEAD, got %q", got)
+	}
+}
+
+func TestRepoTimeout(t *testing.T) {
+	repoPath := createTestRepo(t)
+	repo := NewRepo(repoPath).WithTimeout(context.Background(), time.Nanosecond)
+
+	_, err := repo.CurrentBranch()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	// The original repo is unaffected
+	if _, err := NewRepo(repoPath).CurrentBranch(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseStatusCounts(t *testing.T) {
+	out := "M  staged.go\n M modified.go\nMM both.go\n?? new.go\nUU conflict.go\nAA added-twice.go\nR  old.go -> new.go\n"
+	want := StatusCounts{Staged: 3, Modified: 2, Untracked: 1, Conflicted: 2}
+	if got := parseStatusCounts(out); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestRepoStashAndOperation(t *testing.T) {
+	repoPath := createTestRepo(t)
+	repo := NewRepo(repoPath)
+	gitRun(t, repoPath, "checkout", "-q", "-b", "side")
+	os.WriteFile(filepath.Join(repoPath, "f.txt"), []byte("side\n"), 0644)
+	gitRun(t, repoPath, "add", "f.txt")
+	gitRun(t, repoPath, "commit", "-q", "-m", "side")
+	gitRun(t, repoPath, "checkout", "-q", "main")
+	os.WriteFile(filepath.Join(repoPath, "f.txt"), []byte("main\n"), 0644)
+	gitRun(t, repoPath, "add", "f.txt")
+	gitRun(t, repoPath, "commit", "-q", "-m", "main")
+
+	if n, err := repo.StashCount(); err != nil || n != 0 {
+		t.Errorf("expected no stashes, got %d, %v", n, err)
+	}
+	for i := 0; i < 2; i++ {
+		os.WriteFile(filepath.Join(repoPath, "f.txt"), []byte{byte('a' + i)}, 0644)
+		gitRun(t, repoPath, "stash", "-q")
+	}
+	if n, err := repo.StashCount(); err != nil || n != 2 {
+		t.Errorf("expected 2 stashes, got %d, %v", n, err)
+	}
+
+	if op, err := repo.Operation(); err != nil || op != "" {
+		t.Errorf("expected no operation, got %q, %v", op, err)
+	}
+	// The merge conflicts, leaving MERGE_HEAD behind
+	exec.Command("git", "-C", repoPath, "merge", "-q", "side").Run()
+	if op, err := repo.Operation(); err != nil || op != "merge" {
+		t.Errorf("expected merge in progress, got %q, %v", op, err)
+	}
+	if c, err := repo.Status(); err != nil || c.Conflicted != 1 {
+		t.Errorf("expected one conflicted path, got %+v, %v", c, err)
+	}
+
+	// Without a remote there is no upstream to fall back to
+	if up, err := repo.Upstream("main"); err != nil || up != "" {
+		t.Errorf("expected no upstream, got %q, %v", up, err)
+	}
+}
+
+func TestUpstream(t *testing.T) {
+	origin := createTestRepo(t)
+	gitRun(t, origin, "branch", "pushed")
+	clone := filepath.Join(t.TempDir(), "clone")
+	gitRun(t, origin, "clone", "-q", origin, clone)
+	gitRun(t, clone, "branch", "--no-track", "pushed", "origin/pushed")
+	gitRun(t, clone, "branch", "local-only")
+
+	repo := NewRepo(clone)
+	for branch, want := range map[string]string{
+		"main":       "origin/main",   // tracking configured by clone
+		"pushed":     "origin/pushed", // no tracking, same name on origin
+		"local-only": "",
+	} {
+		if got, err := repo.Upstream(branch); err != nil || got != want {
+			t.Errorf("Upstream(%s) = %q, %v; want %q", branch, got, err, want)
+		}
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	dir := createTestRepo(t)
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("shared.txt", "one\n")
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "-q", "-m", "shared")
+
+	gitRun(t, dir, "checkout", "-q", "-b", "a")
+	write("shared.txt", "from a\n")
+	write("a.txt", "a\n")
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "-q", "-m", "a")
+
+	gitRun(t, dir, "checkout", "-q", "-b", "b", "main")
+	write("shared.txt", "from b\n")
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "-q", "-m", "b")
+
+	gitRun(t, dir, "checkout", "-q", "-b", "c", "main")
+	write("c.txt", "c\n")
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "-q", "-m", "c")
+
+	repo := NewRepo(dir)
+	changed, err := repo.ChangedFiles("b", "a")
+	if err != nil || len(changed) != 2 || changed[0] != "a.txt" || changed[1] != "shared.txt" {
+		t.Errorf("ChangedFiles(b, a) = %v, %v", changed, err)
+	}
+
+	conflicts, err := repo.MergeConflicts("a", "b")
+	if err != nil || len(conflicts) != 1 || conflicts[0] != "shared.txt" {
+		t.Errorf("MergeConflicts(a, b) = %v, %v; want [shared.txt]", conflicts, err)
+	}
+	conflicts, err = repo.MergeConflicts("a", "c")
+	if err != nil || len(conflicts) != 0 {
+		t.Errorf("MergeConflicts(a, c) = %v, %v; want none", conflicts, err)
+	}
+	if _, err := repo.MergeConflicts("a", "missing"); err == nil {
+		t.Error("expected error for unknown branch")
+	}
+}
+
+func TestParseMergeTreeConflicts(t *testing.T) {
+	output := "4b825dc642cb6eb9a060e54bf8d69288fbee4904\nshared.txt\nshared.txt\nother.txt\n"
+	got := parseMergeTreeConflicts(output)
+	if len(got) != 2 || got[0] != "shared.txt" || got[1] != "other.txt" {
+		t.Errorf("parseMergeTreeConflicts = %v", got)
+	}
+}
+
+func TestExclude(t *testing.T) {
+	repoPath := createTestRepo(t)
+	worktreePath := filepath.Join(filepath.Dir(repoPath), "worktree-feature")
+	if err := NewRepo(repoPath).WorktreeAdd(worktreePath, "feature", ""); err != nil {
+		t.Fatal(err)
+	}
+	wt := NewRepo(worktreePath)
+
+	// Excluding from a linked worktree writes the shared file, once
+	for range 2 {
+		if err := wt.Exclude(".env.bearing"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(repoPath, ".git", "info", "exclude"))
+	if strings.Count(string(data), ".env.bearing\n") != 1 {
+		t.Errorf("expected pattern added once, got %q", data)
+	}
+
+	os.WriteFile(filepath.Join(worktreePath, ".env.bearing"), []byte("PORT=1\n"), 0644)
+	if dirty, _ := wt.IsDirty(); dirty {
+		t.Error("expected excluded file to be ignored")
+	}
+}
+
+func TestIsAncestorAndCommitTime(t *testing.T) {
+	repoPath := createTestRepo(t)
+	repo := NewRepo(repoPath)
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("branch", "fresh")
+	run("checkout", "-q", "-b", "feature")
+	run("commit", "--allow-empty", "-q", "-m", "work")
+
+	if ok, err := repo.IsAncestor("fresh", "main"); err != nil || !ok {
+		t.Errorf("expected fresh in main, got %v, %v", ok, err)
+	}
+	if ok, err := repo.IsAncestor("feature", "main"); err != nil || ok {
+		t.Errorf("expected feature not in main, got %v, %v", ok, err)
+	}
+	if _, err := repo.IsAncestor("missing", "main"); err == nil {
+		t.Error("expected error for a missing ref")
+	}
+
+	when, err := repo.CommitTime("feature")
+	if err != nil || time.Since(when) > time.Minute {
+		t.Errorf("expected a recent commit time, got %v, %v", when, err)
+	}
+}
+
+func TestIsMergedInto(t *testing.T) {
+	repoPath := createTestRepo(t)
+	repo := NewRepo(repoPath)
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0644)
+		run("add", name)
+		run("commit", "-q", "-m", name)
+	}
+	// branch makes two commits on a new branch off main
+	branch := func(name string) {
+		t.Helper()
+		run("checkout", "-q", "-b", name, "main")
+		write(name+"-1.txt", "one\n")
+		write(name+"-2.txt", "two\n")
+		run("checkout", "-q", "main")
+	}
+
+	branch("merged")
+	run("merge", "-q", "--no-ff", "--no-edit", "merged")
+
+	branch("rebased")
+	write("other.txt", "other\n")
+	run("cherry-pick", "main..rebased")
+
+	branch("squashed")
+	run("merge", "-q", "--squash", "squashed")
+	run("commit", "-q", "-m", "squashed")
+
+	// Squashed together with another change, so no patch matches
+	branch("amended")
+	run("merge", "-q", "--squash", "amended")
+	os.WriteFile(filepath.Join(repoPath, "extra.txt"), []byte("extra\n"), 0644)
+	run("add", "extra.txt")
+	run("commit", "-q", "-m", "amended")
+
+	branch("open")
+
+	run("checkout", "-q", "-b", "partial", "main")
+	write("partial-1.txt", "one\n")
+	run("checkout", "-q", "main")
+	run("cherry-pick", "partial")
+	run("checkout", "-q", "partial")
+	write("partial-2.txt", "two\n")
+	run("checkout", "-q", "main")
+
+	run("checkout", "-q", "-b", "empty", "main")
+	run("commit", "--allow-empty", "-q", "-m", "empty")
+	run("checkout", "-q", "main")
+
+	for name, want := range map[string]bool{
+		"merged":   true,
+		"rebased":  true,
+		"squashed": true,
+		"amended":  true,
+		"open":     false,
+		"partial":  false,
+		"empty":    false,
+	} {
+		if got, err := repo.IsMergedInto(name, "main"); err != nil || got != want {
+			t.Errorf("IsMergedInto(%s) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := repo.IsMergedInto("missing", "main"); err == nil {
+		t.Error("expected error for a missing branch")
+	}
+}
@@ -0,0 +1,145 @@
+// Package guard decides whether an agent's tool call is safe to run.
+//
+// Base folders must stay on their base branch with no local work, so the
+// pre-tool-use hook asks guard about each Bash command or file edit. Bash
+// commands are split on shell operators, following cd and git -C to find
+// the directory each git command runs in; file edits are checked by path.
+// Each risky action that lands in a base folder is matched against a rule,
+// and the policy sets whether that rule allows, asks or denies.
+package guard
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/joshribakoff/bearing/internal/config"
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	"github.com/joshribakoff/bearing/internal/session"
+)
+
+// Actions a policy can take, from least to most strict
+const (
+	Allow = "allow"
+	Ask   = "ask"
+	Deny  = "deny"
+)
+
+// Rules, named as in the guard config section
+const (
+	RuleBranchSwitch = "branchSwitch"
+	RuleResetHard    = "resetHard"
+	RuleCommit       = "commit"
+	RuleWrite        = "write"
+)
+
+// Policy maps each rule to the action taken when it matches
+type Policy map[string]string
+
+// PolicyFrom returns the policy set in config
+func PolicyFrom(c config.GuardConfig) Policy {
+	return Policy{
+		RuleBranchSwitch: c.BranchSwitch,
+		RuleResetHard:    c.ResetHard,
+		RuleCommit:       c.Commit,
+		RuleWrite:        c.Write,
+	}
+}
+
+// action returns the action for rule; unknown rules are denied
+func (p Policy) action(rule string) string {
+	if a, ok := p[rule]; ok && a != "" {
+		return a
+	}
+	return Deny
+}
+
+// Call is a tool call an agent is about to make
+type Call struct {
+	Tool    string // e.g. Bash, Write or Edit
+	Cwd     string // the agent's working directory
+	Command string // for Bash
+	Path    string // for file edits
+}
+
+// Decision is the outcome of one rule matching a call
+type Decision struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Folder string `json:"folder"`
+	Reason string `json:"reason"`
+}
+
+// writeTools are the tools that edit files, by name
+var writeTools = map[string]bool{
+	"Write":        true,
+	"Edit":         true,
+	"MultiEdit":    true,
+	"NotebookEdit": true,
+}
+
+// Evaluate returns a decision for each risky action in call that lands in
+// a base folder of the workspace. Folders missing from local are not
+// checked.
+func Evaluate(workspaceDir string, local []jsonl.LocalEntry, policy Policy, call Call) []Decision {
+	bases := make(map[string]jsonl.LocalEntry)
+	for _, e := range local {
+		if e.Base {
+			bases[e.Folder] = e
+		}
+	}
+
+	var decisions []Decision
+	decide := func(rule, dir, what string) {
+		e, ok := bases[session.Folder(workspaceDir, dir)]
+		if !ok {
+			return
+		}
+		decisions = append(decisions, Decision{
+			Rule:   rule,
+			Action: policy.action(rule),
+			Folder: e.Folder,
+			Reason: reason(rule, e, what),
+		})
+	}
+
+	switch {
+	case call.Tool == "Bash":
+		for _, a := range GitActions(call.Command, call.Cwd) {
+			decide(a.Rule, a.Dir, a.Command)
+		}
+	case writeTools[call.Tool] && call.Path != "":
+		path := call.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(call.Cwd, path)
+		}
+		decide(RuleWrite, path, path)
+	}
+	return decisions
+}
+
+// Strictest returns the decision with the strictest action, or nil
+func Strictest(decisions []Decision) *Decision {
+	rank := map[string]int{Allow: 0, Ask: 1, Deny: 2}
+	var strictest *Decision
+	for i := range decisions {
+		if strictest == nil || rank[decisions[i].Action] > rank[strictest.Action] {
+			strictest = &decisions[i]
+		}
+	}
+	return strictest
+}
+
+func reason(rule string, e jsonl.LocalEntry, what string) string {
+	fix := fmt.Sprintf("Work in a worktree instead: bearing worktree new %s <branch>", e.Repo)
+	switch rule {
+	case RuleBranchSwitch:
+		return fmt.Sprintf("%s is a base folder and must stay on %s; `%s` would switch it. %s", e.Folder, e.Branch, what, fix)
+	case RuleResetHard:
+		return fmt.Sprintf("`%s` would discard changes in base folder %s. %s", what, e.Folder, fix)
+	case RuleCommit:
+		return fmt.Sprintf("`%s` would add commits to %s in base folder %s. %s", what, e.Branch, e.Folder, fix)
+	case RuleWrite:
+		return fmt.Sprintf("%s is in base folder %s, which must stay clean. %s", what, e.Folder, fix)
+	}
+	return fmt.Sprintf("%s in base folder %s is not allowed", what, e.Folder)
+}
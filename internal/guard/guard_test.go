@@ -0,0 +1,83 @@
+package guard
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+func TestGitActions(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []GitAction
+	}{
+		{"git status", nil},
+		{"git checkout main", []GitAction{{RuleBranchSwitch, "/w/app", "git checkout main"}}},
+		{"git checkout -b feature", []GitAction{{RuleBranchSwitch, "/w/app", "git checkout -b feature"}}},
+		{"git checkout -- file.go", nil},
+		{"git checkout .", nil},
+		{"git switch -c x", []GitAction{{RuleBranchSwitch, "/w/app", "git switch -c x"}}},
+		{"git reset HEAD~1", nil},
+		{"git reset --hard origin/main", []GitAction{{RuleResetHard, "/w/app", "git reset --hard origin/main"}}},
+		{`git commit -m "fix it; now"`, []GitAction{{RuleCommit, "/w/app", "git commit -m fix it; now"}}},
+		{"git merge --ff-only origin/main", nil},
+		{"git merge --abort", nil},
+		{"cd ../app-feat && git commit -am x", []GitAction{{RuleCommit, "/w/app-feat", "git commit -am x"}}},
+		{"git -C /w/other checkout dev", []GitAction{{RuleBranchSwitch, "/w/other", "git checkout dev"}}},
+		{"GIT_EDITOR=true git -c core.x=1 revert HEAD", []GitAction{{RuleCommit, "/w/app", "git revert HEAD"}}},
+		{"echo 'git commit' | cat", nil},
+		{"make test || (cd sub; git switch dev)", []GitAction{{RuleBranchSwitch, "/w/app/sub", "git switch dev"}}},
+	}
+	for _, tt := range tests {
+		if got := GitActions(tt.command, "/w/app"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GitActions(%q) = %+v, want %+v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	local := []jsonl.LocalEntry{
+		{Folder: "app", Repo: "app", Branch: "main", Base: true},
+		{Folder: "app-feat", Repo: "app", Branch: "feat"},
+	}
+	policy := Policy{RuleBranchSwitch: Deny, RuleResetHard: Ask, RuleCommit: Deny, RuleWrite: Allow}
+
+	tests := []struct {
+		name string
+		call Call
+		want []string // rule:action of each decision
+	}{
+		{"switch in base", Call{Tool: "Bash", Cwd: "/w/app", Command: "git checkout dev"}, []string{"branchSwitch:deny"}},
+		{"switch in worktree", Call{Tool: "Bash", Cwd: "/w/app-feat", Command: "git checkout dev"}, nil},
+		{"reset from subdir", Call{Tool: "Bash", Cwd: "/w/app/src", Command: "git reset --hard"}, []string{"resetHard:ask"}},
+		{"commit via cd", Call{Tool: "Bash", Cwd: "/w/app-feat", Command: "cd ../app && git commit -m x"}, []string{"commit:deny"}},
+		{"unregistered folder", Call{Tool: "Bash", Cwd: "/w/other", Command: "git commit"}, nil},
+		{"write in base", Call{Tool: "Edit", Cwd: "/w/app-feat", Path: "/w/app/main.go"}, []string{"write:allow"}},
+		{"relative write", Call{Tool: "Write", Cwd: "/w/app", Path: "main.go"}, []string{"write:allow"}},
+		{"write in worktree", Call{Tool: "Write", Cwd: "/w/app", Path: "/w/app-feat/main.go"}, nil},
+		{"read in base", Call{Tool: "Read", Cwd: "/w/app", Path: "/w/app/main.go"}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, d := range Evaluate("/w", local, policy, tt.call) {
+			got = append(got, d.Rule+":"+d.Action)
+			if d.Folder != "app" || d.Reason == "" {
+				t.Errorf("%s: unexpected decision %+v", tt.name, d)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStrictest(t *testing.T) {
+	if Strictest(nil) != nil {
+		t.Error("expected nil for no decisions")
+	}
+	d := Strictest([]Decision{{Rule: "a", Action: Allow}, {Rule: "b", Action: Deny}, {Rule: "c", Action: Ask}})
+	if d == nil || d.Rule != "b" {
+		t.Errorf("expected the deny decision, got %+v", d)
+	}
+}
@@ -0,0 +1,206 @@
+package guard
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// GitAction is a risky git command found in a shell command line
+type GitAction struct {
+	Rule    string
+	Dir     string // where the command runs
+	Command string // the git command, re-joined
+}
+
+// GitActions returns the risky git commands in a shell command line run
+// from cwd. Only the subset of shell syntax agents commonly use is
+// understood: quoting, command separators, variable assignments and cd.
+func GitActions(command, cwd string) []GitAction {
+	var actions []GitAction
+	dir := cwd
+	for _, words := range splitCommands(command) {
+		words = stripPrefixes(words)
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "cd", "pushd":
+			if len(words) > 1 && !strings.HasPrefix(words[1], "-") && !strings.HasPrefix(words[1], "~") {
+				dir = resolve(dir, words[1])
+			}
+		case "git":
+			if a, ok := gitAction(words[1:], dir); ok {
+				actions = append(actions, a)
+			}
+		}
+	}
+	return actions
+}
+
+// gitAction classifies the arguments of one git invocation
+func gitAction(args []string, dir string) (GitAction, bool) {
+	// Global options come before the subcommand
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		opt := args[0]
+		args = args[1:]
+		switch opt {
+		case "-C":
+			if len(args) > 0 {
+				dir = resolve(dir, args[0])
+				args = args[1:]
+			}
+		case "-c", "--git-dir", "--work-tree", "--namespace":
+			if len(args) > 0 {
+				args = args[1:]
+			}
+		}
+	}
+	if len(args) == 0 {
+		return GitAction{}, false
+	}
+
+	sub, rest := args[0], args[1:]
+	rule := ""
+	switch sub {
+	case "checkout":
+		if switchesBranch(rest) {
+			rule = RuleBranchSwitch
+		}
+	case "switch":
+		if !has(rest, "-h", "--help") {
+			rule = RuleBranchSwitch
+		}
+	case "reset":
+		if has(rest, "--hard") {
+			rule = RuleResetHard
+		}
+	case "commit", "cherry-pick", "revert", "am":
+		if !has(rest, "--abort", "--quit", "--skip") {
+			rule = RuleCommit
+		}
+	case "merge":
+		if !has(rest, "--abort", "--quit", "--ff-only") {
+			rule = RuleCommit
+		}
+	}
+	if rule == "" {
+		return GitAction{}, false
+	}
+	return GitAction{Rule: rule, Dir: dir, Command: strings.Join(append([]string{"git"}, args...), " ")}, true
+}
+
+// switchesBranch reports whether git checkout with args moves HEAD, as
+// opposed to restoring files
+func switchesBranch(args []string) bool {
+	if has(args, "--", "-p", "--patch", "-h", "--help") {
+		return false
+	}
+	if has(args, "-b", "-B", "--orphan", "--detach") {
+		return true
+	}
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			return a != "."
+		}
+	}
+	return false
+}
+
+// has reports whether args contains any of flags
+func has(args []string, flags ...string) bool {
+	for _, a := range args {
+		for _, f := range flags {
+			if a == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve returns path relative to dir, unless it is absolute
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, path)
+}
+
+// stripPrefixes drops leading variable assignments and wrappers such as
+// env or sudo that run the rest of the words as a command
+func stripPrefixes(words []string) []string {
+	for len(words) > 0 {
+		w := words[0]
+		switch {
+		case w == "env" || w == "sudo" || w == "command" || w == "exec" || w == "time" || w == "nohup":
+			words = words[1:]
+		case strings.Contains(w, "=") && !strings.HasPrefix(w, "-") && !strings.HasPrefix(w, "="):
+			words = words[1:]
+		default:
+			return words
+		}
+	}
+	return words
+}
+
+// splitCommands splits a command line into the words of each simple
+// command, honoring quotes and backslashes and breaking on ; & | newlines
+// and parentheses
+func splitCommands(line string) [][]string {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i++
+			if line[i] != '\n' {
+				word.WriteByte(line[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				end = len(line) - i - 1
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		case c == '"':
+			inWord = true
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`", line[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(line[i])
+			}
+		case c == ' ' || c == '\t':
+			endWord()
+		case strings.IndexByte(";&|\n()", c) >= 0:
+			endCommand()
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands
+}
@@ -0,0 +1,149 @@
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Full rewrites never touch the live file in place. writeAtomic streams the
+// new contents to <path>.tmp, fsyncs it, hard-links the current file to
+// <path>.bak and renames the temp file over <path>. Readers that ignore the
+// .lock file (the Python TUI) therefore see either the old or the new file,
+// never a truncated one. A crash can leave .tmp or .bak behind; the next
+// locked read or write calls recoverJSONL to clean up.
+
+func tmpPath(path string) string { return path + ".tmp" }
+func bakPath(path string) string { return path + ".bak" }
+
+// writeAtomic replaces path with the output of write. Caller holds the
+// exclusive lock.
+func writeAtomic(path string, write func(w io.Writer) error) error {
+	tmp := tmpPath(path)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(f)
+	err = write(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Keep the previous version reachable until the rename lands. Hard links
+	// are unsupported on some filesystems; the rename alone is still atomic.
+	bak := bakPath(path)
+	os.Remove(bak)
+	hasBak := os.Link(path, bak) == nil
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		if hasBak {
+			os.Remove(bak)
+		}
+		return err
+	}
+	syncDir(filepath.Dir(path))
+
+	if hasBak {
+		os.Remove(bak)
+	}
+	return nil
+}
+
+// hasLeftovers reports whether an interrupted write left files behind
+func hasLeftovers(path string) bool {
+	if _, err := os.Stat(tmpPath(path)); err == nil {
+		return true
+	}
+	_, err := os.Stat(bakPath(path))
+	return err == nil
+}
+
+// recoverJSONL restores path after an interrupted writeAtomic and removes
+// leftover temp and backup files. Caller holds the exclusive lock.
+//
+// While <path> exists it is always a complete file, because it is only ever
+// replaced by rename, so leftovers are discarded. If <path> is missing the
+// backup is restored, or failing that a temp file that parses completely.
+func recoverJSONL(path string) error {
+	tmp, bak := tmpPath(path), bakPath(path)
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(bak); err == nil {
+			if err := os.Rename(bak, path); err != nil {
+				return err
+			}
+		} else if isCompleteJSONL(tmp) {
+			if err := os.Rename(tmp, path); err != nil {
+				return err
+			}
+		}
+		syncDir(filepath.Dir(path))
+	}
+
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(bak); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// isCompleteJSONL reports whether path is newline-terminated and every
+// non-empty line is valid JSON
+func isCompleteJSONL(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 || data[len(data)-1] != '\n' {
+		return false
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) > 0 && !json.Valid(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// terminateLastLine appends a newline if f does not already end with one
+func terminateLastLine(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = f.Write([]byte("\n"))
+	return err
+}
+
+// syncDir fsyncs a directory so a rename inside it is durable. Errors are
+// ignored: not every filesystem supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
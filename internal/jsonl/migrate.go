@@ -0,0 +1,351 @@
+package jsonl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Every record carries a "v" field with the schema version of its file.
+// Records written before versioning have no "v" and are version 0. Reads
+// upgrade old records in memory; `bearing migrate` rewrites them on disk.
+// Records from a newer bearing are read as-is.
+
+// Record is a raw JSONL record keyed by field name
+type Record map[string]json.RawMessage
+
+// Migration upgrades a record by one version
+type Migration struct {
+	Description string
+	Apply       func(rec Record) error // nil only stamps the new version
+}
+
+// Schema describes the record format of one JSONL file
+type Schema struct {
+	File       string
+	Migrations []Migration // Migrations[i] upgrades version i to i+1
+}
+
+// Version returns the current schema version
+func (s Schema) Version() int {
+	return len(s.Migrations)
+}
+
+var (
+	WorkflowSchema = Schema{
+		File: "workflow.jsonl",
+		Migrations: []Migration{{
+			Description: `add "v"; drop "unknown" basedOn and unparseable created written by the bash scripts`,
+			Apply: func(rec Record) error {
+				if stringField(rec, "basedOn") == "unknown" {
+					delete(rec, "basedOn")
+				}
+				dropInvalidTime(rec, "created")
+				return nil
+			},
+		}},
+	}
+
+	LocalSchema = Schema{
+		File:       "local.jsonl",
+		Migrations: []Migration{{Description: `add "v"`}},
+	}
+
+	HealthSchema = Schema{
+		File: "health.jsonl",
+		Migrations: []Migration{{
+			Description: `add "v"; drop unparseable lastCheck`,
+			Apply: func(rec Record) error {
+				dropInvalidTime(rec, "lastCheck")
+				return nil
+			},
+		}},
+	}
+
+	ProjectSchema = Schema{
+		File:       "projects.jsonl",
+		Migrations: []Migration{{Description: `add "v"`}},
+	}
+
+	LeaseSchema = Schema{
+		File:       "leases.jsonl",
+		Migrations: []Migration{{Description: `add "v"`}},
+	}
+
+	SessionSchema = Schema{
+		File:       "sessions.jsonl",
+		Migrations: []Migration{{Description: `add "v"`}},
+	}
+
+	AuditSchema = Schema{
+		File:       "audit.jsonl",
+		Migrations: []Migration{{Description: `add "v"`}},
+	}
+
+	AllocationSchema = Schema{
+		File:       "allocations.jsonl",
+		Migrations: []Migration{{Description: `add "v"`}},
+	}
+)
+
+// registry holds the schema of every versioned file
+var registry []Schema
+
+// RegisterSchema adds or replaces a schema in the migration registry
+func RegisterSchema(s Schema) {
+	for i := range registry {
+		if registry[i].File == s.File {
+			registry[i] = s
+			return
+		}
+	}
+	registry = append(registry, s)
+}
+
+func init() {
+	RegisterSchema(WorkflowSchema)
+	RegisterSchema(LocalSchema)
+	RegisterSchema(HealthSchema)
+	RegisterSchema(ProjectSchema)
+	RegisterSchema(LeaseSchema)
+	RegisterSchema(SessionSchema)
+	RegisterSchema(AuditSchema)
+	RegisterSchema(AllocationSchema)
+}
+
+// Schemas returns all registered schemas in registration order
+func Schemas() []Schema {
+	return append([]Schema(nil), registry...)
+}
+
+func schemaFor(path string) (Schema, bool) {
+	name := filepath.Base(path)
+	for _, s := range registry {
+		if s.File == name {
+			return s, true
+		}
+	}
+	return Schema{}, false
+}
+
+// versioned is implemented by record types that carry a "v" field
+type versioned interface {
+	schemaVersion() int
+	setSchemaVersion(v int)
+}
+
+func (e *WorkflowEntry) schemaVersion() int       { return e.V }
+func (e *WorkflowEntry) setSchemaVersion(v int)   { e.V = v }
+func (e *LocalEntry) schemaVersion() int          { return e.V }
+func (e *LocalEntry) setSchemaVersion(v int)      { e.V = v }
+func (e *HealthEntry) schemaVersion() int         { return e.V }
+func (e *HealthEntry) setSchemaVersion(v int)     { e.V = v }
+func (e *ProjectEntry) schemaVersion() int        { return e.V }
+func (e *ProjectEntry) setSchemaVersion(v int)    { e.V = v }
+func (e *LeaseEntry) schemaVersion() int          { return e.V }
+func (e *LeaseEntry) setSchemaVersion(v int)      { e.V = v }
+func (e *SessionEntry) schemaVersion() int        { return e.V }
+func (e *SessionEntry) setSchemaVersion(v int)    { e.V = v }
+func (e *AuditEntry) schemaVersion() int          { return e.V }
+func (e *AuditEntry) setSchemaVersion(v int)      { e.V = v }
+func (e *AllocationEntry) schemaVersion() int     { return e.V }
+func (e *AllocationEntry) setSchemaVersion(v int) { e.V = v }
+
+// stampVersion sets the current schema version on entry before writing.
+// A record from a newer bearing keeps its version, so that bearing does
+// not migrate it again.
+func stampVersion[T any](path string, entry *T) {
+	if v, ok := any(entry).(versioned); ok {
+		if s, ok := schemaFor(path); ok && v.schemaVersion() < s.Version() {
+			v.setSchemaVersion(s.Version())
+		}
+	}
+}
+
+// decodeRecord unmarshals one line into entry, upgrading it first if it
+// was written with an older schema version
+func decodeRecord[T any](path string, line []byte, entry *T) error {
+	s, ok := schemaFor(path)
+	if !ok {
+		return json.Unmarshal(line, entry)
+	}
+
+	// Fast path: already current
+	err := json.Unmarshal(line, entry)
+	if v, isVersioned := any(entry).(versioned); err == nil && (!isVersioned || v.schemaVersion() >= s.Version()) {
+		return nil
+	}
+
+	upgraded, _, uerr := upgradeLine(s, line)
+	if uerr != nil {
+		if err != nil {
+			return err
+		}
+		return uerr
+	}
+	var fresh T
+	if err := json.Unmarshal(upgraded, &fresh); err != nil {
+		return err
+	}
+	*entry = fresh
+	return nil
+}
+
+// upgradeLine applies pending migrations to a raw line and returns the
+// upgraded line and the version it started at
+func upgradeLine(s Schema, line []byte) ([]byte, int, error) {
+	var rec Record
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return nil, 0, err
+	}
+	if rec == nil {
+		return nil, 0, fmt.Errorf("record is not an object")
+	}
+
+	from := 0
+	if raw, ok := rec["v"]; ok {
+		if err := json.Unmarshal(raw, &from); err != nil {
+			return nil, 0, fmt.Errorf("invalid schema version %s", raw)
+		}
+	}
+	if from >= s.Version() {
+		return line, from, nil
+	}
+
+	for v := from; v < s.Version(); v++ {
+		if apply := s.Migrations[v].Apply; apply != nil {
+			if err := apply(rec); err != nil {
+				return nil, from, fmt.Errorf("%s v%d->v%d: %w", s.File, v, v+1, err)
+			}
+		}
+	}
+	rec["v"] = json.RawMessage(fmt.Sprint(s.Version()))
+
+	out, err := json.Marshal(rec)
+	return out, from, err
+}
+
+// MigrationReport summarizes migrating one file
+type MigrationReport struct {
+	File     string      `json:"file"`
+	Version  int         `json:"version"`
+	Records  int         `json:"records"`
+	Upgraded int         `json:"upgraded"`
+	From     map[int]int `json:"from,omitempty"` // records per starting version
+	Newer    int         `json:"newer,omitempty"`
+	Invalid  int         `json:"invalid,omitempty"`
+}
+
+// Migrate upgrades every registered file in the workspace to its current
+// schema version. Records are rewritten at the raw JSON level, so fields
+// this build does not know about survive. Lines that do not parse are kept
+// verbatim. With dryRun nothing is written.
+func (s *FileStore) Migrate(dryRun bool) ([]MigrationReport, error) {
+	var reports []MigrationReport
+	for _, schema := range Schemas() {
+		path := filepath.Join(s.baseDir, schema.File)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		report, err := migrateFile(path, schema, dryRun)
+		if err != nil {
+			return reports, fmt.Errorf("%s: %w", schema.File, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func migrateFile(path string, schema Schema, dryRun bool) (MigrationReport, error) {
+	report := MigrationReport{File: schema.File, Version: schema.Version(), From: map[int]int{}}
+
+	release, err := lockPaths(path)
+	if err != nil {
+		return report, err
+	}
+	defer release()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+
+	var out [][]byte
+	scanner := newLineScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		report.Records++
+
+		upgraded, from, err := upgradeLine(schema, line)
+		switch {
+		case err != nil:
+			report.Invalid++
+			out = append(out, line)
+		case from > schema.Version():
+			report.Newer++
+			out = append(out, line)
+		case from == schema.Version():
+			out = append(out, line)
+		default:
+			report.Upgraded++
+			report.From[from]++
+			out = append(out, upgraded)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+
+	if dryRun || report.Upgraded == 0 {
+		return report, nil
+	}
+	return report, writeAtomic(path, func(w io.Writer) error {
+		for _, line := range out {
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func stringField(rec Record, key string) string {
+	var s string
+	if raw, ok := rec[key]; ok {
+		json.Unmarshal(raw, &s)
+	}
+	return s
+}
+
+// dropInvalidTime removes key if it is not an RFC 3339 timestamp
+func dropInvalidTime(rec Record, key string) {
+	raw, ok := rec[key]
+	if !ok {
+		return
+	}
+	var t time.Time
+	if err := json.Unmarshal(raw, &t); err != nil {
+		delete(rec, key)
+	}
+}
+
+// MarshalRecord encodes entry as a record of file, stamped with the current
+// schema version unless it is newer. Backends that store records outside the JSONL files use
+// it so records round-trip through import and export unchanged.
+func MarshalRecord[T any](file string, entry T) ([]byte, error) {
+	stampVersion(file, &entry)
+	return json.Marshal(entry)
+}
+
+// UnmarshalRecord decodes a record of file, upgrading it first if it was
+// written with an older schema version
+func UnmarshalRecord[T any](file string, data []byte, entry *T) error {
+	return decodeRecord(file, data, entry)
+}
@@ -1,98 +1,386 @@
 package jsonl
 
 import (
-	"bufio"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 )
 
-// Store manages JSONL file operations with locking
-type Store struct {
+// Store is the storage backend for workspace state. The JSONL files are
+// the default backend and the interchange format; other backends (see
+// internal/sqlitestore) import and export them.
+type Store interface {
+	ReadWorkflow() ([]WorkflowEntry, error)
+	ReadLocal() ([]LocalEntry, error)
+	ReadHealth() ([]HealthEntry, error)
+	ReadProjects() ([]ProjectEntry, error)
+	ReadLeases() ([]LeaseEntry, error)
+	ReadSessions() ([]SessionEntry, error)
+	ReadAudit() ([]AuditEntry, error)
+	ReadAllocations() ([]AllocationEntry, error)
+
+	WriteWorkflow(entries []WorkflowEntry) error
+	WriteLocal(entries []LocalEntry) error
+	WriteHealth(entries []HealthEntry) error
+	WriteProjects(entries []ProjectEntry) error
+	WriteLeases(entries []LeaseEntry) error
+	WriteSessions(entries []SessionEntry) error
+	WriteAudit(entries []AuditEntry) error
+	WriteAllocations(entries []AllocationEntry) error
+
+	AppendWorkflow(entry WorkflowEntry) error
+	AppendLocal(entry LocalEntry) error
+	AppendAudit(entry AuditEntry) error
+
+	// FindLocal returns the local entry for folder, or nil if none
+	FindLocal(folder string) (*LocalEntry, error)
+	// FindHealth returns the health entry for folder, or nil if none
+	FindHealth(folder string) (*HealthEntry, error)
+	// FindWorkflow returns the newest workflow entry for branch of repo,
+	// or nil if none
+	FindWorkflow(repo, branch string) (*WorkflowEntry, error)
+	// FindAllocation returns the allocation of folder, or nil if none
+	FindAllocation(folder string) (*AllocationEntry, error)
+	// LeasesFor returns the leases on folder, expired or not
+	LeasesFor(folder string) ([]LeaseEntry, error)
+	// SessionsFor returns the sessions seen in folder
+	SessionsFor(folder string) ([]SessionEntry, error)
+
+	Update(fn func(tx Tx) error) error
+	UpdateWorkflow(fn func([]WorkflowEntry) ([]WorkflowEntry, error)) error
+	UpdateLocal(fn func([]LocalEntry) ([]LocalEntry, error)) error
+	UpdateHealth(fn func([]HealthEntry) ([]HealthEntry, error)) error
+	UpdateProjects(fn func([]ProjectEntry) ([]ProjectEntry, error)) error
+	UpdateLeases(fn func([]LeaseEntry) ([]LeaseEntry, error)) error
+	UpdateSessions(fn func([]SessionEntry) ([]SessionEntry, error)) error
+	UpdateAllocations(fn func([]AllocationEntry) ([]AllocationEntry, error)) error
+}
+
+// FileStore manages JSONL file operations with locking
+type FileStore struct {
 	baseDir string
+	opts    readOptions
 }
 
+var _ Store = (*FileStore)(nil)
+
 // NewStore creates a store for the given workspace directory
-func NewStore(baseDir string) *Store {
-	return &Store{baseDir: baseDir}
+func NewStore(baseDir string) *FileStore {
+	return &FileStore{baseDir: baseDir}
 }
 
 // WorkflowPath returns the path to workflow.jsonl
-func (s *Store) WorkflowPath() string {
+func (s *FileStore) WorkflowPath() string {
 	return filepath.Join(s.baseDir, "workflow.jsonl")
 }
 
 // LocalPath returns the path to local.jsonl
-func (s *Store) LocalPath() string {
+func (s *FileStore) LocalPath() string {
 	return filepath.Join(s.baseDir, "local.jsonl")
 }
 
 // HealthPath returns the path to health.jsonl
-func (s *Store) HealthPath() string {
+func (s *FileStore) HealthPath() string {
 	return filepath.Join(s.baseDir, "health.jsonl")
 }
 
 // ProjectsPath returns the path to projects.jsonl
-func (s *Store) ProjectsPath() string {
+func (s *FileStore) ProjectsPath() string {
 	return filepath.Join(s.baseDir, "projects.jsonl")
 }
 
+// LeasesPath returns the path to leases.jsonl
+func (s *FileStore) LeasesPath() string {
+	return filepath.Join(s.baseDir, "leases.jsonl")
+}
+
+// SessionsPath returns the path to sessions.jsonl
+func (s *FileStore) SessionsPath() string {
+	return filepath.Join(s.baseDir, "sessions.jsonl")
+}
+
+// AuditPath returns the path to audit.jsonl
+func (s *FileStore) AuditPath() string {
+	return filepath.Join(s.baseDir, "audit.jsonl")
+}
+
+// AllocationsPath returns the path to allocations.jsonl
+func (s *FileStore) AllocationsPath() string {
+	return filepath.Join(s.baseDir, "allocations.jsonl")
+}
+
 // ReadWorkflow reads all workflow entries
-func (s *Store) ReadWorkflow() ([]WorkflowEntry, error) {
-	return readJSONL[WorkflowEntry](s.WorkflowPath())
+func (s *FileStore) ReadWorkflow() ([]WorkflowEntry, error) {
+	return readJSONL[WorkflowEntry](s.opts, s.WorkflowPath())
 }
 
 // ReadLocal reads all local entries
-func (s *Store) ReadLocal() ([]LocalEntry, error) {
-	return readJSONL[LocalEntry](s.LocalPath())
+func (s *FileStore) ReadLocal() ([]LocalEntry, error) {
+	return readJSONL[LocalEntry](s.opts, s.LocalPath())
 }
 
 // ReadHealth reads all health entries
-func (s *Store) ReadHealth() ([]HealthEntry, error) {
-	return readJSONL[HealthEntry](s.HealthPath())
+func (s *FileStore) ReadHealth() ([]HealthEntry, error) {
+	return readJSONL[HealthEntry](s.opts, s.HealthPath())
 }
 
 // ReadProjects reads all project entries
-func (s *Store) ReadProjects() ([]ProjectEntry, error) {
-	return readJSONL[ProjectEntry](s.ProjectsPath())
+func (s *FileStore) ReadProjects() ([]ProjectEntry, error) {
+	return readJSONL[ProjectEntry](s.opts, s.ProjectsPath())
+}
+
+// ReadLeases reads all lease entries
+func (s *FileStore) ReadLeases() ([]LeaseEntry, error) {
+	return readJSONL[LeaseEntry](s.opts, s.LeasesPath())
+}
+
+// ReadSessions reads all session entries
+func (s *FileStore) ReadSessions() ([]SessionEntry, error) {
+	return readJSONL[SessionEntry](s.opts, s.SessionsPath())
+}
+
+// ReadAudit reads all audit entries
+func (s *FileStore) ReadAudit() ([]AuditEntry, error) {
+	return readJSONL[AuditEntry](s.opts, s.AuditPath())
+}
+
+// ReadAllocations reads all allocation entries
+func (s *FileStore) ReadAllocations() ([]AllocationEntry, error) {
+	return readJSONL[AllocationEntry](s.opts, s.AllocationsPath())
 }
 
 // WriteWorkflow writes all workflow entries (overwrites)
-func (s *Store) WriteWorkflow(entries []WorkflowEntry) error {
+func (s *FileStore) WriteWorkflow(entries []WorkflowEntry) error {
 	return writeJSONL(s.WorkflowPath(), entries)
 }
 
 // WriteLocal writes all local entries (overwrites)
-func (s *Store) WriteLocal(entries []LocalEntry) error {
+func (s *FileStore) WriteLocal(entries []LocalEntry) error {
 	return writeJSONL(s.LocalPath(), entries)
 }
 
 // WriteHealth writes all health entries (overwrites)
-func (s *Store) WriteHealth(entries []HealthEntry) error {
+func (s *FileStore) WriteHealth(entries []HealthEntry) error {
 	return writeJSONL(s.HealthPath(), entries)
 }
 
+// WriteProjects writes all project entries (overwrites)
+func (s *FileStore) WriteProjects(entries []ProjectEntry) error {
+	return writeJSONL(s.ProjectsPath(), entries)
+}
+
+// WriteLeases writes all lease entries (overwrites)
+func (s *FileStore) WriteLeases(entries []LeaseEntry) error {
+	return writeJSONL(s.LeasesPath(), entries)
+}
+
+// WriteSessions writes all session entries (overwrites)
+func (s *FileStore) WriteSessions(entries []SessionEntry) error {
+	return writeJSONL(s.SessionsPath(), entries)
+}
+
+// WriteAudit writes all audit entries (overwrites)
+func (s *FileStore) WriteAudit(entries []AuditEntry) error {
+	return writeJSONL(s.AuditPath(), entries)
+}
+
+// WriteAllocations writes all allocation entries (overwrites)
+func (s *FileStore) WriteAllocations(entries []AllocationEntry) error {
+	return writeJSONL(s.AllocationsPath(), entries)
+}
+
 // AppendWorkflow appends a workflow entry
-func (s *Store) AppendWorkflow(entry WorkflowEntry) error {
+func (s *FileStore) AppendWorkflow(entry WorkflowEntry) error {
 	return appendJSONL(s.WorkflowPath(), entry)
 }
 
 // AppendLocal appends a local entry
-func (s *Store) AppendLocal(entry LocalEntry) error {
+func (s *FileStore) AppendLocal(entry LocalEntry) error {
 	return appendJSONL(s.LocalPath(), entry)
 }
 
-func readJSONL[T any](path string) ([]T, error) {
+// AppendAudit appends an audit entry
+func (s *FileStore) AppendAudit(entry AuditEntry) error {
+	return appendJSONL(s.AuditPath(), entry)
+}
+
+// FindLocal returns the local entry for folder, or nil if none
+func (s *FileStore) FindLocal(folder string) (*LocalEntry, error) {
+	entries, err := s.ReadLocal()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Folder == folder {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// FindHealth returns the health entry for folder, or nil if none
+func (s *FileStore) FindHealth(folder string) (*HealthEntry, error) {
+	entries, err := s.ReadHealth()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Folder == folder {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// FindWorkflow returns the newest workflow entry for branch of repo, or
+// nil if none
+func (s *FileStore) FindWorkflow(repo, branch string) (*WorkflowEntry, error) {
+	entries, err := s.ReadWorkflow()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Repo == repo && entries[i].Branch == branch {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// FindAllocation returns the allocation of folder, or nil if none
+func (s *FileStore) FindAllocation(folder string) (*AllocationEntry, error) {
+	entries, err := s.ReadAllocations()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Folder == folder {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// LeasesFor returns the leases on folder, expired or not
+func (s *FileStore) LeasesFor(folder string) ([]LeaseEntry, error) {
+	entries, err := s.ReadLeases()
+	if err != nil {
+		return nil, err
+	}
+	var found []LeaseEntry
+	for _, e := range entries {
+		if e.Folder == folder {
+			found = append(found, e)
+		}
+	}
+	return found, nil
+}
+
+// SessionsFor returns the sessions seen in folder
+func (s *FileStore) SessionsFor(folder string) ([]SessionEntry, error) {
+	entries, err := s.ReadSessions()
+	if err != nil {
+		return nil, err
+	}
+	var found []SessionEntry
+	for _, e := range entries {
+		if e.Folder == folder {
+			found = append(found, e)
+		}
+	}
+	return found, nil
+}
+
+// Copy replaces everything in dst with the contents of src. Each kind of
+// entry is replaced separately, so an error can leave dst partially copied.
+func Copy(dst, src Store) error {
+	workflow, err := src.ReadWorkflow()
+	if err != nil {
+		return fmt.Errorf("failed to read workflow: %w", err)
+	}
+	local, err := src.ReadLocal()
+	if err != nil {
+		return fmt.Errorf("failed to read local: %w", err)
+	}
+	health, err := src.ReadHealth()
+	if err != nil {
+		return fmt.Errorf("failed to read health: %w", err)
+	}
+	projects, err := src.ReadProjects()
+	if err != nil {
+		return fmt.Errorf("failed to read projects: %w", err)
+	}
+	leases, err := src.ReadLeases()
+	if err != nil {
+		return fmt.Errorf("failed to read leases: %w", err)
+	}
+	sessions, err := src.ReadSessions()
+	if err != nil {
+		return fmt.Errorf("failed to read sessions: %w", err)
+	}
+	audit, err := src.ReadAudit()
+	if err != nil {
+		return fmt.Errorf("failed to read audit: %w", err)
+	}
+	allocations, err := src.ReadAllocations()
+	if err != nil {
+		return fmt.Errorf("failed to read allocations: %w", err)
+	}
+
+	if err := dst.WriteWorkflow(workflow); err != nil {
+		return fmt.Errorf("failed to write workflow: %w", err)
+	}
+	if err := dst.WriteLocal(local); err != nil {
+		return fmt.Errorf("failed to write local: %w", err)
+	}
+	if err := dst.WriteHealth(health); err != nil {
+		return fmt.Errorf("failed to write health: %w", err)
+	}
+	if err := dst.WriteProjects(projects); err != nil {
+		return fmt.Errorf("failed to write projects: %w", err)
+	}
+	if err := dst.WriteLeases(leases); err != nil {
+		return fmt.Errorf("failed to write leases: %w", err)
+	}
+	if err := dst.WriteSessions(sessions); err != nil {
+		return fmt.Errorf("failed to write sessions: %w", err)
+	}
+	if err := dst.WriteAudit(audit); err != nil {
+		return fmt.Errorf("failed to write audit: %w", err)
+	}
+	if err := dst.WriteAllocations(allocations); err != nil {
+		return fmt.Errorf("failed to write allocations: %w", err)
+	}
+	return nil
+}
+
+func readJSONL[T any](opts readOptions, path string) ([]T, error) {
 	lock, err := NewFileLock(path)
 	if err != nil {
 		return nil, err
 	}
 	defer lock.Close()
 
-	if err := lock.RLock(); err != nil {
+	// Repairing an interrupted write needs the exclusive lock; the common
+	// case only takes the shared one
+	if hasLeftovers(path) {
+		if err := lock.Lock(); err != nil {
+			return nil, err
+		}
+		if err := recoverJSONL(path); err != nil {
+			return nil, err
+		}
+	} else if err := lock.RLock(); err != nil {
 		return nil, err
 	}
 
+	return decodeJSONL[T](opts, path)
+}
+
+// decodeJSONL parses path without locking; caller holds a lock
+func decodeJSONL[T any](opts readOptions, path string) ([]T, error) {
 	f, err := os.Open(path)
 	if os.IsNotExist(err) {
 		return nil, nil
@@ -103,14 +391,23 @@ func readJSONL[T any](path string) ([]T, error) {
 	defer f.Close()
 
 	var entries []T
-	scanner := bufio.NewScanner(f)
+	scanner := newLineScanner(f)
+	lineNum := 0
 	for scanner.Scan() {
+		lineNum++
 		line := scanner.Bytes()
 		if len(line) == 0 {
 			continue
 		}
 		var entry T
-		if err := json.Unmarshal(line, &entry); err != nil {
+		if err := decodeRecord(path, line, &entry); err != nil {
+			perr := &ParseError{Path: path, Line: lineNum, Text: string(line), Err: err}
+			if opts.mode == Strict {
+				return nil, perr
+			}
+			if opts.onMalformed != nil {
+				opts.onMalformed(perr)
+			}
 			continue // skip malformed lines
 		}
 		entries = append(entries, entry)
@@ -129,19 +426,26 @@ func writeJSONL[T any](path string, entries []T) error {
 		return err
 	}
 
-	f, err := os.Create(path)
-	if err != nil {
+	return encodeJSONL(path, entries)
+}
+
+// encodeJSONL atomically replaces path with entries, first moving any
+// malformed lines of the old file to its sidecar; caller holds the
+// exclusive lock
+func encodeJSONL[T any](path string, entries []T) error {
+	if err := quarantineBeforeRewrite(path); err != nil {
 		return err
 	}
-	defer f.Close()
-
-	enc := json.NewEncoder(f)
-	for _, entry := range entries {
-		if err := enc.Encode(entry); err != nil {
-			return err
+	return writeAtomic(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for i := range entries {
+			stampVersion(path, &entries[i])
+			if err := enc.Encode(entries[i]); err != nil {
+				return err
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 func appendJSONL[T any](path string, entry T) error {
@@ -154,12 +458,25 @@ func appendJSONL[T any](path string, entry T) error {
 	if err := lock.Lock(); err != nil {
 		return err
 	}
+	if hasLeftovers(path) {
+		if err := recoverJSONL(path); err != nil {
+			return err
+		}
+	}
 
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	return json.NewEncoder(f).Encode(entry)
+	// A torn previous append would otherwise swallow this entry
+	if err := terminateLastLine(f); err != nil {
+		return err
+	}
+	stampVersion(path, &entry)
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		return err
+	}
+	return f.Sync()
 }
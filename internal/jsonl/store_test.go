@@ -1,8 +1,12 @@
 package jsonl
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -131,3 +135,366 @@ func TestReadProjects(t *testing.T) {
 		t.Errorf("expected bearing path, got %s", got[1].Path)
 	}
 }
+
+func TestWriteLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	store.WriteLocal([]LocalEntry{{Folder: "a", Repo: "a", Branch: "main", Base: true}})
+	store.WriteLocal([]LocalEntry{{Folder: "b", Repo: "b", Branch: "main", Base: true}})
+
+	for _, suffix := range []string{".tmp", ".bak"} {
+		if _, err := os.Stat(store.LocalPath() + suffix); !os.IsNotExist(err) {
+			t.Errorf("expected no %s file after write", suffix)
+		}
+	}
+	got, _ := store.ReadLocal()
+	if len(got) != 1 || got[0].Folder != "b" {
+		t.Errorf("expected only b, got %+v", got)
+	}
+}
+
+func TestRecoverDiscardsTempWhenFileExists(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	store.WriteLocal([]LocalEntry{{Folder: "a", Repo: "a", Branch: "main"}})
+
+	// Simulate a crash after writing half of the temp file
+	os.WriteFile(store.LocalPath()+".tmp", []byte(`{"folder":"b","re`), 0644)
+
+	got, err := store.ReadLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Folder != "a" {
+		t.Errorf("expected original entry, got %+v", got)
+	}
+	if _, err := os.Stat(store.LocalPath() + ".tmp"); !os.IsNotExist(err) {
+		t.Error("expected leftover temp file to be removed")
+	}
+}
+
+func TestRecoverRestoresBackup(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	// Live file missing, backup present
+	os.WriteFile(store.LocalPath()+".bak", []byte(`{"folder":"a","repo":"a","branch":"main","base":true}`+"\n"), 0644)
+	os.WriteFile(store.LocalPath()+".tmp", []byte(`{"folder":"b"`), 0644)
+
+	got, err := store.ReadLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Folder != "a" {
+		t.Errorf("expected entry restored from backup, got %+v", got)
+	}
+	if _, err := os.Stat(store.LocalPath() + ".bak"); !os.IsNotExist(err) {
+		t.Error("expected backup to be consumed")
+	}
+}
+
+func TestRecoverPromotesCompleteTemp(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	// First-ever write crashed between fsync and rename
+	os.WriteFile(store.LocalPath()+".tmp", []byte(`{"folder":"a","repo":"a","branch":"main"}`+"\n"), 0644)
+
+	got, err := store.ReadLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("expected temp file to be promoted, got %d entries", len(got))
+	}
+}
+
+func TestAppendAfterTornLine(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	// Previous append was interrupted mid-line
+	os.WriteFile(store.WorkflowPath(), []byte(`{"repo":"a","branch":"x","status":"active"}`+"\n"+`{"repo":"b","bra`), 0644)
+
+	if err := store.AppendWorkflow(WorkflowEntry{Repo: "c", Branch: "z", Status: "active"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := store.ReadWorkflow()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(got))
+	}
+	if got[1].Repo != "c" {
+		t.Errorf("expected appended entry to survive, got %+v", got[1])
+	}
+}
+
+func TestUpdateWorkflowConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	// Each writer appends one entry; without a single lock across
+	// read and write some appends would be lost
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := store.UpdateWorkflow(func(entries []WorkflowEntry) ([]WorkflowEntry, error) {
+				return append(entries, WorkflowEntry{Repo: "r", Branch: fmt.Sprintf("b%d", i), Status: "active"}), nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	got, _ := store.ReadWorkflow()
+	if len(got) != 20 {
+		t.Errorf("expected 20 entries, got %d", len(got))
+	}
+}
+
+func TestUpdateSpansFiles(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	store.WriteWorkflow([]WorkflowEntry{{Repo: "r", Branch: "x", Status: "active"}})
+	store.WriteLocal([]LocalEntry{{Folder: "r-x", Repo: "r", Branch: "x"}})
+
+	err := store.Update(func(tx Tx) error {
+		workflows, err := tx.Workflow()
+		if err != nil {
+			return err
+		}
+		workflows[0].Status = "merged"
+		tx.SetWorkflow(workflows)
+		tx.SetLocal(nil)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workflows, _ := store.ReadWorkflow()
+	if workflows[0].Status != "merged" {
+		t.Errorf("expected merged, got %s", workflows[0].Status)
+	}
+	locals, _ := store.ReadLocal()
+	if len(locals) != 0 {
+		t.Errorf("expected local entry removed, got %d", len(locals))
+	}
+}
+
+func TestUpdateAbortsOnError(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	store.WriteLocal([]LocalEntry{{Folder: "a", Repo: "a", Branch: "main"}})
+
+	abort := errors.New("abort")
+	err := store.Update(func(tx Tx) error {
+		tx.SetLocal(nil)
+		return abort
+	})
+	if !errors.Is(err, abort) {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+
+	got, _ := store.ReadLocal()
+	if len(got) != 1 {
+		t.Errorf("expected local.jsonl untouched, got %d entries", len(got))
+	}
+}
+
+func TestReadUpgradesLegacyRecords(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "workflow.jsonl")
+
+	// Written by the bash scripts before schema versioning
+	content := `{"repo":"a","branch":"x","basedOn":"unknown","status":"active","created":"unknown"}
+`
+	os.WriteFile(path, []byte(content), 0644)
+
+	store := NewStore(dir)
+	got, err := store.ReadWorkflow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected legacy record to be upgraded, got %d entries", len(got))
+	}
+	if got[0].BasedOn != "" {
+		t.Errorf("expected unknown basedOn to be dropped, got %q", got[0].BasedOn)
+	}
+	if got[0].V != WorkflowSchema.Version() {
+		t.Errorf("expected v%d, got v%d", WorkflowSchema.Version(), got[0].V)
+	}
+}
+
+func TestWriteStampsVersion(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	store.AppendLocal(LocalEntry{Folder: "a", Repo: "a", Branch: "main"})
+
+	data, _ := os.ReadFile(store.LocalPath())
+	if !strings.Contains(string(data), fmt.Sprintf(`"v":%d`, LocalSchema.Version())) {
+		t.Errorf("expected version stamp, got %s", data)
+	}
+}
+
+func TestRewriteKeepsNewerVersion(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	newer := WorkflowSchema.Version() + 1
+	line := fmt.Sprintf(`{"v":%d,"repo":"a","branch":"x","status":"active"}`, newer)
+	os.WriteFile(store.WorkflowPath(), []byte(line+"\n"), 0644)
+
+	err := store.UpdateWorkflow(func(entries []WorkflowEntry) ([]WorkflowEntry, error) {
+		return append(entries, WorkflowEntry{Repo: "b", Branch: "y", Status: "active"}), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(store.WorkflowPath())
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], fmt.Sprintf(`"v":%d`, newer)) {
+		t.Errorf("expected the newer record to keep v%d, got:\n%s", newer, data)
+	}
+	if !strings.Contains(lines[1], fmt.Sprintf(`"v":%d`, WorkflowSchema.Version())) {
+		t.Errorf("expected the new record stamped v%d, got %s", WorkflowSchema.Version(), lines[1])
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "workflow.jsonl")
+	content := `{"repo":"a","branch":"x","status":"active","created":"unknown","extra":"kept"}
+not json at all
+{"v":99,"repo":"b","branch":"y","status":"active"}
+`
+	os.WriteFile(path, []byte(content), 0644)
+	store := NewStore(dir)
+
+	// Dry run reports but does not write
+	reports, err := store.Migrate(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Upgraded != 1 || reports[0].Invalid != 1 || reports[0].Newer != 1 {
+		t.Fatalf("unexpected report: %+v", reports)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Error("dry run modified the file")
+	}
+
+	if _, err := store.Migrate(false); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines preserved, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], `"v":1`) || !strings.Contains(lines[0], `"extra":"kept"`) || strings.Contains(lines[0], "created") {
+		t.Errorf("unexpected upgraded line: %s", lines[0])
+	}
+	if lines[1] != "not json at all" {
+		t.Errorf("expected malformed line kept verbatim, got %s", lines[1])
+	}
+
+	// Second run is a no-op
+	reports, _ = store.Migrate(false)
+	if reports[0].Upgraded != 0 {
+		t.Errorf("expected nothing left to upgrade, got %d", reports[0].Upgraded)
+	}
+}
+
+func TestStrictReadReportsLine(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"repo":"a","branch":"x","status":"active"}
+{"repo": "b", broken
+`
+	os.WriteFile(filepath.Join(dir, "workflow.jsonl"), []byte(content), 0644)
+	store := NewStore(dir)
+
+	var skipped []*ParseError
+	store.OnMalformed(func(e *ParseError) { skipped = append(skipped, e) })
+	entries, err := store.ReadWorkflow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || len(skipped) != 1 || skipped[0].Line != 2 {
+		t.Fatalf("lenient read: %d entries, skipped %+v", len(entries), skipped)
+	}
+
+	store.SetReadMode(Strict)
+	_, err = store.ReadWorkflow()
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected *ParseError, got %v", err)
+	}
+	if perr.Line != 2 || !strings.HasPrefix(err.Error(), "workflow.jsonl:2:") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "local.jsonl")
+	content := `{"folder":"a","repo":"a","branch":"main","base":true}
+garbage
+`
+	os.WriteFile(path, []byte(content), 0644)
+	store := NewStore(dir)
+
+	problems, err := store.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Line != 2 || problems[0].Text != "garbage" {
+		t.Fatalf("unexpected problems: %+v", problems)
+	}
+
+	if _, err := store.Quarantine(); err != nil {
+		t.Fatal(err)
+	}
+	if problems, _ := store.Check(); len(problems) != 0 {
+		t.Errorf("expected clean file after quarantine, got %+v", problems)
+	}
+	entries, _ := store.ReadLocal()
+	if len(entries) != 1 {
+		t.Errorf("expected valid entry kept, got %d", len(entries))
+	}
+
+	rejected, err := readJSONL[RejectedEntry](readOptions{}, RejectedPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 1 || rejected[0].Text != "garbage" || rejected[0].Line != 2 {
+		t.Errorf("unexpected sidecar: %+v", rejected)
+	}
+}
+
+func TestRewritePreservesMalformedLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "workflow.jsonl")
+	os.WriteFile(path, []byte("{\"repo\":\"a\",\"branch\":\"x\",\"status\":\"active\"}\n{oops\n"), 0644)
+	store := NewStore(dir)
+
+	err := store.UpdateWorkflow(func(entries []WorkflowEntry) ([]WorkflowEntry, error) {
+		return append(entries, WorkflowEntry{Repo: "b", Branch: "y", Status: "active"}), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(RejectedPath(path))
+	if !strings.Contains(string(data), `"text":"{oops"`) {
+		t.Errorf("expected malformed line in sidecar, got %q", data)
+	}
+	entries, _ := store.ReadWorkflow()
+	if len(entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(entries))
+	}
+}
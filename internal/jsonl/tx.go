@@ -0,0 +1,195 @@
+package jsonl
+
+import "sort"
+
+// Tx is a read-modify-write transaction over the workflow and local
+// entries. Nothing else can modify them between the first read and the
+// commit, so concurrent bearing processes cannot interleave updates.
+//
+// Entries are read lazily and only written back if Set* was called. Do not
+// call Store methods inside a transaction: for FileStore, flock locks are
+// per open file, so a second lock on a held file blocks forever.
+type Tx interface {
+	// Workflow returns the workflow entries as of the start of the
+	// transaction plus any changes made with SetWorkflow
+	Workflow() ([]WorkflowEntry, error)
+	// SetWorkflow replaces the workflow entries written on commit
+	SetWorkflow(entries []WorkflowEntry)
+	// Local returns the local entries, including changes made with SetLocal
+	Local() ([]LocalEntry, error)
+	// SetLocal replaces the local entries written on commit
+	SetLocal(entries []LocalEntry)
+}
+
+// fileTx holds workflow.jsonl and local.jsonl exclusively locked
+type fileTx struct {
+	workflow txFile[WorkflowEntry]
+	local    txFile[LocalEntry]
+}
+
+func (tx *fileTx) Workflow() ([]WorkflowEntry, error) {
+	return tx.workflow.get()
+}
+
+func (tx *fileTx) SetWorkflow(entries []WorkflowEntry) {
+	tx.workflow.set(entries)
+}
+
+func (tx *fileTx) Local() ([]LocalEntry, error) {
+	return tx.local.get()
+}
+
+func (tx *fileTx) SetLocal(entries []LocalEntry) {
+	tx.local.set(entries)
+}
+
+// Update runs fn inside a transaction spanning workflow.jsonl and
+// local.jsonl. If fn returns an error nothing is written. Each file is
+// replaced atomically, but a failure while writing the second file leaves
+// the first one committed.
+func (s *FileStore) Update(fn func(tx Tx) error) error {
+	tx := &fileTx{
+		workflow: txFile[WorkflowEntry]{path: s.WorkflowPath(), opts: s.opts},
+		local:    txFile[LocalEntry]{path: s.LocalPath(), opts: s.opts},
+	}
+
+	release, err := lockPaths(tx.workflow.path, tx.local.path)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.workflow.commit(); err != nil {
+		return err
+	}
+	return tx.local.commit()
+}
+
+// UpdateWorkflow applies fn to workflow.jsonl under a single exclusive lock
+func (s *FileStore) UpdateWorkflow(fn func([]WorkflowEntry) ([]WorkflowEntry, error)) error {
+	return updateJSONL(s.opts, s.WorkflowPath(), fn)
+}
+
+// UpdateLocal applies fn to local.jsonl under a single exclusive lock
+func (s *FileStore) UpdateLocal(fn func([]LocalEntry) ([]LocalEntry, error)) error {
+	return updateJSONL(s.opts, s.LocalPath(), fn)
+}
+
+// UpdateHealth applies fn to health.jsonl under a single exclusive lock
+func (s *FileStore) UpdateHealth(fn func([]HealthEntry) ([]HealthEntry, error)) error {
+	return updateJSONL(s.opts, s.HealthPath(), fn)
+}
+
+// UpdateProjects applies fn to projects.jsonl under a single exclusive lock
+func (s *FileStore) UpdateProjects(fn func([]ProjectEntry) ([]ProjectEntry, error)) error {
+	return updateJSONL(s.opts, s.ProjectsPath(), fn)
+}
+
+// UpdateLeases applies fn to leases.jsonl under a single exclusive lock
+func (s *FileStore) UpdateLeases(fn func([]LeaseEntry) ([]LeaseEntry, error)) error {
+	return updateJSONL(s.opts, s.LeasesPath(), fn)
+}
+
+// UpdateSessions applies fn to sessions.jsonl under a single exclusive lock
+func (s *FileStore) UpdateSessions(fn func([]SessionEntry) ([]SessionEntry, error)) error {
+	return updateJSONL(s.opts, s.SessionsPath(), fn)
+}
+
+// UpdateAllocations applies fn to allocations.jsonl under a single exclusive lock
+func (s *FileStore) UpdateAllocations(fn func([]AllocationEntry) ([]AllocationEntry, error)) error {
+	return updateJSONL(s.opts, s.AllocationsPath(), fn)
+}
+
+func updateJSONL[T any](opts readOptions, path string, fn func([]T) ([]T, error)) error {
+	release, err := lockPaths(path)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	f := txFile[T]{path: path, opts: opts}
+	entries, err := f.get()
+	if err != nil {
+		return err
+	}
+	entries, err = fn(entries)
+	if err != nil {
+		return err
+	}
+	f.set(entries)
+	return f.commit()
+}
+
+// txFile buffers one file's entries for the duration of a transaction
+type txFile[T any] struct {
+	path    string
+	opts    readOptions
+	entries []T
+	loaded  bool
+	dirty   bool
+}
+
+func (f *txFile[T]) get() ([]T, error) {
+	if !f.loaded {
+		entries, err := decodeJSONL[T](f.opts, f.path)
+		if err != nil {
+			return nil, err
+		}
+		f.entries = entries
+		f.loaded = true
+	}
+	return f.entries, nil
+}
+
+func (f *txFile[T]) set(entries []T) {
+	f.entries = entries
+	f.loaded = true
+	f.dirty = true
+}
+
+func (f *txFile[T]) commit() error {
+	if !f.dirty {
+		return nil
+	}
+	return encodeJSONL(f.path, f.entries)
+}
+
+// lockPaths takes exclusive locks on all paths in a fixed order, so two
+// transactions over overlapping files cannot deadlock, and repairs any
+// interrupted writes. The returned func releases every lock.
+func lockPaths(paths ...string) (func(), error) {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	var locks []*FileLock
+	release := func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			locks[i].Close()
+		}
+	}
+
+	for _, path := range sorted {
+		lock, err := NewFileLock(path)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		if err := lock.Lock(); err != nil {
+			lock.Close()
+			release()
+			return nil, err
+		}
+		locks = append(locks, lock)
+
+		if hasLeftovers(path) {
+			if err := recoverJSONL(path); err != nil {
+				release()
+				return nil, err
+			}
+		}
+	}
+	return release, nil
+}
@@ -4,16 +4,38 @@ import "time"
 
 // WorkflowEntry tracks worktree lifecycle in workflow.jsonl
 type WorkflowEntry struct {
-	Repo    string    `json:"repo"`
-	Branch  string    `json:"branch"`
-	BasedOn string    `json:"basedOn,omitempty"`
-	Purpose string    `json:"purpose,omitempty"`
-	Status  string    `json:"status"` // active, merged, abandoned
-	Created time.Time `json:"created"`
+	V         int              `json:"v,omitempty"` // schema version
+	Repo      string           `json:"repo"`
+	Branch    string           `json:"branch"`
+	BasedOn   string           `json:"basedOn,omitempty"`
+	Purpose   string           `json:"purpose,omitempty"`
+	Status    string           `json:"status"` // active, merged, abandoned
+	Created   time.Time        `json:"created"`
+	Bootstrap *BootstrapStatus `json:"bootstrap,omitempty"` // last bootstrap of the worktree
+	Archive   string           `json:"archive,omitempty"`   // tag or bundle the branch was saved to before deletion
+	GC        *GCStatus        `json:"gc,omitempty"`        // last time worktree gc considered the worktree
+}
+
+// GCStatus records what worktree gc did with a worktree it found
+type GCStatus struct {
+	Reason   string    `json:"reason"`             // merged, closed, contained or idle
+	Outcome  string    `json:"outcome"`            // cleaned, skipped or failed
+	Problems []string  `json:"problems,omitempty"` // why it was skipped or failed
+	By       string    `json:"by"`                 // cli or daemon
+	Time     time.Time `json:"time"`
+}
+
+// BootstrapStatus records how preparing a new worktree went
+type BootstrapStatus struct {
+	Status     string    `json:"status"` // ok or failed
+	Error      string    `json:"error,omitempty"`
+	Finished   time.Time `json:"finished"`
+	DurationMs int64     `json:"durationMs"`
 }
 
 // LocalEntry tracks local worktree folders in local.jsonl
 type LocalEntry struct {
+	V      int    `json:"v,omitempty"` // schema version
 	Folder string `json:"folder"`
 	Repo   string `json:"repo"`
 	Branch string `json:"branch"`
@@ -22,17 +44,101 @@ type LocalEntry struct {
 
 // HealthEntry tracks worktree health status in health.jsonl
 type HealthEntry struct {
+	V          int       `json:"v,omitempty"` // schema version
+	Folder     string    `json:"folder"`
+	Dirty      bool      `json:"dirty"`
+	Unpushed   int       `json:"unpushed"`
+	PRState    *string   `json:"prState,omitempty"`
+	PRTitle    *string   `json:"prTitle,omitempty"`
+	LastCheck  time.Time `json:"lastCheck"`
+	Error      string    `json:"error,omitempty"`      // why part of the check failed
+	DurationMs int64     `json:"durationMs,omitempty"` // how long the check took
+	GitStatus
+	ConflictsWith []string `json:"conflictsWith,omitempty"` // folders or base branches a merge with would conflict
+}
+
+// GitStatus details a worktree's git state. Unpushed counts commits not on
+// the upstream or, for a branch never pushed, not on the base branch.
+type GitStatus struct {
+	Upstream   string `json:"upstream,omitempty"`   // e.g. origin/feature; empty if never pushed
+	Ahead      int    `json:"ahead,omitempty"`      // commits not on upstream
+	Behind     int    `json:"behind,omitempty"`     // upstream commits not in HEAD
+	BasedOn    string `json:"basedOn,omitempty"`    // base branch compared against
+	AheadBase  int    `json:"aheadBase,omitempty"`  // commits not on the base branch
+	BehindBase int    `json:"behindBase,omitempty"` // base branch commits not in HEAD
+	Merged     bool   `json:"merged,omitempty"`     // the commits ahead of base are in it, squashed or rebased
+	Staged     int    `json:"staged,omitempty"`
+	Modified   int    `json:"modified,omitempty"`
+	Untracked  int    `json:"untracked,omitempty"`
+	Conflicted int    `json:"conflicted,omitempty"`
+	Stashes    int    `json:"stashes,omitempty"`   // shared by all worktrees of the repo
+	Detached   bool   `json:"detached,omitempty"`  // HEAD is not on a branch
+	Operation  string `json:"operation,omitempty"` // merge, rebase, cherry-pick, revert or bisect in progress
+}
+
+// LeaseEntry records an agent's exclusive claim on a worktree in
+// leases.jsonl. A lease lapses at Expires unless renewed by a heartbeat.
+type LeaseEntry struct {
+	V          int       `json:"v,omitempty"` // schema version
+	Folder     string    `json:"folder"`
+	Owner      string    `json:"owner"` // e.g. an agent session ID
+	Acquired   time.Time `json:"acquired"`
+	Heartbeat  time.Time `json:"heartbeat"`
+	Expires    time.Time `json:"expires"`
+	TTLSeconds int64     `json:"ttlSeconds"` // how far each heartbeat extends Expires
+}
+
+// Expired reports whether the lease has lapsed at now
+func (l LeaseEntry) Expired(now time.Time) bool {
+	return !now.Before(l.Expires)
+}
+
+// SessionEntry records an agent session in sessions.jsonl: where it runs
+// and when it last did anything
+type SessionEntry struct {
+	V            int        `json:"v,omitempty"` // schema version
+	ID           string     `json:"id"`
+	Cwd          string     `json:"cwd"`
+	Folder       string     `json:"folder,omitempty"` // worktree containing cwd, if any
+	Started      time.Time  `json:"started"`
+	LastActivity time.Time  `json:"lastActivity"`
+	Ended        *time.Time `json:"ended,omitempty"` // set when the agent's session ends; cleared by new activity
+}
+
+// AuditEntry records a decision made by a hook in audit.jsonl
+type AuditEntry struct {
+	V         int       `json:"v,omitempty"` // schema version
+	Time      time.Time `json:"time"`
+	SessionID string    `json:"sessionId,omitempty"`
+	Tool      string    `json:"tool"`
+	Folder    string    `json:"folder,omitempty"`
+	Cwd       string    `json:"cwd,omitempty"`
+	Command   string    `json:"command,omitempty"` // Bash command
+	Path      string    `json:"path,omitempty"`    // file written
+	Rule      string    `json:"rule"`
+	Decision  string    `json:"decision"` // allow, ask or deny
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// AllocationEntry records the resources handed out to a worktree in
+// allocations.jsonl, so dev servers in different worktrees don't collide
+type AllocationEntry struct {
+	V         int       `json:"v,omitempty"` // schema version
 	Folder    string    `json:"folder"`
-	Dirty     bool      `json:"dirty"`
-	Unpushed  int       `json:"unpushed"`
-	PRState   *string   `json:"prState,omitempty"`
-	PRTitle   *string   `json:"prTitle,omitempty"`
-	LastCheck time.Time `json:"lastCheck"`
+	Repo      string    `json:"repo"`
+	Slot      int       `json:"slot"`      // index of the worktree's port range
+	Port      int       `json:"port"`      // first port of the range
+	PortCount int       `json:"portCount"` // ports in the range, starting at Port
+	Database  string    `json:"database"`
+	CacheDir  string    `json:"cacheDir"`
+	Allocated time.Time `json:"allocated"`
 }
 
 // ProjectEntry maps project names to GitHub repos in projects.jsonl
 type ProjectEntry struct {
-	Name       string `json:"name"`
-	GitHubRepo string `json:"github_repo"`
-	Path       string `json:"path"`
+	V             int    `json:"v,omitempty"` // schema version
+	Name          string `json:"name"`
+	GitHubRepo    string `json:"github_repo"`
+	Path          string `json:"path"`
+	DefaultBranch string `json:"defaultBranch,omitempty"` // detected from origin/HEAD, cached
 }
@@ -0,0 +1,257 @@
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ReadMode controls how reads treat lines that fail to parse
+type ReadMode int
+
+const (
+	// Lenient skips malformed lines, reporting them to OnMalformed
+	Lenient ReadMode = iota
+	// Strict fails the read with a *ParseError on the first malformed line
+	Strict
+)
+
+type readOptions struct {
+	mode        ReadMode
+	onMalformed func(*ParseError)
+}
+
+// SetReadMode sets how reads treat malformed lines (default Lenient)
+func (s *FileStore) SetReadMode(mode ReadMode) {
+	s.opts.mode = mode
+}
+
+// OnMalformed registers fn to be called for each line skipped by a
+// lenient read
+func (s *FileStore) OnMalformed(fn func(*ParseError)) {
+	s.opts.onMalformed = fn
+}
+
+// ParseError describes a JSONL line that could not be parsed
+type ParseError struct {
+	Path string
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", filepath.Base(e.Path), e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Malformed lines are never silently destroyed. Whenever the store rewrites
+// a file it first moves lines it cannot parse into a sidecar next to it,
+// e.g. workflow.jsonl -> workflow.rejected.jsonl, wrapped as RejectedEntry
+// records so the sidecar is itself valid JSONL.
+
+// RejectedEntry records a quarantined line in a *.rejected.jsonl sidecar
+type RejectedEntry struct {
+	Line       int       `json:"line"`
+	Error      string    `json:"error"`
+	Text       string    `json:"text"`
+	RejectedAt time.Time `json:"rejectedAt"`
+}
+
+// RejectedPath returns the sidecar path for quarantined lines of path
+func RejectedPath(path string) string {
+	return strings.TrimSuffix(path, ".jsonl") + ".rejected.jsonl"
+}
+
+// validators decode a line as the record type of each known file
+var validators = map[string]func(path string, line []byte) error{
+	"workflow.jsonl":    validateLine[WorkflowEntry],
+	"local.jsonl":       validateLine[LocalEntry],
+	"health.jsonl":      validateLine[HealthEntry],
+	"projects.jsonl":    validateLine[ProjectEntry],
+	"leases.jsonl":      validateLine[LeaseEntry],
+	"sessions.jsonl":    validateLine[SessionEntry],
+	"audit.jsonl":       validateLine[AuditEntry],
+	"allocations.jsonl": validateLine[AllocationEntry],
+}
+
+// RegisterValidator sets the record type used to check lines of file
+func RegisterValidator(file string, fn func(path string, line []byte) error) {
+	validators[file] = fn
+}
+
+func validateLine[T any](path string, line []byte) error {
+	var entry T
+	return decodeRecord(path, line, &entry)
+}
+
+func validatorFor(path string) func(path string, line []byte) error {
+	if fn, ok := validators[filepath.Base(path)]; ok {
+		return fn
+	}
+	return func(_ string, line []byte) error {
+		var rec Record
+		return json.Unmarshal(line, &rec)
+	}
+}
+
+// Check scans every known file in the workspace and returns all lines that
+// fail to parse. It does not modify anything.
+func (s *FileStore) Check() ([]*ParseError, error) {
+	var problems []*ParseError
+	for _, path := range s.knownFiles() {
+		lock, err := NewFileLock(path)
+		if err != nil {
+			return problems, err
+		}
+		err = lock.RLock()
+		if err == nil {
+			var bad []*ParseError
+			bad, _, err = scanLines(path)
+			problems = append(problems, bad...)
+		}
+		lock.Close()
+		if err != nil {
+			return problems, err
+		}
+	}
+	return problems, nil
+}
+
+// Quarantine moves every malformed line of every known file into its
+// *.rejected.jsonl sidecar and returns the lines moved
+func (s *FileStore) Quarantine() ([]*ParseError, error) {
+	var moved []*ParseError
+	for _, path := range s.knownFiles() {
+		bad, err := quarantineFile(path)
+		moved = append(moved, bad...)
+		if err != nil {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
+
+// knownFiles returns the paths of validated files present in the workspace
+func (s *FileStore) knownFiles() []string {
+	var paths []string
+	for _, schema := range Schemas() {
+		path := filepath.Join(s.baseDir, schema.File)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func quarantineFile(path string) ([]*ParseError, error) {
+	release, err := lockPaths(path)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	bad, good, err := scanLines(path)
+	if err != nil || len(bad) == 0 {
+		return nil, err
+	}
+	if err := appendRejected(path, bad); err != nil {
+		return nil, err
+	}
+	err = writeAtomic(path, func(w io.Writer) error {
+		for _, line := range good {
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return bad, err
+}
+
+// quarantineBeforeRewrite moves malformed lines out of path before it is
+// overwritten. Caller holds the exclusive lock.
+func quarantineBeforeRewrite(path string) error {
+	bad, _, err := scanLines(path)
+	if err != nil || len(bad) == 0 {
+		return err
+	}
+	return appendRejected(path, bad)
+}
+
+// scanLines splits path into malformed lines and raw valid lines. Caller
+// holds a lock.
+func scanLines(path string) ([]*ParseError, [][]byte, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	validate := validatorFor(path)
+	var bad []*ParseError
+	var good [][]byte
+	scanner := newLineScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if err := validate(path, line); err != nil {
+			bad = append(bad, &ParseError{Path: path, Line: lineNum, Text: string(line), Err: err})
+			continue
+		}
+		good = append(good, append([]byte(nil), line...))
+	}
+	return bad, good, scanner.Err()
+}
+
+// appendRejected appends bad lines to the sidecar of path
+func appendRejected(path string, bad []*ParseError) error {
+	if len(bad) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(RejectedPath(path), os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := terminateLastLine(f); err != nil {
+		return err
+	}
+	now := time.Now()
+	enc := json.NewEncoder(f)
+	for _, b := range bad {
+		if err := enc.Encode(RejectedEntry{
+			Line:       b.Line,
+			Error:      b.Err.Error(),
+			Text:       b.Text,
+			RejectedAt: now,
+		}); err != nil {
+			return err
+		}
+	}
+	return f.Sync()
+}
+
+// newLineScanner returns a scanner that accepts lines up to 16MB
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return scanner
+}
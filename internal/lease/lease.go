@@ -0,0 +1,157 @@
+// Package lease lets agents claim exclusive ownership of a worktree.
+//
+// Leases live in leases.jsonl. Each one names an owner (usually an agent
+// session ID) and lapses unless renewed by a heartbeat before it expires.
+// An expired lease no longer blocks anyone and is removed by the daemon,
+// or replaced by the next claim.
+package lease
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+// DefaultTTL is how long a claim lasts without a heartbeat
+const DefaultTTL = 2 * time.Hour
+
+// ErrNotHeld is returned when releasing or renewing a lease the caller
+// does not hold
+var ErrNotHeld = errors.New("no lease held")
+
+// HeldError is returned when a worktree is already leased to someone else
+type HeldError struct {
+	Lease jsonl.LeaseEntry
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("%s is claimed by %s until %s", e.Lease.Folder, e.Lease.Owner, e.Lease.Expires.Local().Format(time.Kitchen))
+}
+
+// Claim leases folder to owner for ttl. Claiming a folder the owner
+// already holds renews it; a live lease held by anyone else fails with a
+// *HeldError.
+func Claim(store jsonl.Store, folder, owner string, ttl time.Duration, now time.Time) (jsonl.LeaseEntry, error) {
+	if owner == "" {
+		return jsonl.LeaseEntry{}, errors.New("lease owner is required")
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	claimed := jsonl.LeaseEntry{
+		Folder:     folder,
+		Owner:      owner,
+		Acquired:   now,
+		Heartbeat:  now,
+		Expires:    now.Add(ttl),
+		TTLSeconds: int64(ttl / time.Second),
+	}
+	err := store.UpdateLeases(func(entries []jsonl.LeaseEntry) ([]jsonl.LeaseEntry, error) {
+		kept := entries[:0]
+		for _, l := range entries {
+			if l.Folder != folder {
+				kept = append(kept, l)
+				continue
+			}
+			if l.Owner != owner && !l.Expired(now) {
+				return nil, &HeldError{Lease: l}
+			}
+			if l.Owner == owner {
+				claimed.Acquired = l.Acquired
+			}
+		}
+		return append(kept, claimed), nil
+	})
+	return claimed, err
+}
+
+// Release gives up owner's lease on folder. With force, the lease is
+// dropped whoever holds it.
+func Release(store jsonl.Store, folder, owner string, force bool) error {
+	return store.UpdateLeases(func(entries []jsonl.LeaseEntry) ([]jsonl.LeaseEntry, error) {
+		kept := entries[:0]
+		released := false
+		for _, l := range entries {
+			if l.Folder == folder && (force || l.Owner == owner) {
+				released = true
+				continue
+			}
+			kept = append(kept, l)
+		}
+		if !released {
+			return nil, fmt.Errorf("%w on %s by %s", ErrNotHeld, folder, owner)
+		}
+		return kept, nil
+	})
+}
+
+// Heartbeat renews owner's lease on folder for another TTL. A lease that
+// has expired but not yet been reaped is renewed too.
+func Heartbeat(store jsonl.Store, folder, owner string, now time.Time) (jsonl.LeaseEntry, error) {
+	var renewed jsonl.LeaseEntry
+	err := store.UpdateLeases(func(entries []jsonl.LeaseEntry) ([]jsonl.LeaseEntry, error) {
+		for i, l := range entries {
+			if l.Folder != folder || l.Owner != owner {
+				continue
+			}
+			ttl := time.Duration(l.TTLSeconds) * time.Second
+			if ttl <= 0 {
+				ttl = DefaultTTL
+			}
+			entries[i].Heartbeat = now
+			entries[i].Expires = now.Add(ttl)
+			renewed = entries[i]
+			return entries, nil
+		}
+		return nil, fmt.Errorf("%w on %s by %s", ErrNotHeld, folder, owner)
+	})
+	return renewed, err
+}
+
+// Active returns the live lease on folder, or nil if it is free
+func Active(store jsonl.Store, folder string, now time.Time) (*jsonl.LeaseEntry, error) {
+	entries, err := store.LeasesFor(folder)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if !entries[i].Expired(now) {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Reap removes expired leases and returns them. leases.jsonl is only
+// rewritten when something expired.
+func Reap(store jsonl.Store, now time.Time) ([]jsonl.LeaseEntry, error) {
+	entries, err := store.ReadLeases()
+	if err != nil {
+		return nil, err
+	}
+	expired := false
+	for _, l := range entries {
+		expired = expired || l.Expired(now)
+	}
+	if !expired {
+		return nil, nil
+	}
+
+	var reaped []jsonl.LeaseEntry
+	err = store.UpdateLeases(func(entries []jsonl.LeaseEntry) ([]jsonl.LeaseEntry, error) {
+		reaped = nil
+		kept := entries[:0]
+		for _, l := range entries {
+			if l.Expired(now) {
+				reaped = append(reaped, l)
+			} else {
+				kept = append(kept, l)
+			}
+		}
+		return kept, nil
+	})
+	return reaped, err
+}
@@ -0,0 +1,81 @@
+package lease
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+func TestClaimRenewAndConflict(t *testing.T) {
+	store := jsonl.NewStore(t.TempDir())
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	l, err := Claim(store, "app-feat", "s1", time.Hour, now)
+	if err != nil || !l.Expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Claim = %+v, %v", l, err)
+	}
+
+	// Same owner renews and keeps the original acquire time
+	l, err = Claim(store, "app-feat", "s1", time.Hour, now.Add(time.Minute))
+	if err != nil || !l.Acquired.Equal(now) || !l.Expires.Equal(now.Add(61*time.Minute)) {
+		t.Fatalf("renewing Claim = %+v, %v", l, err)
+	}
+
+	var held *HeldError
+	if _, err := Claim(store, "app-feat", "s2", time.Hour, now.Add(30*time.Minute)); !errors.As(err, &held) || held.Lease.Owner != "s1" {
+		t.Fatalf("expected HeldError for s1, got %v", err)
+	}
+
+	// Once s1's lease lapses s2 may take over
+	if _, err := Claim(store, "app-feat", "s2", time.Hour, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("claim after expiry: %v", err)
+	}
+	leases, _ := store.ReadLeases()
+	if len(leases) != 1 || leases[0].Owner != "s2" {
+		t.Errorf("expected a single lease for s2, got %+v", leases)
+	}
+}
+
+func TestHeartbeatAndRelease(t *testing.T) {
+	store := jsonl.NewStore(t.TempDir())
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	Claim(store, "app-feat", "s1", 10*time.Minute, now)
+
+	l, err := Heartbeat(store, "app-feat", "s1", now.Add(8*time.Minute))
+	if err != nil || !l.Expires.Equal(now.Add(18*time.Minute)) {
+		t.Fatalf("Heartbeat = %+v, %v", l, err)
+	}
+	if _, err := Heartbeat(store, "app-feat", "s2", now); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("expected ErrNotHeld for another owner, got %v", err)
+	}
+
+	if err := Release(store, "app-feat", "s2", false); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("expected ErrNotHeld releasing another owner's lease, got %v", err)
+	}
+	if err := Release(store, "app-feat", "s2", true); err != nil {
+		t.Errorf("forced release: %v", err)
+	}
+	if l, _ := Active(store, "app-feat", now); l != nil {
+		t.Errorf("expected no lease after release, got %+v", l)
+	}
+}
+
+func TestReap(t *testing.T) {
+	store := jsonl.NewStore(t.TempDir())
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	Claim(store, "short", "s1", time.Minute, now)
+	Claim(store, "long", "s2", time.Hour, now)
+
+	reaped, err := Reap(store, now.Add(5*time.Minute))
+	if err != nil || len(reaped) != 1 || reaped[0].Folder != "short" {
+		t.Fatalf("Reap = %+v, %v", reaped, err)
+	}
+	if l, _ := Active(store, "long", now.Add(5*time.Minute)); l == nil || l.Owner != "s2" {
+		t.Errorf("expected long lease to survive, got %+v", l)
+	}
+	if reaped, _ := Reap(store, now.Add(5*time.Minute)); len(reaped) != 0 {
+		t.Errorf("expected nothing left to reap, got %+v", reaped)
+	}
+}
@@ -0,0 +1,275 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ProtocolVersion is the MCP protocol revision this server speaks
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Handler executes a tool call with raw JSON arguments
+type Handler func(args json.RawMessage) (interface{}, error)
+
+// Tool describes a callable tool exposed over MCP
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	Handler     Handler         `json:"-"`
+}
+
+// ToolError is a tool failure with a machine-readable code
+type ToolError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ToolError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Errorf creates a ToolError with the given code
+func Errorf(code, format string, args ...interface{}) *ToolError {
+	return &ToolError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Server dispatches JSON-RPC requests to registered tools
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+	byName  map[string]Tool
+}
+
+// NewServer creates a server that reports the given name and version
+func NewServer(name, version string) *Server {
+	return &Server{
+		name:    name,
+		version: version,
+		byName:  make(map[string]Tool),
+	}
+}
+
+// AddTool registers a tool
+func (s *Server) AddTool(t Tool) {
+	if _, exists := s.byName[t.Name]; exists {
+		panic("mcp: duplicate tool " + t.Name)
+	}
+	s.tools = append(s.tools, t)
+	s.byName[t.Name] = t
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type callResult struct {
+	Content           []content   `json:"content"`
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+	IsError           bool        `json:"isError,omitempty"`
+}
+
+// Serve reads newline-delimited JSON-RPC messages from r and writes
+// responses to w until r is exhausted
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if resp := s.handleMessage(line); resp != nil {
+			if err := s.write(w, resp); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *Server) write(w io.Writer, resp *response) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// handleMessage processes one message; notifications return nil
+func (s *Server) handleMessage(line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), CodeParseError, "parse error: "+err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(idOrNull(req.ID), CodeInvalidRequest, "invalid request")
+	}
+
+	// Notifications carry no id and never get a response
+	isNotification := len(req.ID) == 0
+
+	result, rerr := s.dispatch(req.Method, req.Params)
+	if isNotification {
+		return nil
+	}
+	if rerr != nil {
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: rerr}
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func (s *Server) dispatch(method string, params json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities": map[string]interface{}{
+				"tools": map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{
+				"name":    s.name,
+				"version": s.version,
+			},
+		}, nil
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		tools := s.tools
+		if tools == nil {
+			tools = []Tool{}
+		}
+		return map[string]interface{}{"tools": tools}, nil
+	case "tools/call":
+		return s.callTool(params)
+	default:
+		return nil, &rpcError{Code: CodeMethodNotFound, Message: "method not found: " + method}
+	}
+}
+
+func (s *Server) callTool(params json.RawMessage) (interface{}, *rpcError) {
+	var call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &rpcError{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	tool, ok := s.byName[call.Name]
+	if !ok {
+		return nil, &rpcError{Code: CodeInvalidParams, Message: "unknown tool: " + call.Name}
+	}
+	if len(call.Arguments) == 0 || string(call.Arguments) == "null" {
+		call.Arguments = json.RawMessage("{}")
+	}
+
+	out, err := runHandler(tool.Handler, call.Arguments)
+	if err != nil {
+		var te *ToolError
+		if !errors.As(err, &te) {
+			te = &ToolError{Code: "error", Message: err.Error()}
+		}
+		return &callResult{
+			Content:           []content{{Type: "text", Text: te.Error()}},
+			StructuredContent: map[string]interface{}{"error": te},
+			IsError:           true,
+		}, nil
+	}
+
+	text, err := json.Marshal(out)
+	if err != nil {
+		return nil, &rpcError{Code: CodeInternalError, Message: err.Error()}
+	}
+	res := &callResult{Content: []content{{Type: "text", Text: string(text)}}}
+	// structuredContent must be a JSON object; wrap anything else
+	if isJSONObject(text) {
+		res.StructuredContent = json.RawMessage(text)
+	} else {
+		res.StructuredContent = map[string]json.RawMessage{"result": text}
+	}
+	return res, nil
+}
+
+// runHandler invokes h, converting a panic into an internal error so one
+// bad tool call cannot take down the server
+func runHandler(h Handler, args json.RawMessage) (out interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Errorf("internal", "%v", r)
+		}
+	}()
+	return h(args)
+}
+
+// DecodeArgs unmarshals tool arguments, rejecting unknown fields
+func DecodeArgs(args json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return Errorf("invalid_arguments", "%v", err)
+	}
+	return nil
+}
+
+func errorResponse(id json.RawMessage, code int, msg string) *response {
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: msg}}
+}
+
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
+
+func isJSONObject(data []byte) bool {
+	for _, b := range data {
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '{':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
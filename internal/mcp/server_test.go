@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func serve(t *testing.T, s *Server, lines ...string) []map[string]interface{} {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var resps []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		resps = append(resps, m)
+	}
+	return resps
+}
+
+func newTestServer() *Server {
+	s := NewServer("bearing", "test")
+	s.AddTool(Tool{
+		Name:        "echo",
+		Description: "Echo the message back",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"message":{"type":"string"}}}`),
+		Handler: func(args json.RawMessage) (interface{}, error) {
+			var in struct {
+				Message string `json:"message"`
+			}
+			if err := DecodeArgs(args, &in); err != nil {
+				return nil, err
+			}
+			return map[string]string{"message": in.Message}, nil
+		},
+	})
+	s.AddTool(Tool{
+		Name:        "fail",
+		InputSchema: json.RawMessage(`{"type":"object"}`),
+		Handler: func(args json.RawMessage) (interface{}, error) {
+			return nil, Errorf("not_found", "nothing here")
+		},
+	})
+	s.AddTool(Tool{
+		Name:        "list",
+		InputSchema: json.RawMessage(`{"type":"object"}`),
+		Handler: func(args json.RawMessage) (interface{}, error) {
+			return []int{1, 2}, nil
+		},
+	})
+	return s
+}
+
+func TestInitialize(t *testing.T) {
+	resps := serve(t, newTestServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+	)
+	// Notification must not produce a response
+	if len(resps) != 1 {
+		t.Fatalf("expected 1 response, got %d", len(resps))
+	}
+	result := resps[0]["result"].(map[string]interface{})
+	if result["protocolVersion"] != ProtocolVersion {
+		t.Errorf("unexpected protocol version: %v", result["protocolVersion"])
+	}
+	info := result["serverInfo"].(map[string]interface{})
+	if info["name"] != "bearing" {
+		t.Errorf("expected server name bearing, got %v", info["name"])
+	}
+}
+
+func TestToolsList(t *testing.T) {
+	resps := serve(t, newTestServer(), `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	tools := resps[0]["result"].(map[string]interface{})["tools"].([]interface{})
+	if len(tools) != 3 {
+		t.Fatalf("expected 3 tools, got %d", len(tools))
+	}
+	first := tools[0].(map[string]interface{})
+	if first["name"] != "echo" {
+		t.Errorf("expected echo first, got %v", first["name"])
+	}
+	if _, ok := first["inputSchema"]; !ok {
+		t.Error("expected inputSchema")
+	}
+}
+
+func TestToolsCall(t *testing.T) {
+	resps := serve(t, newTestServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`)
+	result := resps[0]["result"].(map[string]interface{})
+	if result["isError"] == true {
+		t.Fatalf("unexpected error result: %v", result)
+	}
+	structured := result["structuredContent"].(map[string]interface{})
+	if structured["message"] != "hi" {
+		t.Errorf("expected hi, got %v", structured["message"])
+	}
+}
+
+func TestToolsCall_NonObjectResult(t *testing.T) {
+	resps := serve(t, newTestServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list"}}`)
+	structured := resps[0]["result"].(map[string]interface{})["structuredContent"].(map[string]interface{})
+	if got := fmt.Sprint(structured["result"]); got != "[1 2]" {
+		t.Errorf("expected wrapped array, got %s", got)
+	}
+}
+
+func TestToolsCall_ToolError(t *testing.T) {
+	resps := serve(t, newTestServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"fail","arguments":{}}}`)
+	result := resps[0]["result"].(map[string]interface{})
+	if result["isError"] != true {
+		t.Fatal("expected isError")
+	}
+	toolErr := result["structuredContent"].(map[string]interface{})["error"].(map[string]interface{})
+	if toolErr["code"] != "not_found" {
+		t.Errorf("expected not_found, got %v", toolErr["code"])
+	}
+}
+
+func TestToolsCall_InvalidArguments(t *testing.T) {
+	resps := serve(t, newTestServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"bogus":1}}}`)
+	result := resps[0]["result"].(map[string]interface{})
+	toolErr := result["structuredContent"].(map[string]interface{})["error"].(map[string]interface{})
+	if toolErr["code"] != "invalid_arguments" {
+		t.Errorf("expected invalid_arguments, got %v", toolErr["code"])
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	resps := serve(t, newTestServer(),
+		`not json`,
+		`{"jsonrpc":"2.0","id":2,"method":"nope"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"missing"}}`,
+	)
+	if len(resps) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(resps))
+	}
+	want := []float64{CodeParseError, CodeMethodNotFound, CodeInvalidParams}
+	for i, code := range want {
+		rerr, ok := resps[i]["error"].(map[string]interface{})
+		if !ok {
+			t.Errorf("response %d: expected error", i)
+			continue
+		}
+		if rerr["code"] != code {
+			t.Errorf("response %d: expected code %v, got %v", i, code, rerr["code"])
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package session tracks which agent session is working in which worktree.
+//
+// Sessions live in sessions.jsonl, written by the agent's hooks: a session
+// starts when the agent opens, is touched by later hooks, including the
+// one at the end of each turn, and ends when the agent's session ends. A
+// session counts as live until it ends or goes quiet for longer than
+// IdleTimeout, which covers agents that crash without running their
+// session end hook.
+package session
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+// IdleTimeout is how long a session without activity still counts as live
+const IdleTimeout = time.Hour
+
+// Retention is how long ended sessions are kept before Prune drops them
+const Retention = 7 * 24 * time.Hour
+
+// State of a session at some moment
+const (
+	StateActive  = "active"  // working, with recent activity
+	StateIdle    = "idle"    // no stop recorded, but quiet for IdleTimeout
+	StateStopped = "stopped" // the agent's session ended
+)
+
+// Folder returns the top-level workspace folder containing dir, or "" if
+// dir is outside the workspace or is the workspace itself
+func Folder(workspaceDir, dir string) string {
+	rel, err := filepath.Rel(workspaceDir, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	folder, _, _ := strings.Cut(rel, string(filepath.Separator))
+	return folder
+}
+
+// State returns whether s is active, idle or stopped at now
+func State(s jsonl.SessionEntry, now time.Time) string {
+	switch {
+	case s.Ended != nil:
+		return StateStopped
+	case now.Sub(s.LastActivity) > IdleTimeout:
+		return StateIdle
+	}
+	return StateActive
+}
+
+// Live reports whether s is active at now
+func Live(s jsonl.SessionEntry, now time.Time) bool {
+	return State(s, now) == StateActive
+}
+
+// Touch records activity by session id in cwd: an unknown session is
+// started, and a stopped one (as on resume) reopened
+func Touch(store jsonl.Store, workspaceDir, id, cwd string, now time.Time) (jsonl.SessionEntry, error) {
+	return upsert(store, workspaceDir, id, cwd, now, func(s *jsonl.SessionEntry) {
+		s.Ended = nil
+	})
+}
+
+// Stop records that session id ended, as opposed to finishing a turn,
+// which is activity. cwd may be empty to keep the recorded one.
+func Stop(store jsonl.Store, workspaceDir, id, cwd string, now time.Time) (jsonl.SessionEntry, error) {
+	return upsert(store, workspaceDir, id, cwd, now, func(s *jsonl.SessionEntry) {
+		ended := now
+		s.Ended = &ended
+	})
+}
+
+// upsert applies fn to session id, creating it if needed, and stamps its
+// activity and location
+func upsert(store jsonl.Store, workspaceDir, id, cwd string, now time.Time, fn func(s *jsonl.SessionEntry)) (jsonl.SessionEntry, error) {
+	var result jsonl.SessionEntry
+	err := store.UpdateSessions(func(entries []jsonl.SessionEntry) ([]jsonl.SessionEntry, error) {
+		i := -1
+		for j := range entries {
+			if entries[j].ID == id {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			entries = append(entries, jsonl.SessionEntry{ID: id, Started: now})
+			i = len(entries) - 1
+		}
+
+		s := &entries[i]
+		if cwd != "" {
+			s.Cwd = cwd
+			s.Folder = Folder(workspaceDir, cwd)
+		}
+		s.LastActivity = now
+		fn(s)
+		result = *s
+		return entries, nil
+	})
+	return result, err
+}
+
+// ByFolder returns the most recently active session in each folder
+func ByFolder(sessions []jsonl.SessionEntry) map[string]jsonl.SessionEntry {
+	latest := make(map[string]jsonl.SessionEntry)
+	for _, s := range sessions {
+		if s.Folder == "" {
+			continue
+		}
+		if cur, ok := latest[s.Folder]; !ok || s.LastActivity.After(cur.LastActivity) {
+			latest[s.Folder] = s
+		}
+	}
+	return latest
+}
+
+// Prune drops sessions that ended, or went idle, more than Retention ago
+// and returns how many were dropped. sessions.jsonl is only rewritten
+// when something is dropped.
+func Prune(store jsonl.Store, now time.Time) (int, error) {
+	old := func(s jsonl.SessionEntry) bool {
+		return now.Sub(s.LastActivity) > Retention
+	}
+
+	entries, err := store.ReadSessions()
+	if err != nil {
+		return 0, err
+	}
+	stale := false
+	for _, s := range entries {
+		stale = stale || old(s)
+	}
+	if !stale {
+		return 0, nil
+	}
+
+	pruned := 0
+	err = store.UpdateSessions(func(entries []jsonl.SessionEntry) ([]jsonl.SessionEntry, error) {
+		pruned = 0
+		kept := entries[:0]
+		for _, s := range entries {
+			if old(s) {
+				pruned++
+			} else {
+				kept = append(kept, s)
+			}
+		}
+		return kept, nil
+	})
+	return pruned, err
+}
+
+// Unattended reports whether a worktree has uncommitted changes but no
+// live session working in it. s is the folder's latest session, or nil.
+func Unattended(dirty bool, s *jsonl.SessionEntry, now time.Time) bool {
+	return dirty && (s == nil || !Live(*s, now))
+}
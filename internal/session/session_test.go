@@ -0,0 +1,94 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+func TestFolder(t *testing.T) {
+	for dir, want := range map[string]string{
+		"/w/app-feat":       "app-feat",
+		"/w/app-feat/src/x": "app-feat",
+		"/w":                "",
+		"/elsewhere":        "",
+		"/w2/app":           "",
+	} {
+		if got := Folder("/w", dir); got != want {
+			t.Errorf("Folder(/w, %s) = %q, want %q", dir, got, want)
+		}
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	store := jsonl.NewStore(t.TempDir())
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	s, err := Touch(store, "/w", "s1", "/w/app-feat/src", start)
+	if err != nil || s.Folder != "app-feat" || !s.Started.Equal(start) {
+		t.Fatalf("Touch = %+v, %v", s, err)
+	}
+	if State(s, start.Add(time.Minute)) != StateActive {
+		t.Errorf("expected active session")
+	}
+	if State(s, start.Add(2*IdleTimeout)) != StateIdle {
+		t.Errorf("expected idle session after IdleTimeout")
+	}
+
+	s, err = Stop(store, "/w", "s1", "", start.Add(10*time.Minute))
+	if err != nil || s.Ended == nil || s.Folder != "app-feat" || State(s, start.Add(11*time.Minute)) != StateStopped {
+		t.Fatalf("Stop = %+v, %v", s, err)
+	}
+
+	// Resuming reopens the same session
+	s, _ = Touch(store, "/w", "s1", "/w/app-feat", start.Add(time.Hour))
+	if s.Ended != nil || !s.Started.Equal(start) {
+		t.Errorf("expected resumed session to keep its start and clear its end, got %+v", s)
+	}
+	sessions, _ := store.ReadSessions()
+	if len(sessions) != 1 {
+		t.Errorf("expected one session, got %+v", sessions)
+	}
+}
+
+func TestByFolderAndUnattended(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ended := now.Add(-time.Minute)
+	sessions := []jsonl.SessionEntry{
+		{ID: "old", Folder: "a", LastActivity: now.Add(-time.Hour), Ended: &ended},
+		{ID: "new", Folder: "a", LastActivity: now.Add(-time.Minute)},
+		{ID: "stopped", Folder: "b", LastActivity: now.Add(-time.Minute), Ended: &ended},
+		{ID: "outside", LastActivity: now},
+	}
+	latest := ByFolder(sessions)
+	if len(latest) != 2 || latest["a"].ID != "new" {
+		t.Fatalf("unexpected ByFolder: %+v", latest)
+	}
+
+	a, b := latest["a"], latest["b"]
+	if Unattended(true, &a, now) {
+		t.Error("dirty folder with an active session is attended")
+	}
+	if !Unattended(true, &b, now) || !Unattended(true, nil, now) {
+		t.Error("dirty folder without a live session should be unattended")
+	}
+	if Unattended(false, nil, now) {
+		t.Error("clean folder is never unattended")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	store := jsonl.NewStore(t.TempDir())
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	Touch(store, "/w", "old", "/w/a", now.Add(-2*Retention))
+	Touch(store, "/w", "recent", "/w/a", now.Add(-time.Hour))
+
+	if n, err := Prune(store, now); err != nil || n != 1 {
+		t.Fatalf("Prune = %d, %v", n, err)
+	}
+	sessions, _ := store.ReadSessions()
+	if len(sessions) != 1 || sessions[0].ID != "recent" {
+		t.Errorf("unexpected sessions after prune: %+v", sessions)
+	}
+}
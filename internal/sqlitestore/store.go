@@ -0,0 +1,548 @@
+// Package sqlitestore implements jsonl.Store on top of an SQLite database.
+//
+// Each record is kept in its JSONL encoding next to indexed key columns, so
+// records round-trip through import and export unchanged and older schema
+// versions are upgraded on read exactly like the files.
+package sqlitestore
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+	_ "modernc.org/sqlite" // pure-Go driver, registers "sqlite"
+)
+
+// DefaultFile is the database file name inside the workspace
+const DefaultFile = "bearing.db"
+
+const schema = `
+CREATE TABLE IF NOT EXISTS workflow (
+	seq    INTEGER PRIMARY KEY,
+	repo   TEXT NOT NULL,
+	branch TEXT NOT NULL,
+	record TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS workflow_repo_branch ON workflow (repo, branch);
+
+CREATE TABLE IF NOT EXISTS local (
+	seq    INTEGER PRIMARY KEY,
+	folder TEXT NOT NULL,
+	repo   TEXT NOT NULL,
+	record TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS local_folder ON local (folder);
+CREATE INDEX IF NOT EXISTS local_repo ON local (repo);
+
+CREATE TABLE IF NOT EXISTS health (
+	seq    INTEGER PRIMARY KEY,
+	folder TEXT NOT NULL,
+	record TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS health_folder ON health (folder);
+
+CREATE TABLE IF NOT EXISTS projects (
+	seq    INTEGER PRIMARY KEY,
+	name   TEXT NOT NULL,
+	record TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS projects_name ON projects (name);
+
+CREATE TABLE IF NOT EXISTS leases (
+	seq    INTEGER PRIMARY KEY,
+	folder TEXT NOT NULL,
+	record TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS leases_folder ON leases (folder);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	seq    INTEGER PRIMARY KEY,
+	id     TEXT NOT NULL,
+	folder TEXT NOT NULL,
+	record TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sessions_id ON sessions (id);
+CREATE INDEX IF NOT EXISTS sessions_folder ON sessions (folder);
+
+CREATE TABLE IF NOT EXISTS audit (
+	seq    INTEGER PRIMARY KEY,
+	folder TEXT NOT NULL,
+	record TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_folder ON audit (folder);
+
+CREATE TABLE IF NOT EXISTS allocations (
+	seq    INTEGER PRIMARY KEY,
+	folder TEXT NOT NULL,
+	record TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS allocations_folder ON allocations (folder);
+`
+
+// Store keeps workspace state in an SQLite database
+type Store struct {
+	path string
+
+	once sync.Once
+	db   *sql.DB
+	err  error
+}
+
+var _ jsonl.Store = (*Store)(nil)
+
+// New returns a store backed by the database at path. The database is
+// opened and its schema created on first use.
+func New(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the database file path
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func (s *Store) open() (*sql.DB, error) {
+	s.once.Do(func() {
+		// Write transactions take the lock up front so concurrent
+		// read-modify-write cycles serialize instead of failing on upgrade
+		dsn := s.path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+		db, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			s.err = fmt.Errorf("failed to open %s: %w", s.path, err)
+			return
+		}
+		if _, err := db.Exec(schema); err != nil {
+			db.Close()
+			s.err = fmt.Errorf("failed to create schema in %s: %w", s.path, err)
+			return
+		}
+		s.db = db
+	})
+	return s.db, s.err
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// table maps one kind of entry to its SQL table
+type table[T any] struct {
+	name string   // SQL table
+	file string   // JSONL file, for schema versioning
+	cols []string // indexed key columns
+	keys func(T) []any
+}
+
+var (
+	workflowTable = table[jsonl.WorkflowEntry]{
+		name: "workflow",
+		file: jsonl.WorkflowSchema.File,
+		cols: []string{"repo", "branch"},
+		keys: func(e jsonl.WorkflowEntry) []any { return []any{e.Repo, e.Branch} },
+	}
+	localTable = table[jsonl.LocalEntry]{
+		name: "local",
+		file: jsonl.LocalSchema.File,
+		cols: []string{"folder", "repo"},
+		keys: func(e jsonl.LocalEntry) []any { return []any{e.Folder, e.Repo} },
+	}
+	healthTable = table[jsonl.HealthEntry]{
+		name: "health",
+		file: jsonl.HealthSchema.File,
+		cols: []string{"folder"},
+		keys: func(e jsonl.HealthEntry) []any { return []any{e.Folder} },
+	}
+	projectsTable = table[jsonl.ProjectEntry]{
+		name: "projects",
+		file: jsonl.ProjectSchema.File,
+		cols: []string{"name"},
+		keys: func(e jsonl.ProjectEntry) []any { return []any{e.Name} },
+	}
+	leasesTable = table[jsonl.LeaseEntry]{
+		name: "leases",
+		file: jsonl.LeaseSchema.File,
+		cols: []string{"folder"},
+		keys: func(e jsonl.LeaseEntry) []any { return []any{e.Folder} },
+	}
+	sessionsTable = table[jsonl.SessionEntry]{
+		name: "sessions",
+		file: jsonl.SessionSchema.File,
+		cols: []string{"id", "folder"},
+		keys: func(e jsonl.SessionEntry) []any { return []any{e.ID, e.Folder} },
+	}
+	auditTable = table[jsonl.AuditEntry]{
+		name: "audit",
+		file: jsonl.AuditSchema.File,
+		cols: []string{"folder"},
+		keys: func(e jsonl.AuditEntry) []any { return []any{e.Folder} },
+	}
+	allocationsTable = table[jsonl.AllocationEntry]{
+		name: "allocations",
+		file: jsonl.AllocationSchema.File,
+		cols: []string{"folder"},
+		keys: func(e jsonl.AllocationEntry) []any { return []any{e.Folder} },
+	}
+)
+
+// query returns the entries matching where (an SQL condition, may be
+// empty) in insertion order
+func (t table[T]) query(q querier, where string, args ...any) ([]T, error) {
+	stmt := "SELECT record FROM " + t.name
+	if where != "" {
+		stmt += " WHERE " + where
+	}
+	rows, err := q.Query(stmt+" ORDER BY seq", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []T
+	for rows.Next() {
+		var record []byte
+		if err := rows.Scan(&record); err != nil {
+			return nil, err
+		}
+		var entry T
+		if err := jsonl.UnmarshalRecord(t.file, record, &entry); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (t table[T]) insert(q querier, entry T) error {
+	record, err := jsonl.MarshalRecord(t.file, entry)
+	if err != nil {
+		return err
+	}
+	placeholders := strings.Repeat("?, ", len(t.cols)) + "?"
+	stmt := fmt.Sprintf("INSERT INTO %s (%s, record) VALUES (%s)", t.name, strings.Join(t.cols, ", "), placeholders)
+	_, err = q.Exec(stmt, append(t.keys(entry), string(record))...)
+	return err
+}
+
+// replace deletes every row and inserts entries in order
+func (t table[T]) replace(q querier, entries []T) error {
+	if _, err := q.Exec("DELETE FROM " + t.name); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := t.insert(q, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readAll[T any](s *Store, t table[T]) ([]T, error) {
+	return readWhere(s, t, "")
+}
+
+// readWhere returns the entries of t matching where, which should test
+// indexed key columns
+func readWhere[T any](s *Store, t table[T], where string, args ...any) ([]T, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	return t.query(db, where, args...)
+}
+
+// withTx runs fn in a write transaction, committing if it succeeds
+func (s *Store) withTx(fn func(tx *sql.Tx) error) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func writeAll[T any](s *Store, t table[T], entries []T) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return t.replace(tx, entries)
+	})
+}
+
+func insertOne[T any](s *Store, t table[T], entry T) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	return t.insert(db, entry)
+}
+
+func update[T any](s *Store, t table[T], fn func([]T) ([]T, error)) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		entries, err := t.query(tx, "")
+		if err != nil {
+			return err
+		}
+		entries, err = fn(entries)
+		if err != nil {
+			return err
+		}
+		return t.replace(tx, entries)
+	})
+}
+
+// ReadWorkflow reads all workflow entries
+func (s *Store) ReadWorkflow() ([]jsonl.WorkflowEntry, error) {
+	return readAll(s, workflowTable)
+}
+
+// ReadLocal reads all local entries
+func (s *Store) ReadLocal() ([]jsonl.LocalEntry, error) {
+	return readAll(s, localTable)
+}
+
+// ReadHealth reads all health entries
+func (s *Store) ReadHealth() ([]jsonl.HealthEntry, error) {
+	return readAll(s, healthTable)
+}
+
+// ReadProjects reads all project entries
+func (s *Store) ReadProjects() ([]jsonl.ProjectEntry, error) {
+	return readAll(s, projectsTable)
+}
+
+// ReadLeases reads all lease entries
+func (s *Store) ReadLeases() ([]jsonl.LeaseEntry, error) {
+	return readAll(s, leasesTable)
+}
+
+// ReadSessions reads all session entries
+func (s *Store) ReadSessions() ([]jsonl.SessionEntry, error) {
+	return readAll(s, sessionsTable)
+}
+
+// ReadAudit reads all audit entries
+func (s *Store) ReadAudit() ([]jsonl.AuditEntry, error) {
+	return readAll(s, auditTable)
+}
+
+// ReadAllocations reads all allocation entries
+func (s *Store) ReadAllocations() ([]jsonl.AllocationEntry, error) {
+	return readAll(s, allocationsTable)
+}
+
+// WriteWorkflow writes all workflow entries (overwrites)
+func (s *Store) WriteWorkflow(entries []jsonl.WorkflowEntry) error {
+	return writeAll(s, workflowTable, entries)
+}
+
+// WriteLocal writes all local entries (overwrites)
+func (s *Store) WriteLocal(entries []jsonl.LocalEntry) error {
+	return writeAll(s, localTable, entries)
+}
+
+// WriteHealth writes all health entries (overwrites)
+func (s *Store) WriteHealth(entries []jsonl.HealthEntry) error {
+	return writeAll(s, healthTable, entries)
+}
+
+// WriteProjects writes all project entries (overwrites)
+func (s *Store) WriteProjects(entries []jsonl.ProjectEntry) error {
+	return writeAll(s, projectsTable, entries)
+}
+
+// WriteLeases writes all lease entries (overwrites)
+func (s *Store) WriteLeases(entries []jsonl.LeaseEntry) error {
+	return writeAll(s, leasesTable, entries)
+}
+
+// WriteSessions writes all session entries (overwrites)
+func (s *Store) WriteSessions(entries []jsonl.SessionEntry) error {
+	return writeAll(s, sessionsTable, entries)
+}
+
+// WriteAudit writes all audit entries (overwrites)
+func (s *Store) WriteAudit(entries []jsonl.AuditEntry) error {
+	return writeAll(s, auditTable, entries)
+}
+
+// WriteAllocations writes all allocation entries (overwrites)
+func (s *Store) WriteAllocations(entries []jsonl.AllocationEntry) error {
+	return writeAll(s, allocationsTable, entries)
+}
+
+// AppendWorkflow appends a workflow entry
+func (s *Store) AppendWorkflow(entry jsonl.WorkflowEntry) error {
+	return insertOne(s, workflowTable, entry)
+}
+
+// AppendLocal appends a local entry
+func (s *Store) AppendLocal(entry jsonl.LocalEntry) error {
+	return insertOne(s, localTable, entry)
+}
+
+// AppendAudit appends an audit entry
+func (s *Store) AppendAudit(entry jsonl.AuditEntry) error {
+	return insertOne(s, auditTable, entry)
+}
+
+// FindLocal returns the local entry for folder, or nil if none
+func (s *Store) FindLocal(folder string) (*jsonl.LocalEntry, error) {
+	entries, err := readWhere(s, localTable, "folder = ?", folder)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// FindHealth returns the health entry for folder, or nil if none
+func (s *Store) FindHealth(folder string) (*jsonl.HealthEntry, error) {
+	entries, err := readWhere(s, healthTable, "folder = ?", folder)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// FindWorkflow returns the newest workflow entry for branch of repo, or
+// nil if none
+func (s *Store) FindWorkflow(repo, branch string) (*jsonl.WorkflowEntry, error) {
+	entries, err := readWhere(s, workflowTable, "repo = ? AND branch = ?", repo, branch)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[len(entries)-1], nil
+}
+
+// FindAllocation returns the allocation of folder, or nil if none
+func (s *Store) FindAllocation(folder string) (*jsonl.AllocationEntry, error) {
+	entries, err := readWhere(s, allocationsTable, "folder = ?", folder)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// LeasesFor returns the leases on folder, expired or not
+func (s *Store) LeasesFor(folder string) ([]jsonl.LeaseEntry, error) {
+	return readWhere(s, leasesTable, "folder = ?", folder)
+}
+
+// SessionsFor returns the sessions seen in folder
+func (s *Store) SessionsFor(folder string) ([]jsonl.SessionEntry, error) {
+	return readWhere(s, sessionsTable, "folder = ?", folder)
+}
+
+// UpdateWorkflow applies fn to the workflow entries in one transaction
+func (s *Store) UpdateWorkflow(fn func([]jsonl.WorkflowEntry) ([]jsonl.WorkflowEntry, error)) error {
+	return update(s, workflowTable, fn)
+}
+
+// UpdateLocal applies fn to the local entries in one transaction
+func (s *Store) UpdateLocal(fn func([]jsonl.LocalEntry) ([]jsonl.LocalEntry, error)) error {
+	return update(s, localTable, fn)
+}
+
+// UpdateHealth applies fn to the health entries in one transaction
+func (s *Store) UpdateHealth(fn func([]jsonl.HealthEntry) ([]jsonl.HealthEntry, error)) error {
+	return update(s, healthTable, fn)
+}
+
+// UpdateProjects applies fn to the project entries in one transaction
+func (s *Store) UpdateProjects(fn func([]jsonl.ProjectEntry) ([]jsonl.ProjectEntry, error)) error {
+	return update(s, projectsTable, fn)
+}
+
+// UpdateLeases applies fn to the lease entries in one transaction
+func (s *Store) UpdateLeases(fn func([]jsonl.LeaseEntry) ([]jsonl.LeaseEntry, error)) error {
+	return update(s, leasesTable, fn)
+}
+
+// UpdateSessions applies fn to the session entries in one transaction
+func (s *Store) UpdateSessions(fn func([]jsonl.SessionEntry) ([]jsonl.SessionEntry, error)) error {
+	return update(s, sessionsTable, fn)
+}
+
+// UpdateAllocations applies fn to the allocation entries in one transaction
+func (s *Store) UpdateAllocations(fn func([]jsonl.AllocationEntry) ([]jsonl.AllocationEntry, error)) error {
+	return update(s, allocationsTable, fn)
+}
+
+// Update runs fn inside one transaction over the workflow and local
+// entries. If fn returns an error nothing is written.
+func (s *Store) Update(fn func(tx jsonl.Tx) error) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		t := &sqlTx{tx: tx}
+		if err := fn(t); err != nil {
+			return err
+		}
+		if t.workflowDirty {
+			if err := workflowTable.replace(tx, t.workflow); err != nil {
+				return err
+			}
+		}
+		if t.localDirty {
+			return localTable.replace(tx, t.local)
+		}
+		return nil
+	})
+}
+
+// sqlTx buffers entries read and set during Update
+type sqlTx struct {
+	tx *sql.Tx
+
+	workflow       []jsonl.WorkflowEntry
+	workflowLoaded bool
+	workflowDirty  bool
+
+	local       []jsonl.LocalEntry
+	localLoaded bool
+	localDirty  bool
+}
+
+func (t *sqlTx) Workflow() ([]jsonl.WorkflowEntry, error) {
+	if !t.workflowLoaded {
+		entries, err := workflowTable.query(t.tx, "")
+		if err != nil {
+			return nil, err
+		}
+		t.workflow, t.workflowLoaded = entries, true
+	}
+	return t.workflow, nil
+}
+
+func (t *sqlTx) SetWorkflow(entries []jsonl.WorkflowEntry) {
+	t.workflow, t.workflowLoaded, t.workflowDirty = entries, true, true
+}
+
+func (t *sqlTx) Local() ([]jsonl.LocalEntry, error) {
+	if !t.localLoaded {
+		entries, err := localTable.query(t.tx, "")
+		if err != nil {
+			return nil, err
+		}
+		t.local, t.localLoaded = entries, true
+	}
+	return t.local, nil
+}
+
+func (t *sqlTx) SetLocal(entries []jsonl.LocalEntry) {
+	t.local, t.localLoaded, t.localDirty = entries, true, true
+}
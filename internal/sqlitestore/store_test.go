@@ -0,0 +1,203 @@
+package sqlitestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joshribakoff/bearing/internal/jsonl"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s := New(filepath.Join(t.TempDir(), DefaultFile))
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestReadWriteAppend(t *testing.T) {
+	s := newTestStore(t)
+
+	if entries, err := s.ReadLocal(); err != nil || len(entries) != 0 {
+		t.Fatalf("expected empty store, got %v, %v", entries, err)
+	}
+
+	locals := []jsonl.LocalEntry{
+		{Folder: "b", Repo: "b", Branch: "main", Base: true},
+		{Folder: "a", Repo: "a", Branch: "main", Base: true},
+	}
+	if err := s.WriteLocal(locals); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AppendLocal(jsonl.LocalEntry{Folder: "a-feat", Repo: "a", Branch: "feat"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.ReadLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Folder != "b" || got[2].Folder != "a-feat" {
+		t.Fatalf("expected insertion order preserved, got %+v", got)
+	}
+	if got[0].V != jsonl.LocalSchema.Version() {
+		t.Errorf("expected version stamped, got %d", got[0].V)
+	}
+
+	found, err := s.FindLocal("a-feat")
+	if err != nil || found == nil || found.Branch != "feat" {
+		t.Errorf("FindLocal: %+v, %v", found, err)
+	}
+	if found, _ := s.FindLocal("missing"); found != nil {
+		t.Errorf("expected nil for missing folder, got %+v", found)
+	}
+}
+
+func TestUpdateAbortsOnError(t *testing.T) {
+	s := newTestStore(t)
+	s.WriteWorkflow([]jsonl.WorkflowEntry{{Repo: "a", Branch: "x", Status: "active"}})
+
+	err := s.Update(func(tx jsonl.Tx) error {
+		tx.SetWorkflow(nil)
+		tx.SetLocal([]jsonl.LocalEntry{{Folder: "a-x"}})
+		return errors.New("abort")
+	})
+	if err == nil || err.Error() != "abort" {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+
+	workflow, _ := s.ReadWorkflow()
+	local, _ := s.ReadLocal()
+	if len(workflow) != 1 || len(local) != 0 {
+		t.Errorf("expected nothing written, got %d workflow, %d local", len(workflow), len(local))
+	}
+}
+
+func TestUpdateWorkflowConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	const n = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate stores stand in for separate bearing processes
+			s := New(path)
+			defer s.Close()
+			errs <- s.UpdateWorkflow(func(entries []jsonl.WorkflowEntry) ([]jsonl.WorkflowEntry, error) {
+				return append(entries, jsonl.WorkflowEntry{Repo: "r", Branch: fmt.Sprint(i), Status: "active"}), nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New(path)
+	defer s.Close()
+	entries, _ := s.ReadWorkflow()
+	if len(entries) != n {
+		t.Errorf("expected %d entries, got %d", n, len(entries))
+	}
+}
+
+func TestCopyRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	legacy := `{"repo":"a","branch":"x","basedOn":"unknown","status":"active","created":"2025-01-02T03:04:05Z"}` + "\n"
+	os.WriteFile(filepath.Join(dir, "workflow.jsonl"), []byte(legacy), 0644)
+	files := jsonl.NewStore(dir)
+	files.WriteLocal([]jsonl.LocalEntry{{Folder: "a", Repo: "a", Branch: "main", Base: true}})
+	files.WriteProjects([]jsonl.ProjectEntry{{Name: "a", GitHubRepo: "o/a", Path: "a"}})
+	files.WriteLeases([]jsonl.LeaseEntry{{Folder: "a", Owner: "s1", Expires: created, TTLSeconds: 60}})
+	files.WriteSessions([]jsonl.SessionEntry{{ID: "s1", Cwd: "/w/a", Folder: "a", Started: created, LastActivity: created}})
+	files.AppendAudit(jsonl.AuditEntry{Time: created, Tool: "Bash", Folder: "a", Command: "git commit", Rule: "commit", Decision: "deny"})
+	files.WriteAllocations([]jsonl.AllocationEntry{{Folder: "a-x", Repo: "a", Slot: 2, Port: 3120, PortCount: 10, Database: "a_x", CacheDir: "/c/a-x", Allocated: created}})
+
+	db := newTestStore(t)
+	if err := jsonl.Copy(db, files); err != nil {
+		t.Fatal(err)
+	}
+	workflow, _ := db.ReadWorkflow()
+	if len(workflow) != 1 || workflow[0].BasedOn != "" || !workflow[0].Created.Equal(created) {
+		t.Fatalf("expected upgraded workflow entry, got %+v", workflow)
+	}
+
+	// Export into a fresh workspace and compare
+	out := jsonl.NewStore(t.TempDir())
+	if err := jsonl.Copy(out, db); err != nil {
+		t.Fatal(err)
+	}
+	projects, _ := out.ReadProjects()
+	if len(projects) != 1 || projects[0].GitHubRepo != "o/a" {
+		t.Errorf("unexpected projects: %+v", projects)
+	}
+	leases, _ := out.ReadLeases()
+	if len(leases) != 1 || leases[0].Owner != "s1" || !leases[0].Expires.Equal(created) {
+		t.Errorf("unexpected leases: %+v", leases)
+	}
+	sessions, _ := out.ReadSessions()
+	if len(sessions) != 1 || sessions[0].Folder != "a" || sessions[0].Ended != nil {
+		t.Errorf("unexpected sessions: %+v", sessions)
+	}
+	audit, _ := out.ReadAudit()
+	if len(audit) != 1 || audit[0].Rule != "commit" || audit[0].Decision != "deny" {
+		t.Errorf("unexpected audit: %+v", audit)
+	}
+	allocations, _ := out.ReadAllocations()
+	if len(allocations) != 1 || allocations[0].Port != 3120 || allocations[0].Database != "a_x" {
+		t.Errorf("unexpected allocations: %+v", allocations)
+	}
+	data, _ := os.ReadFile(out.WorkflowPath())
+	if !strings.Contains(string(data), `"v":1`) || strings.Contains(string(data), "unknown") {
+		t.Errorf("unexpected exported workflow: %s", data)
+	}
+}
+
+func TestKeyedLookups(t *testing.T) {
+	// Both backends answer keyed lookups the same way
+	for name, s := range map[string]jsonl.Store{"sqlite": newTestStore(t), "jsonl": jsonl.NewStore(t.TempDir())} {
+		s.WriteWorkflow([]jsonl.WorkflowEntry{
+			{Repo: "a", Branch: "x", BasedOn: "main"},
+			{Repo: "b", Branch: "x"},
+			{Repo: "a", Branch: "x", BasedOn: "dev"},
+		})
+		s.WriteHealth([]jsonl.HealthEntry{{Folder: "a", Unpushed: 1}, {Folder: "a-x", Unpushed: 2}})
+		s.WriteAllocations([]jsonl.AllocationEntry{{Folder: "a-x", Port: 3120}})
+		s.WriteLeases([]jsonl.LeaseEntry{{Folder: "a-x", Owner: "s1"}, {Folder: "a", Owner: "s2"}, {Folder: "a-x", Owner: "s3"}})
+		s.WriteSessions([]jsonl.SessionEntry{{ID: "s1", Folder: "a-x"}, {ID: "s2", Folder: "a"}})
+
+		if w, err := s.FindWorkflow("a", "x"); err != nil || w == nil || w.BasedOn != "dev" {
+			t.Errorf("%s: FindWorkflow = %+v, %v; want the newest entry", name, w, err)
+		}
+		if w, _ := s.FindWorkflow("c", "x"); w != nil {
+			t.Errorf("%s: expected no workflow entry, got %+v", name, w)
+		}
+		if h, err := s.FindHealth("a-x"); err != nil || h == nil || h.Unpushed != 2 {
+			t.Errorf("%s: FindHealth = %+v, %v", name, h, err)
+		}
+		if a, err := s.FindAllocation("a-x"); err != nil || a == nil || a.Port != 3120 {
+			t.Errorf("%s: FindAllocation = %+v, %v", name, a, err)
+		}
+		if a, _ := s.FindAllocation("a"); a != nil {
+			t.Errorf("%s: expected no allocation, got %+v", name, a)
+		}
+		if l, err := s.LeasesFor("a-x"); err != nil || len(l) != 2 || l[0].Owner != "s1" || l[1].Owner != "s3" {
+			t.Errorf("%s: LeasesFor = %+v, %v", name, l, err)
+		}
+		if ss, err := s.SessionsFor("a"); err != nil || len(ss) != 1 || ss[0].ID != "s2" {
+			t.Errorf("%s: SessionsFor = %+v, %v", name, ss, err)
+		}
+	}
+}
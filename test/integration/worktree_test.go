@@ -2,10 +2,14 @@ package integration
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/joshribakoff/bearing/internal/jsonl"
 	"github.com/joshribakoff/bearing/test/testutil"
@@ -225,3 +229,779 @@ func TestWorktreeCheckNonExistentFolder(t *testing.T) {
 		t.Errorf("expected error for non-existent folder, got success: %s", output)
 	}
 }
+
+func TestDefaultBranchDetection(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+	testutil.InitWorkspace(t, tmpDir)
+
+	// Origin lives outside the workspace and defaults to develop
+	origin := testutil.CreateTestRepo(t, t.TempDir(), "origin")
+	exec.Command("git", "-C", origin, "branch", "-m", "main", "develop").Run()
+	if out, err := exec.Command("git", "clone", "--quiet", origin, filepath.Join(tmpDir, "app")).CombinedOutput(); err != nil {
+		t.Fatalf("clone failed: %v\n%s", err, out)
+	}
+	os.WriteFile(filepath.Join(tmpDir, "projects.jsonl"), []byte(`{"name":"app","github_repo":"acme/app","path":"app"}`+"\n"), 0644)
+
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "new", "app", "feature-y")
+	if err != nil {
+		t.Fatalf("worktree new failed: %v\nOutput: %s", err, output)
+	}
+
+	store := jsonl.NewStore(tmpDir)
+	workflows, _ := store.ReadWorkflow()
+	if len(workflows) != 1 || workflows[0].BasedOn != "develop" {
+		t.Errorf("expected worktree based on develop, got %+v", workflows)
+	}
+	projects, _ := store.ReadProjects()
+	if len(projects) != 1 || projects[0].DefaultBranch != "develop" {
+		t.Errorf("expected default branch cached in projects.jsonl, got %+v", projects)
+	}
+
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "sync"); err != nil {
+		t.Fatalf("worktree sync failed: %v\nOutput: %s", err, output)
+	}
+	locals, _ := store.ReadLocal()
+	for _, l := range locals {
+		if l.Base != (l.Folder == "app") {
+			t.Errorf("unexpected base flag for %+v", l)
+		}
+	}
+}
+
+func TestWorktreeRefresh(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "register", "test-repo"); err != nil {
+		t.Fatalf("register failed: %v\nOutput: %s", err, output)
+	}
+
+	store := jsonl.NewStore(tmpDir)
+	store.WriteHealth([]jsonl.HealthEntry{{Folder: "other", Unpushed: 4}})
+	os.WriteFile(filepath.Join(repoPath, "new.txt"), []byte("x"), 0644)
+
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "refresh", "test-repo", "--json")
+	if err != nil {
+		t.Fatalf("refresh failed: %v\nOutput: %s", err, output)
+	}
+	var h jsonl.HealthEntry
+	if err := json.Unmarshal([]byte(output), &h); err != nil {
+		t.Fatalf("invalid JSON: %v\nOutput: %s", err, output)
+	}
+	if h.Folder != "test-repo" || !h.Dirty {
+		t.Errorf("expected dirty test-repo, got %+v", h)
+	}
+
+	// The other worktree's cached health is kept
+	health, _ := store.ReadHealth()
+	if len(health) != 2 || health[0].Folder != "other" {
+		t.Errorf("expected refresh to merge into health.jsonl, got %+v", health)
+	}
+
+	if _, err := testutil.RunBearing(t, tmpDir, "worktree", "refresh", "missing"); err == nil {
+		t.Error("expected error for unknown folder")
+	}
+}
+
+func TestWorktreeLeases(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "register", "test-repo"); err != nil {
+		t.Fatalf("register failed: %v\nOutput: %s", err, output)
+	}
+
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "claim", "test-repo", "--owner", "s1", "--ttl", "1h"); err != nil {
+		t.Fatalf("claim failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "claim", "test-repo", "--owner", "s2"); err == nil {
+		t.Errorf("expected second claim to fail, got: %s", output)
+	}
+
+	hook := func(session string) map[string]any {
+		t.Helper()
+		input := fmt.Sprintf(`{"session_id":%q,"cwd":%q}`, session, filepath.Join(repoPath, "sub"))
+		output, err := testutil.RunBearingInput(t, tmpDir, input, "worktree", "check", "--json")
+		if err != nil {
+			t.Fatalf("check failed: %v\nOutput: %s", err, output)
+		}
+		var out map[string]any
+		if err := json.Unmarshal([]byte(output), &out); err != nil {
+			t.Fatalf("invalid JSON: %v\nOutput: %s", err, output)
+		}
+		return out
+	}
+	if out := hook("s2"); out["decision"] != "block" {
+		t.Errorf("expected another session to be blocked, got %v", out)
+	}
+	if out := hook("s1"); out["decision"] != nil {
+		t.Errorf("expected the owner not to be blocked, got %v", out)
+	}
+
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "heartbeat", "test-repo", "--owner", "s1"); err != nil {
+		t.Fatalf("heartbeat failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "release", "test-repo", "--owner", "s1"); err != nil {
+		t.Fatalf("release failed: %v\nOutput: %s", err, output)
+	}
+	if out := hook("s2"); out["decision"] != nil {
+		t.Errorf("expected no block after release, got %v", out)
+	}
+}
+
+func TestSessionHooks(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "register", "test-repo"); err != nil {
+		t.Fatalf("register failed: %v\nOutput: %s", err, output)
+	}
+	os.WriteFile(filepath.Join(repoPath, "wip.txt"), []byte("x"), 0644)
+
+	input := fmt.Sprintf(`{"session_id":"s1","cwd":%q,"hook_event_name":"SessionStart","source":"startup"}`, repoPath)
+	if output, err := testutil.RunBearingInput(t, tmpDir, input, "hook", "session-start"); err != nil {
+		t.Fatalf("session-start failed: %v\nOutput: %s", err, output)
+	}
+
+	status := func() map[string]any {
+		t.Helper()
+		output, err := testutil.RunBearing(t, tmpDir, "worktree", "status", "--json")
+		if err != nil {
+			t.Fatalf("status failed: %v\nOutput: %s", err, output)
+		}
+		var statuses []map[string]any
+		if err := json.Unmarshal([]byte(output), &statuses); err != nil || len(statuses) != 1 {
+			t.Fatalf("unexpected status: %v\nOutput: %s", err, output)
+		}
+		return statuses[0]
+	}
+	if s := status(); s["sessionState"] != "active" || s["unattended"] != nil {
+		t.Errorf("expected active session, got %v", s)
+	}
+
+	// Stop fires at the end of every turn; the session stays live
+	input = fmt.Sprintf(`{"session_id":"s1","cwd":%q,"hook_event_name":"Stop"}`, repoPath)
+	if output, err := testutil.RunBearingInput(t, tmpDir, input, "hook", "stop"); err != nil {
+		t.Fatalf("stop failed: %v\nOutput: %s", err, output)
+	}
+	if s := status(); s["sessionState"] != "active" || s["unattended"] != nil {
+		t.Errorf("expected session still active after a turn, got %v", s)
+	}
+
+	input = fmt.Sprintf(`{"session_id":"s1","cwd":%q,"hook_event_name":"SessionEnd","reason":"exit"}`, repoPath)
+	if output, err := testutil.RunBearingInput(t, tmpDir, input, "hook", "session-end"); err != nil {
+		t.Fatalf("session-end failed: %v\nOutput: %s", err, output)
+	}
+	if s := status(); s["sessionState"] != "stopped" || s["unattended"] != true {
+		t.Errorf("expected dirty worktree without a live session to be unattended, got %v", s)
+	}
+
+	for _, hook := range []string{"stop", "session-end"} {
+		if _, err := testutil.RunBearingInput(t, tmpDir, `{}`, "hook", hook); err == nil {
+			t.Errorf("expected %s to fail without a session_id", hook)
+		}
+	}
+}
+
+func TestPreToolUseHook(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "register", "test-repo"); err != nil {
+		t.Fatalf("register failed: %v\nOutput: %s", err, output)
+	}
+
+	input := fmt.Sprintf(`{"session_id":"s1","cwd":%q,"hook_event_name":"PreToolUse","tool_name":"Bash","tool_input":{"command":"git checkout -b wip"}}`, repoPath)
+	output, err := testutil.RunBearingInput(t, tmpDir, input, "hook", "pre-tool-use")
+	if err != nil {
+		t.Fatalf("pre-tool-use failed: %v\nOutput: %s", err, output)
+	}
+	var resp struct {
+		HookSpecificOutput struct {
+			PermissionDecision       string `json:"permissionDecision"`
+			PermissionDecisionReason string `json:"permissionDecisionReason"`
+		} `json:"hookSpecificOutput"`
+	}
+	if err := json.Unmarshal([]byte(output), &resp); err != nil {
+		t.Fatalf("invalid hook output: %v\nOutput: %s", err, output)
+	}
+	if resp.HookSpecificOutput.PermissionDecision != "deny" || !strings.Contains(resp.HookSpecificOutput.PermissionDecisionReason, "test-repo") {
+		t.Errorf("expected deny for branch switch in base folder, got %+v", resp)
+	}
+
+	// Reads are not checked, and allowed calls print nothing
+	input = fmt.Sprintf(`{"cwd":%q,"tool_name":"Read","tool_input":{"file_path":"README.md"}}`, repoPath)
+	if output, err := testutil.RunBearingInput(t, tmpDir, input, "hook", "pre-tool-use"); err != nil || strings.TrimSpace(output) != "" {
+		t.Errorf("expected no output for a read, got %q (%v)", output, err)
+	}
+
+	// The policy is configurable
+	os.WriteFile(filepath.Join(tmpDir, ".bearing.yaml"), []byte("guard:\n  write: allow\n"), 0644)
+	input = fmt.Sprintf(`{"cwd":%q,"tool_name":"Write","tool_input":{"file_path":"notes.md"}}`, repoPath)
+	if output, err := testutil.RunBearingInput(t, tmpDir, input, "hook", "pre-tool-use"); err != nil || strings.TrimSpace(output) != "" {
+		t.Errorf("expected allowed write, got %q (%v)", output, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"decision":"deny"`) || !strings.Contains(lines[1], `"rule":"write"`) {
+		t.Errorf("unexpected audit log:\n%s", data)
+	}
+}
+
+func TestInitHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	settingsPath := filepath.Join(tmpDir, ".claude", "settings.json")
+	os.MkdirAll(filepath.Dir(settingsPath), 0755)
+	os.WriteFile(settingsPath, []byte(`{"model":"opus"}`), 0644)
+
+	output, err := testutil.RunBearing(t, tmpDir, "init", "--dry-run")
+	if err != nil {
+		t.Fatalf("init --dry-run failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "+++ "+settingsPath) || !strings.Contains(output, `"command": "bearing hook pre-tool-use"`) {
+		t.Errorf("expected diff adding hooks, got:\n%s", output)
+	}
+	if data, _ := os.ReadFile(settingsPath); string(data) != `{"model":"opus"}` {
+		t.Errorf("dry run wrote settings: %s", data)
+	}
+
+	if output, err := testutil.RunBearing(t, tmpDir, "init"); err != nil {
+		t.Fatalf("init failed: %v\nOutput: %s", err, output)
+	}
+	if output, _ := testutil.RunBearing(t, tmpDir, "init"); !strings.Contains(output, "already configured") {
+		t.Errorf("expected second init to change nothing, got:\n%s", output)
+	}
+
+	if output, err := testutil.RunBearing(t, tmpDir, "init", "--uninstall"); err != nil {
+		t.Fatalf("init --uninstall failed: %v\nOutput: %s", err, output)
+	}
+	var settings map[string]any
+	data, _ := os.ReadFile(settingsPath)
+	if err := json.Unmarshal(data, &settings); err != nil || settings["model"] != "opus" || settings["hooks"] != nil {
+		t.Errorf("expected only the original settings after uninstall, got %s", data)
+	}
+}
+
+func TestSessionStartContext(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "register", "test-repo"); err != nil {
+		t.Fatalf("register failed: %v\nOutput: %s", err, output)
+	}
+
+	input := fmt.Sprintf(`{"session_id":"s1","cwd":%q,"hook_event_name":"SessionStart","source":"startup"}`, repoPath)
+	output, err := testutil.RunBearingInput(t, tmpDir, input, "hook", "session-start")
+	if err != nil {
+		t.Fatalf("session-start failed: %v\nOutput: %s", err, output)
+	}
+	var resp struct {
+		HookSpecificOutput struct {
+			HookEventName     string `json:"hookEventName"`
+			AdditionalContext string `json:"additionalContext"`
+		} `json:"hookSpecificOutput"`
+	}
+	if err := json.Unmarshal([]byte(output), &resp); err != nil {
+		t.Fatalf("invalid hook output: %v\nOutput: %s", err, output)
+	}
+	if resp.HookSpecificOutput.HookEventName != "SessionStart" || !strings.Contains(resp.HookSpecificOutput.AdditionalContext, "base folder test-repo") {
+		t.Errorf("unexpected context: %+v", resp)
+	}
+
+	output, err = testutil.RunBearingInput(t, tmpDir, `{"cwd":"`+tmpDir+`"}`, "hook", "context", "--plain")
+	if err != nil {
+		t.Fatalf("hook context failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "Worktrees:\n- test-repo (main, base)") {
+		t.Errorf("unexpected workspace summary:\n%s", output)
+	}
+}
+
+func TestWorktreeBootstrap(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	os.WriteFile(filepath.Join(repoPath, ".env"), []byte("SECRET=1\n"), 0600)
+	config := "projects:\n  test-repo:\n    bootstrap:\n      copy: [.env]\n      run: [\"echo setup ran > setup.txt\"]\n"
+	os.WriteFile(filepath.Join(tmpDir, ".bearing.yaml"), []byte(config), 0644)
+
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "new", "test-repo", "feature-b")
+	if err != nil {
+		t.Fatalf("worktree new failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "bootstrap: copied .env") {
+		t.Errorf("expected streamed bootstrap output, got:\n%s", output)
+	}
+	worktreePath := filepath.Join(tmpDir, "test-repo-feature-b")
+	if data, _ := os.ReadFile(filepath.Join(worktreePath, "setup.txt")); string(data) != "setup ran\n" {
+		t.Errorf("expected setup command to run in the worktree, got %q", data)
+	}
+
+	workflows, _ := jsonl.NewStore(tmpDir).ReadWorkflow()
+	if len(workflows) != 1 || workflows[0].Bootstrap == nil || workflows[0].Bootstrap.Status != "ok" {
+		t.Fatalf("expected bootstrap recorded as ok, got %+v", workflows)
+	}
+
+	// A failing step keeps the worktree and records the failure
+	config = "bootstrap:\n  run: [\"exit 7\"]\n"
+	os.WriteFile(filepath.Join(tmpDir, ".bearing.yaml"), []byte(config), 0644)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "bootstrap", "test-repo-feature-b"); err == nil || !strings.Contains(output, "exit 7 failed") {
+		t.Errorf("expected bootstrap failure, got %v\nOutput: %s", err, output)
+	}
+	workflows, _ = jsonl.NewStore(tmpDir).ReadWorkflow()
+	if b := workflows[0].Bootstrap; b == nil || b.Status != "failed" || b.Error == "" {
+		t.Errorf("expected failure recorded, got %+v", b)
+	}
+}
+
+func TestWorktreeEnv(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	os.WriteFile(filepath.Join(tmpDir, ".bearing.yaml"), []byte("allocations:\n  portBase: 41000\n  portsPerWorktree: 5\n"), 0644)
+
+	for _, branch := range []string{"feature-a", "feature-b"} {
+		if output, err := testutil.RunBearing(t, tmpDir, "worktree", "new", "test-repo", branch); err != nil {
+			t.Fatalf("worktree new failed: %v\nOutput: %s", err, output)
+		}
+	}
+	allocations, _ := jsonl.NewStore(tmpDir).ReadAllocations()
+	if len(allocations) != 2 || allocations[0].Port == allocations[1].Port || allocations[0].Database == allocations[1].Database {
+		t.Fatalf("expected distinct allocations, got %+v", allocations)
+	}
+
+	// The generated file is written but not shown as a change
+	worktreePath := filepath.Join(tmpDir, "test-repo-feature-b")
+	data, err := os.ReadFile(filepath.Join(worktreePath, ".env.bearing"))
+	if err != nil || !strings.Contains(string(data), fmt.Sprintf("PORT=%d\n", allocations[1].Port)) {
+		t.Errorf("expected .env.bearing with the port, got %q (%v)", data, err)
+	}
+	status, _ := exec.Command("git", "-C", worktreePath, "status", "--porcelain").Output()
+	if len(status) != 0 {
+		t.Errorf("expected .env.bearing excluded from git, got %q", status)
+	}
+
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "env", "test-repo-feature-b", "--export")
+	if err != nil || !strings.Contains(output, fmt.Sprintf("export BEARING_PORT_RANGE=%d-%d\n", allocations[1].Port, allocations[1].Port+4)) {
+		t.Errorf("unexpected env output: %v\n%s", err, output)
+	}
+
+	// Cleanup frees the allocation and its cache dir
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "cleanup", "test-repo", "feature-b"); err != nil {
+		t.Fatalf("worktree cleanup failed: %v\nOutput: %s", err, output)
+	}
+	allocations, _ = jsonl.NewStore(tmpDir).ReadAllocations()
+	if len(allocations) != 1 || allocations[0].Folder != "test-repo-feature-a" {
+		t.Errorf("expected only feature-a allocated, got %+v", allocations)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".cache", "bearing", "test-repo-feature-b")); !os.IsNotExist(err) {
+		t.Errorf("expected cache dir removed, got %v", err)
+	}
+}
+
+func TestWorktreeExec(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	for _, branch := range []string{"feature-a", "feature-b"} {
+		if output, err := testutil.RunBearing(t, tmpDir, "worktree", "new", "test-repo", branch); err != nil {
+			t.Fatalf("worktree new failed: %v\nOutput: %s", err, output)
+		}
+	}
+	os.WriteFile(filepath.Join(tmpDir, "test-repo-feature-b", "wip.txt"), []byte("wip\n"), 0644)
+
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "exec", "--filter", "dirty", "--json", "--", "git", "status", "--short")
+	if err != nil {
+		t.Fatalf("worktree exec failed: %v\nOutput: %s", err, output)
+	}
+	var results []struct {
+		Folder   string `json:"folder"`
+		ExitCode int    `json:"exitCode"`
+		Output   string `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if len(results) != 1 || results[0].Folder != "test-repo-feature-b" || !strings.Contains(results[0].Output, "wip.txt") {
+		t.Errorf("expected only the dirty worktree, got %+v", results)
+	}
+
+	// A failure in any folder fails the whole run, after running everywhere
+	output, err = testutil.RunBearing(t, tmpDir, "worktree", "exec", "--repo", "test-repo", "--", "sh", "-c", `echo "in $BEARING_FOLDER"; test -f wip.txt`)
+	if err == nil || !strings.Contains(output, "failed in 1 of 2 folders: test-repo-feature-a (exit 1)") {
+		t.Errorf("expected one failure, got %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "test-repo-feature-a | in test-repo-feature-a") {
+		t.Errorf("expected prefixed output, got:\n%s", output)
+	}
+}
+
+func TestWorktreeCleanupPreflight(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "new", "test-repo", "feature-p"); err != nil {
+		t.Fatalf("worktree new failed: %v\nOutput: %s", err, output)
+	}
+	worktreePath := filepath.Join(tmpDir, "test-repo-feature-p")
+	os.WriteFile(filepath.Join(worktreePath, "wip.txt"), []byte("wip\n"), 0644)
+
+	// Uncommitted changes block cleanup; --dry-run only reports
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "cleanup", "test-repo", "feature-p")
+	if err == nil || !strings.Contains(output, "problem   uncommitted changes") || !strings.Contains(output, "--force") {
+		t.Fatalf("expected cleanup refused, got %v\nOutput: %s", err, output)
+	}
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "cleanup", "test-repo", "feature-p", "--dry-run"); err != nil || !strings.Contains(output, "will be removed") {
+		t.Errorf("unexpected dry run: %v\nOutput: %s", err, output)
+	}
+	if _, err := os.Stat(worktreePath); err != nil {
+		t.Fatalf("expected worktree kept: %v", err)
+	}
+
+	// Committed but unpushed work blocks too, as does someone else's lease
+	for _, args := range [][]string{{"add", "wip.txt"}, {"commit", "-m", "wip"}} {
+		if out, err := exec.Command("git", append([]string{"-C", worktreePath}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "claim", "test-repo-feature-p", "--owner", "s1"); err != nil {
+		t.Fatalf("claim failed: %v\nOutput: %s", err, output)
+	}
+	output, err = testutil.RunBearing(t, tmpDir, "worktree", "cleanup", "test-repo", "feature-p")
+	if err == nil || !strings.Contains(output, "1 unpushed commit(s)") || !strings.Contains(output, "claimed by s1") {
+		t.Fatalf("expected unpushed and lease problems, got %v\nOutput: %s", err, output)
+	}
+	// The lease holder's own lease doesn't count
+	output, _ = testutil.RunBearing(t, tmpDir, "worktree", "cleanup", "test-repo", "feature-p", "--owner", "s1", "--dry-run")
+	if strings.Contains(output, "claimed by") {
+		t.Errorf("expected owner's lease ignored, got:\n%s", output)
+	}
+
+	// --force with an archive keeps the commits in a tag and deletes the branch
+	output, err = testutil.RunBearing(t, tmpDir, "worktree", "cleanup", "test-repo", "feature-p", "--force", "--archive", "tag")
+	if err != nil {
+		t.Fatalf("forced cleanup failed: %v\nOutput: %s", err, output)
+	}
+	tags, _ := exec.Command("git", "-C", repoPath, "tag", "--list", "archive/feature-p/*").Output()
+	if len(strings.TrimSpace(string(tags))) == 0 {
+		t.Errorf("expected archive tag, got none\nOutput: %s", output)
+	}
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "refs/heads/feature-p").Run(); err == nil {
+		t.Error("expected archived branch deleted")
+	}
+	workflows, _ := jsonl.NewStore(tmpDir).ReadWorkflow()
+	if len(workflows) != 1 || workflows[0].Status != "cleaned" || !strings.HasPrefix(workflows[0].Archive, "archive/feature-p/") {
+		t.Errorf("expected archive recorded, got %+v", workflows)
+	}
+}
+
+func TestWorktreeGC(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	for _, branch := range []string{"merged", "dirty", "stale"} {
+		if output, err := testutil.RunBearing(t, tmpDir, "worktree", "new", "test-repo", branch); err != nil {
+			t.Fatalf("worktree new %s failed: %v\nOutput: %s", branch, err, output)
+		}
+	}
+	git := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2020-01-01T00:00:00Z", "GIT_AUTHOR_DATE=2020-01-01T00:00:00Z")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	// Each gets an old commit; merged's and dirty's land in main
+	for _, branch := range []string{"merged", "dirty", "stale"} {
+		wt := filepath.Join(tmpDir, "test-repo-"+branch)
+		os.WriteFile(filepath.Join(wt, branch+".txt"), []byte(branch+"\n"), 0644)
+		git(wt, "add", ".")
+		git(wt, "commit", "-m", branch)
+	}
+	git(repoPath, "merge", "--ff-only", "merged")
+	git(repoPath, "merge", "--no-edit", "dirty")
+	os.WriteFile(filepath.Join(tmpDir, "test-repo-dirty", "wip.txt"), []byte("wip\n"), 0644)
+
+	// Backdate creation and the HEAD reflogs so only the checks decide
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	store := jsonl.NewStore(tmpDir)
+	store.UpdateWorkflow(func(entries []jsonl.WorkflowEntry) ([]jsonl.WorkflowEntry, error) {
+		for i := range entries {
+			entries[i].Created = old
+		}
+		return entries, nil
+	})
+	for _, branch := range []string{"merged", "dirty", "stale"} {
+		os.Chtimes(filepath.Join(repoPath, ".git", "worktrees", "test-repo-"+branch, "logs", "HEAD"), old, old)
+	}
+
+	// Idle collection is off with --idle-days 0: stale isn't a candidate
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "gc", "--dry-run", "--idle-days", "0")
+	if err != nil {
+		t.Fatalf("gc --dry-run failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "test-repo-merged  contained  would clean") || strings.Contains(output, "test-repo-stale") {
+		t.Errorf("unexpected dry run:\n%s", output)
+	}
+	if !strings.Contains(output, "test-repo-dirty   contained  skipped") {
+		t.Errorf("expected dirty worktree skipped:\n%s", output)
+	}
+
+	output, err = testutil.RunBearing(t, tmpDir, "worktree", "gc", "--json", "--idle-days", "30")
+	if err != nil {
+		t.Fatalf("gc failed: %v\nOutput: %s", err, output)
+	}
+	var results []struct {
+		Folder   string   `json:"folder"`
+		Reason   string   `json:"reason"`
+		Outcome  string   `json:"outcome"`
+		Problems []string `json:"problems"`
+	}
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("invalid JSON: %v\nOutput: %s", err, output)
+	}
+	got := make(map[string]string)
+	for _, r := range results {
+		got[r.Folder] = r.Reason + " " + r.Outcome
+	}
+	want := map[string]string{
+		"test-repo-merged": "contained cleaned",
+		"test-repo-dirty":  "contained skipped",
+		"test-repo-stale":  "idle skipped", // its commit was never pushed
+	}
+	for folder, w := range want {
+		if got[folder] != w {
+			t.Errorf("%s: got %q, want %q\nOutput: %s", folder, got[folder], w, output)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "test-repo-merged")); !os.IsNotExist(err) {
+		t.Error("expected merged worktree removed")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "test-repo-dirty")); err != nil {
+		t.Error("expected dirty worktree kept")
+	}
+	workflows, _ := store.ReadWorkflow()
+	for _, w := range workflows {
+		switch w.Branch {
+		case "merged":
+			if w.Status != "merged" || w.GC == nil || w.GC.Outcome != "cleaned" || w.GC.By != "cli" {
+				t.Errorf("unexpected merged workflow: %+v", w)
+			}
+		case "dirty":
+			if w.Status != "active" || w.GC == nil || w.GC.Outcome != "skipped" || len(w.GC.Problems) == 0 {
+				t.Errorf("unexpected dirty workflow: %+v", w)
+			}
+		}
+	}
+}
+
+func TestWorktreeCleanupSquashMerged(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "new", "test-repo", "squashed"); err != nil {
+		t.Fatalf("worktree new failed: %v\nOutput: %s", err, output)
+	}
+	worktreePath := filepath.Join(tmpDir, "test-repo-squashed")
+	git := func(dir string, args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		os.WriteFile(filepath.Join(worktreePath, name), []byte(name+"\n"), 0644)
+		git(worktreePath, "add", name)
+		git(worktreePath, "commit", "-m", name)
+	}
+	git(repoPath, "merge", "--squash", "squashed")
+	git(repoPath, "commit", "-m", "Squashed")
+
+	// The unpushed commits are in main, so status flags the branch merged
+	// and cleanup needs no --force
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "status", "--refresh")
+	if err != nil || !strings.Contains(output, "merged into main") {
+		t.Errorf("expected merged state, got %v\nOutput: %s", err, output)
+	}
+	output, err = testutil.RunBearing(t, tmpDir, "worktree", "cleanup", "test-repo", "squashed")
+	if err != nil {
+		t.Fatalf("cleanup failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(output, "changes are in its base, will be deleted") {
+		t.Errorf("unexpected plan:\n%s", output)
+	}
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "refs/heads/squashed").Run(); err == nil {
+		t.Error("expected squash merged branch deleted")
+	}
+	workflows, _ := jsonl.NewStore(tmpDir).ReadWorkflow()
+	if len(workflows) != 1 || workflows[0].Status != "merged" {
+		t.Errorf("expected status merged, got %+v", workflows)
+	}
+}
+
+func TestWorktreeCleanupCommitsAfterMerge(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "new", "test-repo", "shipped"); err != nil {
+		t.Fatalf("worktree new failed: %v\nOutput: %s", err, output)
+	}
+	worktreePath := filepath.Join(tmpDir, "test-repo-shipped")
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", worktreePath}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(name string) {
+		t.Helper()
+		os.WriteFile(filepath.Join(worktreePath, name), []byte(name+"\n"), 0644)
+		git("add", name)
+		git("commit", "-m", name)
+	}
+
+	// The pull request merged a.txt remotely; b.txt came after
+	commit("a.txt")
+	merged := git("rev-parse", "HEAD")
+	testutil.FakeGH(t, map[string]string{
+		"shipped": fmt.Sprintf(`{"state":"MERGED","number":7,"url":"","title":"Ship","headRefOid":"%s"}`, merged),
+	})
+	commit("b.txt")
+
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "cleanup", "test-repo", "shipped")
+	if err == nil || !strings.Contains(output, "1 commit(s) after merge") {
+		t.Fatalf("expected cleanup refused, got %v\nOutput: %s", err, output)
+	}
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "refs/heads/shipped").Run(); err != nil {
+		t.Fatal("expected branch kept")
+	}
+
+	// Without the later commit, the merged pull request covers everything
+	git("reset", "--hard", merged)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "cleanup", "test-repo", "shipped"); err != nil {
+		t.Fatalf("cleanup failed: %v\nOutput: %s", err, output)
+	}
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "refs/heads/shipped").Run(); err == nil {
+		t.Error("expected merged branch deleted")
+	}
+}
+
+func TestWorktreeGCSkipsCommitsAfterMerge(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "new", "test-repo", "shipped"); err != nil {
+		t.Fatalf("worktree new failed: %v\nOutput: %s", err, output)
+	}
+	worktreePath := filepath.Join(tmpDir, "test-repo-shipped")
+	var merged string
+	for _, name := range []string{"a.txt", "b.txt"} {
+		os.WriteFile(filepath.Join(worktreePath, name), []byte(name+"\n"), 0644)
+		for _, args := range [][]string{{"add", name}, {"commit", "-m", name}} {
+			if out, err := exec.Command("git", append([]string{"-C", worktreePath}, args...)...).CombinedOutput(); err != nil {
+				t.Fatalf("git %v failed: %v\n%s", args, err, out)
+			}
+		}
+		if merged == "" {
+			out, _ := exec.Command("git", "-C", worktreePath, "rev-parse", "HEAD").Output()
+			merged = strings.TrimSpace(string(out))
+		}
+	}
+	testutil.FakeGH(t, map[string]string{
+		"shipped": fmt.Sprintf(`{"state":"MERGED","number":7,"url":"","title":"Ship","headRefOid":"%s"}`, merged),
+	})
+
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "gc", "--json")
+	if err != nil {
+		t.Fatalf("gc failed: %v\nOutput: %s", err, output)
+	}
+	var results []struct {
+		Folder   string   `json:"folder"`
+		Reason   string   `json:"reason"`
+		Outcome  string   `json:"outcome"`
+		Problems []string `json:"problems"`
+	}
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("invalid JSON: %v\nOutput: %s", err, output)
+	}
+	if len(results) != 1 || results[0].Reason != "merged" || results[0].Outcome != "skipped" ||
+		!slices.Contains(results[0].Problems, "1 commit(s) after merge") {
+		t.Errorf("expected merged worktree with a later commit skipped, got %+v", results)
+	}
+	if err := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", "refs/heads/shipped").Run(); err != nil {
+		t.Error("expected branch kept")
+	}
+	if _, err := os.Stat(worktreePath); err != nil {
+		t.Error("expected worktree kept")
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	t.Setenv("BEARING_AI_ENABLED", "0")
+	tmpDir := t.TempDir()
+
+	repoPath := testutil.CreateTestRepo(t, tmpDir, "test-repo")
+	testutil.InitWorkspace(t, tmpDir)
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "register", "test-repo"); err != nil {
+		t.Fatalf("register failed: %v\nOutput: %s", err, output)
+	}
+	configPath := filepath.Join(tmpDir, ".bearing.yaml")
+	os.WriteFile(configPath, []byte("daemon:\n  workers: 0\n"), 0644)
+
+	// Ordinary commands refuse, naming the file and line
+	output, err := testutil.RunBearing(t, tmpDir, "worktree", "list")
+	if err == nil || !strings.Contains(output, configPath+":2: daemon.workers") {
+		t.Errorf("expected located config error, got %v\nOutput: %s", err, output)
+	}
+
+	// The guard still runs, on the defaults
+	input := fmt.Sprintf(`{"session_id":"s1","cwd":%q,"hook_event_name":"PreToolUse","tool_name":"Bash","tool_input":{"command":"git checkout -b wip"}}`, repoPath)
+	output, err = testutil.RunBearingInput(t, tmpDir, input, "hook", "pre-tool-use")
+	if err != nil || !strings.Contains(output, `"permissionDecision":"deny"`) {
+		t.Errorf("expected deny on defaults, got %v\nOutput: %s", err, output)
+	}
+
+	// And config can repair the file
+	if output, err := testutil.RunBearing(t, tmpDir, "config", "set", "daemon.workers", "2"); err != nil {
+		t.Fatalf("config set failed: %v\nOutput: %s", err, output)
+	}
+	if output, err := testutil.RunBearing(t, tmpDir, "worktree", "list"); err != nil {
+		t.Errorf("expected repaired config, got %v\nOutput: %s", err, output)
+	}
+}
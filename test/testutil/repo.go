@@ -1,9 +1,11 @@
 package testutil
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -46,6 +48,9 @@ func CreateTestRepo(t *testing.T, dir, name string) string {
 func InitWorkspace(t *testing.T, dir string) {
 	t.Helper()
 
+	// Keep per-worktree cache dirs allocated by bearing inside the test
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, ".cache"))
+
 	files := []string{"workflow.jsonl", "local.jsonl", "health.jsonl"}
 	for _, f := range files {
 		path := filepath.Join(dir, f)
@@ -66,3 +71,34 @@ func RunBearing(t *testing.T, workspaceDir string, args ...string) (string, erro
 	output, err := cmd.CombinedOutput()
 	return string(output), err
 }
+
+// RunBearingInput executes the bearing binary with stdin set to input,
+// as when it runs as a hook
+func RunBearingInput(t *testing.T, workspaceDir, input string, args ...string) (string, error) {
+	t.Helper()
+
+	fullArgs := append([]string{"-w", workspaceDir}, args...)
+
+	cmd := exec.Command("bearing", fullArgs...)
+	cmd.Stdin = strings.NewReader(input)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// FakeGH puts a gh on PATH for the rest of the test that answers
+// `gh pr view <branch>` with prs[branch], a JSON object, and reports no
+// pull request for other branches
+func FakeGH(t *testing.T, prs map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n[ \"$1 $2\" = \"pr view\" ] || exit 1\ncase \"$3\" in\n")
+	for branch, pr := range prs {
+		script.WriteString(fmt.Sprintf("%s) echo '%s' ;;\n", branch, pr))
+	}
+	script.WriteString("*) echo 'no pull requests found for branch' >&2; exit 1 ;;\nesac\n")
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script.String()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
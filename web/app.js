@@ -234,7 +234,16 @@ function renderWorktrees() {
     const statusParts = [];
     if (w.dirty) statusParts.push('<span class="status-dirty">*</span>');
     if (w.unpushed > 0) statusParts.push(`<span class="status-unpushed">${w.unpushed}↑</span>`);
-    if (!w.dirty && w.unpushed === 0) statusParts.push('<span class="status-clean">✓</span>');
+    if (w.behind > 0) statusParts.push(`<span class="status-behind">${w.behind}↓</span>`);
+    if (w.operation) statusParts.push(`<span class="status-operation">${escapeHtml(w.operation)}</span>`);
+    if (w.detached) statusParts.push('<span class="status-operation">detached</span>');
+    if (w.merged) statusParts.push(`<span class="status-clean" title="Changes are in ${escapeHtml(w.basedOn)}">merged</span>`);
+    if (w.conflictsWith?.length) {
+      const title = `Likely conflicts with ${w.conflictsWith.join(', ')}`;
+      statusParts.push(`<span class="status-conflicts" title="${escapeHtml(title)}">⚠${w.conflictsWith.length}</span>`);
+    }
+    if (w.unattended) statusParts.push('<span class="status-unattended" title="Uncommitted changes and no live agent session">unattended</span>');
+    if (!w.dirty && w.unpushed === 0 && !w.operation) statusParts.push('<span class="status-clean">✓</span>');
 
     let prBadge = '';
     if (w.prState) {
@@ -243,11 +252,17 @@ function renderWorktrees() {
     }
 
     const baseTag = w.base ? '<span class="base-indicator">BASE</span>' : '';
+    const sessionTag = w.session
+      ? `<span class="session-${w.sessionState}" title="${escapeHtml(`Session ${w.session.id}, last active ${new Date(w.session.lastActivity).toLocaleString()}`)}">●</span>`
+      : '';
+    const leaseTag = w.leaseOwner
+      ? `<span class="lease-indicator" title="${escapeHtml(`Claimed by ${w.leaseOwner} until ${new Date(w.leaseExpires).toLocaleString()}`)}">⚿</span>`
+      : '';
 
     return `
       <div class="table-row ${i === state.worktreeIndex ? 'selected' : ''}"
            data-folder="${w.folder}" data-index="${i}">
-        <span class="col-folder">${escapeHtml(w.folder)}${baseTag}</span>
+        <span class="col-folder">${escapeHtml(w.folder)}${baseTag}${leaseTag}${sessionTag}</span>
         <span class="col-branch">${escapeHtml(w.branch)}</span>
         <span class="col-status">${statusParts.join(' ')}</span>
         <span class="col-pr">${prBadge}</span>
@@ -616,8 +631,12 @@ function connectSSE() {
   state.evtSource.addEventListener('update', (e) => {
     try {
       const data = JSON.parse(e.data);
-      if (data.type === 'health' || data.type === 'worktrees') {
+      if (data.type === 'worktree') {
+        applyWorktreeHealth(data.data);
+      } else if (data.type === 'health' || data.type === 'worktrees' || data.type === 'workflow' || data.type === 'conflicts' || data.type === 'leases' || data.type === 'sessions') {
         refresh();
+      } else if (data.type === 'plans') {
+        loadPlans();
       }
     } catch (err) {
       console.error('SSE parse error:', err);
@@ -631,6 +650,30 @@ function connectSSE() {
   };
 }
 
+// Update one worktree from a daemon re-check without refetching everything.
+// conflictsWith is kept: it comes from the cross-worktree pass, not the check.
+function applyWorktreeHealth({ folder, health }) {
+  const wt = state.worktrees.find(w => w.folder === folder);
+  if (!wt) {
+    refresh();
+    return;
+  }
+  wt.dirty = health.dirty;
+  wt.unpushed = health.unpushed;
+  wt.prState = health.prState;
+  wt.healthError = health.error;
+  for (const field of GIT_STATUS_FIELDS) {
+    wt[field] = health[field];
+  }
+  renderWorktrees();
+}
+
+// Git state fields shared by health entries and /api/worktrees rows
+const GIT_STATUS_FIELDS = [
+  'upstream', 'ahead', 'behind', 'basedOn', 'aheadBase', 'behindBase', 'merged',
+  'staged', 'modified', 'untracked', 'conflicted', 'stashes', 'detached', 'operation',
+];
+
 function setStatus(status) {
   els.statusIndicator.className = `status-${status}`;
   els.statusIndicator.title = status === 'ok' ? 'Connected' :